@@ -8,12 +8,19 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authenticator"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authentification"
 	"github.com/trymanytimes/UpdateWeb/pkg/business"
 	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/exporter"
 	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcserver"
 	auditlog "github.com/trymanytimes/UpdateWeb/pkg/log"
 	"github.com/trymanytimes/UpdateWeb/pkg/metric"
+	"github.com/trymanytimes/UpdateWeb/pkg/report"
+	"github.com/trymanytimes/UpdateWeb/pkg/stats"
 	restserver "github.com/trymanytimes/UpdateWeb/server"
 )
 
@@ -32,10 +39,19 @@ func main() {
 	}
 	db.RegisterResources(auditlog.PersistentResources()...)
 	db.RegisterResources(auth.PersistentResources()...)
+	db.RegisterResources(stats.PersistentResources()...)
+	db.RegisterResources(report.PersistentResources()...)
+	db.RegisterResources(metric.PersistentResources()...)
+	db.RegisterResources(alarm.PersistentResources()...)
+	db.RegisterMigrations(auditlog.Migrations()...)
+	db.RegisterMigrations(auth.Migrations()...)
+	db.RegisterMigrations(metric.Migrations()...)
+	db.RegisterIndexes(stats.Indexes()...)
 	if err := db.Init(conf); err != nil {
 		log.Fatalf("init db failed: %s", err.Error())
 	}
-	conn, err := grpc.Dial(conf.APIServer.GrpcAddr, grpc.WithInsecure())
+	conn, err := grpc.Dial(conf.APIServer.GrpcAddr, grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(exporter.UnaryClientInterceptor))
 	if err != nil {
 		log.Fatalf("dail grpc failed: %s", err.Error())
 	}
@@ -44,6 +60,31 @@ func main() {
 		log.Fatalf("grpc address is not correct")
 	}
 	grpcclient.NewGrpcClient(conn)
+	grpcServer, err := grpcserver.New(conf)
+	if err != nil {
+		log.Fatalf("new grpc server failed: %s", err.Error())
+	}
+	go func() {
+		if err := grpcServer.Run(); err != nil {
+			log.Fatalf("grpc server run failed: %s", err.Error())
+		}
+	}()
+	stats.Run(conf)
+	metric.Run(conf)
+	report.Run(conf)
+	if err := alarm.Init(conf); err != nil {
+		log.Fatalf("init alarm failed: %s", err.Error())
+	}
+	alarm.Run()
+	exporter.Run(conf)
+
+	if err := authentification.Init(conf); err != nil {
+		log.Fatalf("init jwt keys failed: %s", err.Error())
+	}
+
+	if err := authenticator.Init(conf); err != nil {
+		log.Fatalf("init authenticators failed: %s", err.Error())
+	}
 
 	server, err := restserver.NewServer()
 	if err != nil {
@@ -56,6 +97,10 @@ func main() {
 	}
 	server.RegisterHandler(restserver.HandlerRegister(auditlog.RegisterHandler))
 	server.RegisterHandler(restserver.HandlerRegister(business.RegisterHandler))
+	server.RegisterHandler(restserver.HandlerRegister(report.RegisterHandler))
+	if err := server.RegisterHandler(restserver.HandlerRegister(alarm.RegisterHandler)); err != nil {
+		log.Fatalf("register alarm failed: %s", err.Error())
+	}
 
 	if err := server.Run(conf); err != nil {
 		log.Fatalf("server run failed: %s", err.Error())
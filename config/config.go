@@ -5,15 +5,26 @@ import (
 )
 
 type DDIControllerConfig struct {
-	Path          string            `yaml:"-"`
-	DB            DBConf            `yaml:"db"`
-	Server        ServerConf        `yaml:"server"`
-	Prometheus    PrometheusConf    `yaml:"prometheus"`
-	Elasticsearch ElasticsearchConf `yaml:"elasticsearch"`
-	MonitorNode   MonitorNodeConf   `yaml:"monitor_node"`
-	AuditLog      AuditLogConf      `yaml:"audit_log"`
-	APIServer     APIGrpcConf       `yaml:"api_server"`
-	VIP           VIPConf           `yaml:"vip"`
+	Path           string             `yaml:"-"`
+	DB             DBConf             `yaml:"db"`
+	Server         ServerConf         `yaml:"server"`
+	Prometheus     PrometheusConf     `yaml:"prometheus"`
+	Elasticsearch  ElasticsearchConf  `yaml:"elasticsearch"`
+	MonitorNode    MonitorNodeConf    `yaml:"monitor_node"`
+	AuditLog       AuditLogConf       `yaml:"audit_log"`
+	APIServer      APIGrpcConf        `yaml:"api_server"`
+	VIP            VIPConf            `yaml:"vip"`
+	Stats          StatsConf          `yaml:"stats"`
+	Mail           MailConf           `yaml:"mail"`
+	Report         ReportConf         `yaml:"report"`
+	HomePage       HomePageConf       `yaml:"home_page"`
+	Exporter       ExporterConf       `yaml:"exporter"`
+	JWT            JWTConf            `yaml:"jwt"`
+	LoginLimit     LoginLimitConf     `yaml:"login_limit"`
+	PasswordPolicy PasswordPolicyConf `yaml:"password_policy"`
+	LDAP           LDAPConf           `yaml:"ldap"`
+	OIDC           OIDCConf           `yaml:"oidc"`
+	SecretKey      SecretKeyConf      `yaml:"secret_key"`
 }
 
 type DBConf struct {
@@ -45,7 +56,8 @@ type PrometheusConf struct {
 }
 
 type MonitorNodeConf struct {
-	TimeOut int64 `yaml:"time_out"`
+	TimeOut           int64 `yaml:"time_out"`
+	RaltCheckInterval int64 `yaml:"ralt_check_interval"`
 }
 type ElasticsearchConf struct {
 	Addr  string `yaml:"addr"`
@@ -62,6 +74,141 @@ type VIPConf struct {
 	Length   int32  `yaml:"length"`
 }
 
+type StatsConf struct {
+	CollectInterval uint32 `yaml:"collect_interval"`
+	Retention5m     uint32 `yaml:"retention_5m"`
+	Retention1h     uint32 `yaml:"retention_1h"`
+	Retention1d     uint32 `yaml:"retention_1d"`
+}
+
+type MailConf struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+//ReportConf retention is the days report files are kept
+type ReportConf struct {
+	Retention uint32 `yaml:"retention"`
+}
+
+type HomePageConf struct {
+	CacheTTL uint32 `yaml:"cache_ttl"`
+	Timeout  uint32 `yaml:"timeout"`
+}
+
+type ExporterConf struct {
+	Addr            string `yaml:"addr"`
+	RefreshInterval uint32 `yaml:"refresh_interval"`
+	RefreshTimeout  uint32 `yaml:"refresh_timeout"`
+}
+
+//JWTConf keys are read from key_file if it is set, token_lifetime is the
+//lifetime of access token and refresh_token_lifetime is the lifetime of login
+//session, both are in seconds
+type JWTConf struct {
+	TokenLifetime        uint32   `yaml:"token_lifetime"`
+	RefreshTokenLifetime uint32   `yaml:"refresh_token_lifetime"`
+	KeyFile              string   `yaml:"key_file"`
+	SigningKeyID         string   `yaml:"signing_key_id"`
+	Keys                 []JWTKey `yaml:"keys"`
+}
+
+//JWTKeySet is the content of jwt key file
+type JWTKeySet struct {
+	SigningKeyID string   `yaml:"signing_key_id"`
+	Keys         []JWTKey `yaml:"keys"`
+}
+
+//JWTKey secret is used by HS256, key files are PEM files used by RS256 and
+//ES256, a key is only used to verify token before verify_until when it is set
+type JWTKey struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"`
+	Secret         string `yaml:"secret"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+	VerifyUntil    string `yaml:"verify_until"`
+}
+
+//LoginLimitConf durations are in seconds, failures older than failure_window
+//are forgotten
+type LoginLimitConf struct {
+	MaxUserFailures uint32 `yaml:"max_user_failures"`
+	MaxIPFailures   uint32 `yaml:"max_ip_failures"`
+	BaseBackoff     uint32 `yaml:"base_backoff"`
+	MaxBackoff      uint32 `yaml:"max_backoff"`
+	LockoutDuration uint32 `yaml:"lockout_duration"`
+	FailureWindow   uint32 `yaml:"failure_window"`
+}
+
+//PasswordPolicyConf history_count is the number of recent passwords which
+//can't be reused, max_age is in days and disabled if it is zero
+type PasswordPolicyConf struct {
+	MinLength        uint32 `yaml:"min_length"`
+	MaxLength        uint32 `yaml:"max_length"`
+	RequireUppercase bool   `yaml:"require_uppercase"`
+	RequireLowercase bool   `yaml:"require_lowercase"`
+	RequireDigit     bool   `yaml:"require_digit"`
+	RequireSpecial   bool   `yaml:"require_special"`
+	HistoryCount     uint32 `yaml:"history_count"`
+	MaxAge           uint32 `yaml:"max_age"`
+}
+
+//LDAPConf user_filter and group_filter are formatted with the escaped
+//username and user dn, groups are read from group_attribute of user entry if
+//group_filter is empty, timeout is in seconds. ldap:// url needs start_tls
+//unless allow_plaintext is set, ca_file verifies certificate of server
+type LDAPConf struct {
+	Enabled            bool           `yaml:"enabled"`
+	URL                string         `yaml:"url"`
+	StartTLS           bool           `yaml:"start_tls"`
+	AllowPlaintext     bool           `yaml:"allow_plaintext"`
+	CAFile             string         `yaml:"ca_file"`
+	InsecureSkipVerify bool           `yaml:"insecure_skip_verify"`
+	BindDN             string         `yaml:"bind_dn"`
+	BindPassword       string         `yaml:"bind_password"`
+	BaseDN             string         `yaml:"base_dn"`
+	UserFilter         string         `yaml:"user_filter"`
+	GroupAttribute     string         `yaml:"group_attribute"`
+	GroupBaseDN        string         `yaml:"group_base_dn"`
+	GroupFilter        string         `yaml:"group_filter"`
+	Timeout            uint32         `yaml:"timeout"`
+	GroupMappings      []GroupMapping `yaml:"group_mappings"`
+}
+
+//GroupMapping group is the dn or cn of an external group, its members get
+//the user groups and roles
+type GroupMapping struct {
+	Group      string   `yaml:"group"`
+	UserGroups []string `yaml:"user_groups"`
+	Roles      []string `yaml:"roles"`
+}
+
+//OIDCConf issuer is used to discover endpoints, redirect_url is the page
+//which posts code and state back to /login/oidc, timeout is in seconds
+type OIDCConf struct {
+	Enabled       bool           `yaml:"enabled"`
+	Issuer        string         `yaml:"issuer"`
+	ClientID      string         `yaml:"client_id"`
+	ClientSecret  string         `yaml:"client_secret"`
+	RedirectURL   string         `yaml:"redirect_url"`
+	Scopes        []string       `yaml:"scopes"`
+	UsernameClaim string         `yaml:"username_claim"`
+	GroupsClaim   string         `yaml:"groups_claim"`
+	Timeout       uint32         `yaml:"timeout"`
+	GroupMappings []GroupMapping `yaml:"group_mappings"`
+}
+
+//SecretKeyConf key_file holds the hex encoded aes key of secrets saved in db,
+//it must be provisioned before start, all controllers share the same key
+//and it should be backed up with db
+type SecretKeyConf struct {
+	KeyFile string `yaml:"key_file"`
+}
+
 var gConf *DDIControllerConfig
 
 func LoadConfig(path string) (*DDIControllerConfig, error) {
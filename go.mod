@@ -3,22 +3,30 @@ module github.com/trymanytimes/UpdateWeb
 go 1.13
 
 require (
+	github.com/coreos/go-oidc v2.2.1+incompatible
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/gin-gonic/gin v1.6.3
+	github.com/go-asn1-ber/asn1-ber v1.5.1
+	github.com/go-ldap/ldap/v3 v3.3.0
 	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/websocket v1.4.2
 	github.com/jlaffaye/ftp v0.0.0-20200812143550-39e3779af0db
 	github.com/linkingthing/ddi-agent v1.2.0
 	github.com/linkingthing/ddi-monitor v0.0.0-20201030024156-9af45c2922d8
 	github.com/linkingthing/pg-ha v1.0.1
+	github.com/pquerna/cachecontrol v0.0.0-20180517163645-1555304b9b35 // indirect
+	github.com/prometheus/client_golang v1.7.1
 	github.com/segmentio/kafka-go v0.4.5
 	github.com/soniah/gosnmp v1.27.0
 	github.com/zdnscloud/cement v0.0.0-20200612070849-67372f989797
 	github.com/zdnscloud/g53 v0.0.0-20200610043040-c71a4decb734
 	github.com/zdnscloud/gorest v0.0.0-20200909072941-55569cb2f203
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/net v0.0.0-20201010224723-4f7140c49acb
+	golang.org/x/oauth2 v0.0.0-20190226205417-e64efc72b421
 	google.golang.org/grpc v1.32.0
 	google.golang.org/protobuf v1.25.0
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
+	gopkg.in/square/go-jose.v2 v2.6.0 // indirect
 	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,53 @@
+package alarm
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zdnscloud/gorest"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+)
+
+var (
+	Version = restresource.APIVersion{
+		Version: "v1",
+		Group:   "linkingthing.com/alarm",
+	}
+)
+
+//Init loads key of mail sender password, it should be called before Run
+func Init(conf *config.DDIControllerConfig) error {
+	if err := handler.InitMailSenderKey(conf.SecretKey.KeyFile); err != nil {
+		return fmt.Errorf("load secret key failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+func RegisterHandler(apiServer *gorest.Server, router gin.IRoutes) error {
+	thresholdHandler, err := handler.NewThresholdHandler()
+	if err != nil {
+		return err
+	}
+
+	alarmHandler := handler.NewAlarmHandler()
+	alarmHandler.RegisterWSHandler(router)
+	apiServer.Schemas.MustImport(&Version, resource.Threshold{}, thresholdHandler)
+	apiServer.Schemas.MustImport(&Version, resource.Alarm{}, alarmHandler)
+	apiServer.Schemas.MustImport(&Version, resource.MailSender{}, handler.NewMailSenderHandler())
+	apiServer.Schemas.MustImport(&Version, resource.MailReceiver{}, handler.NewMailReceiverHandler())
+	return nil
+}
+
+func PersistentResources() []restresource.Resource {
+	return []restresource.Resource{
+		&resource.Threshold{},
+		&resource.Alarm{},
+		&resource.MailSender{},
+		&resource.MailReceiver{},
+	}
+}
@@ -0,0 +1,170 @@
+package alarm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	businesshandler "github.com/trymanytimes/UpdateWeb/pkg/business/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	metricresource "github.com/trymanytimes/UpdateWeb/pkg/metric/resource"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+)
+
+const (
+	evaluateInterval = time.Minute
+	evaluateTimeout  = 30 * time.Second
+)
+
+//Run checks thresholds periodically, ha trigger is published by node monitor
+//when ha switches
+func Run() {
+	go func() {
+		ticker := time.NewTicker(evaluateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				evaluate()
+			}
+		}
+	}()
+}
+
+func evaluate() {
+	thresholds, err := handler.GetThresholds()
+	if err != nil {
+		log.Warnf("evaluate alarm failed: %s", err.Error())
+		return
+	}
+
+	var events []*Event
+	if nodeEvents, err := evaluateNodes(thresholds); err != nil {
+		log.Warnf("evaluate node alarm failed: %s", err.Error())
+	} else {
+		events = append(events, nodeEvents...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evaluateTimeout)
+	defer cancel()
+	if event, err := evaluateWebsites(ctx, thresholds); err != nil {
+		log.Warnf("evaluate website alarm failed: %s", err.Error())
+	} else if event != nil {
+		events = append(events, event)
+	}
+
+	if event, err := evaluateVIPs(ctx, thresholds); err != nil {
+		log.Warnf("evaluate vip alarm failed: %s", err.Error())
+	} else if event != nil {
+		events = append(events, event)
+	}
+
+	for _, event := range events {
+		event.Publish()
+	}
+}
+
+//evaluateNodes reads nodes state which is refreshed by keepalive of node
+//monitor and marked offline when keepalive times out
+func evaluateNodes(thresholds map[resource.ThresholdName]*resource.Threshold) ([]*Event, error) {
+	var nodes []*metricresource.Node
+	if err := db.GetResources(map[string]interface{}{}, &nodes); err != nil {
+		return nil, fmt.Errorf("list nodes failed: %s", err.Error())
+	}
+
+	return nodesEvents(thresholds, nodes), nil
+}
+
+func nodesEvents(thresholds map[resource.ThresholdName]*resource.Threshold, nodes []*metricresource.Node) []*Event {
+	var events []*Event
+	for _, node := range nodes {
+		if node.NodeIsAlive == false {
+			if threshold, ok := thresholds[resource.ThresholdNameNodeOffline]; ok {
+				events = append(events, newThresholdEvent(threshold).NodeIp(node.Ip))
+			}
+			continue
+		}
+
+		if event := nodeRatioEvent(thresholds[resource.ThresholdNameCPUUsedRatio], node.Ip, node.CpuRatio); event != nil {
+			events = append(events, event)
+		}
+
+		if event := nodeRatioEvent(thresholds[resource.ThresholdNameMemoryUsedRatio], node.Ip, node.MemRatio); event != nil {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+//nodeRatioEvent compares ratio reported by node, such as 0.8512, with
+//percent of threshold
+func nodeRatioEvent(threshold *resource.Threshold, ip, ratio string) *Event {
+	if threshold == nil || ratio == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(ratio, 64)
+	if err != nil {
+		log.Warnf("parse ratio %s of node %s failed: %s", ratio, ip, err.Error())
+		return nil
+	}
+
+	if event := valueEvent(threshold, uint64(f*100)); event != nil {
+		return event.NodeIp(ip)
+	}
+
+	return nil
+}
+
+//valueEvent returns nil if value doesn't reach threshold
+func valueEvent(threshold *resource.Threshold, value uint64) *Event {
+	if value < threshold.Value {
+		return nil
+	}
+
+	return newThresholdEvent(threshold).Value(value)
+}
+
+func evaluateWebsites(ctx context.Context, thresholds map[resource.ThresholdName]*resource.Threshold) (*Event, error) {
+	threshold, ok := thresholds[resource.ThresholdNameWebsiteAbnormal]
+	if ok == false {
+		return nil, nil
+	}
+
+	resp, err := grpcclient.GetGrpcClient().MonitorClient.ShowHomePageData(ctx,
+		&pbHomePage.ShowHomePageDataReq{ClusterId: businesshandler.DefaultClusterID})
+	if err != nil {
+		return nil, fmt.Errorf("grpc service exec ShowHomePageData failed: %s", err.Error())
+	}
+
+	return valueEvent(threshold, uint64(resp.GetDomainIsAbnormal())), nil
+}
+
+func evaluateVIPs(ctx context.Context, thresholds map[resource.ThresholdName]*resource.Threshold) (*Event, error) {
+	threshold, ok := thresholds[resource.ThresholdNameVIPUsedRatio]
+	if ok == false {
+		return nil, nil
+	}
+
+	used, total, err := businesshandler.GetVIPUsage(ctx, businesshandler.DefaultClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return vipEvent(threshold, used, total), nil
+}
+
+func vipEvent(threshold *resource.Threshold, used, total uint64) *Event {
+	if total == 0 {
+		return nil
+	}
+
+	return valueEvent(threshold, used*100/total)
+}
@@ -0,0 +1,129 @@
+package alarm
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	metricresource "github.com/trymanytimes/UpdateWeb/pkg/metric/resource"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+func defaultThresholds() map[resource.ThresholdName]*resource.Threshold {
+	thresholds := make(map[resource.ThresholdName]*resource.Threshold)
+	for _, threshold := range resource.DefaultThresholds {
+		t := *threshold
+		thresholds[t.Name] = &t
+	}
+	return thresholds
+}
+
+func TestNodesEvents(t *testing.T) {
+	nodes := []*metricresource.Node{
+		&metricresource.Node{Ip: "10.0.0.1", NodeIsAlive: false, CpuRatio: "0.99"},
+		&metricresource.Node{Ip: "10.0.0.2", NodeIsAlive: true, CpuRatio: "0.8512", MemRatio: "0.7999"},
+		&metricresource.Node{Ip: "10.0.0.3", NodeIsAlive: true, CpuRatio: "0.10", MemRatio: "0.95"},
+		&metricresource.Node{Ip: "10.0.0.4", NodeIsAlive: true, CpuRatio: "bad", MemRatio: ""},
+	}
+
+	events := nodesEvents(defaultThresholds(), nodes)
+	expected := []struct {
+		name  resource.ThresholdName
+		ip    string
+		value uint64
+	}{
+		{resource.ThresholdNameNodeOffline, "10.0.0.1", 0},
+		{resource.ThresholdNameCPUUsedRatio, "10.0.0.2", 85},
+		{resource.ThresholdNameMemoryUsedRatio, "10.0.0.3", 95},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("events expected %d but get %d", len(expected), len(events))
+	}
+
+	for i, e := range expected {
+		alarm := events[i].alarm
+		if alarm.Name != e.name || alarm.NodeIp != e.ip || alarm.Value != e.value {
+			t.Errorf("event %d expected %s of %s with %d but get %s of %s with %d",
+				i, e.name, e.ip, e.value, alarm.Name, alarm.NodeIp, alarm.Value)
+		}
+	}
+
+	if events[1].alarm.Threshold != 80 || events[1].alarm.Level != resource.ThresholdLevelMajor {
+		t.Errorf("event should carry threshold and level but get %d %s", events[1].alarm.Threshold, events[1].alarm.Level)
+	}
+}
+
+func TestNodesEventsDisabledThreshold(t *testing.T) {
+	thresholds := defaultThresholds()
+	delete(thresholds, resource.ThresholdNameNodeOffline)
+	delete(thresholds, resource.ThresholdNameCPUUsedRatio)
+	nodes := []*metricresource.Node{
+		&metricresource.Node{Ip: "10.0.0.1", NodeIsAlive: false},
+		&metricresource.Node{Ip: "10.0.0.2", NodeIsAlive: true, CpuRatio: "0.99", MemRatio: "0.10"},
+	}
+
+	if events := nodesEvents(thresholds, nodes); len(events) != 0 {
+		t.Errorf("disabled threshold should not generate event but get %d", len(events))
+	}
+}
+
+func TestVIPEvent(t *testing.T) {
+	threshold := defaultThresholds()[resource.ThresholdNameVIPUsedRatio]
+	cases := []struct {
+		used     uint64
+		total    uint64
+		hasEvent bool
+		value    uint64
+	}{
+		{0, 0, false, 0},
+		{5, 0, false, 0},
+		{89, 100, false, 0},
+		{9, 10, true, 90},
+		{10, 10, true, 100},
+	}
+
+	for _, c := range cases {
+		event := vipEvent(threshold, c.used, c.total)
+		if (event != nil) != c.hasEvent {
+			t.Errorf("vip %d/%d expected event %v", c.used, c.total, c.hasEvent)
+		} else if event != nil && event.alarm.Value != c.value {
+			t.Errorf("vip %d/%d expected value %d but get %d", c.used, c.total, c.value, event.alarm.Value)
+		}
+	}
+}
+
+func TestValueEvent(t *testing.T) {
+	threshold := defaultThresholds()[resource.ThresholdNameWebsiteAbnormal]
+	if event := valueEvent(threshold, 0); event != nil {
+		t.Errorf("value under threshold should not generate event")
+	}
+
+	if event := valueEvent(threshold, 3); event == nil || event.alarm.Value != 3 || event.alarm.Threshold != 1 {
+		t.Errorf("value reaches threshold should generate event")
+	}
+}
+
+func TestHATriggerEvent(t *testing.T) {
+	thresholds := defaultThresholds()
+	event := haTriggerEvent(thresholds, "master_up", "10.0.0.1", "10.0.0.2")
+	if event == nil {
+		t.Fatal("ha trigger should generate event")
+	}
+
+	if alarm := event.alarm; alarm.Name != resource.ThresholdNameHATrigger || alarm.HaCmd != "master_up" ||
+		alarm.MasterIp != "10.0.0.1" || alarm.SlaveIp != "10.0.0.2" || alarm.Level != resource.ThresholdLevelCritical {
+		t.Errorf("unexpected ha trigger alarm %+v", alarm)
+	}
+
+	delete(thresholds, resource.ThresholdNameHATrigger)
+	if event := haTriggerEvent(thresholds, "master_up", "10.0.0.1", "10.0.0.2"); event != nil {
+		t.Errorf("disabled ha trigger should not generate event")
+	}
+}
@@ -0,0 +1,113 @@
+package alarm
+
+import (
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+)
+
+type Event struct {
+	alarm    *resource.Alarm
+	sendMail bool
+}
+
+func NewEvent() *Event {
+	return &Event{alarm: &resource.Alarm{}}
+}
+
+func (e *Event) Name(name resource.ThresholdName) *Event {
+	e.alarm.Name = name
+	return e
+}
+
+func (e *Event) Level(level resource.ThresholdLevel) *Event {
+	e.alarm.Level = level
+	return e
+}
+
+func (e *Event) ThresholdType(thresholdType resource.ThresholdType) *Event {
+	e.alarm.ThresholdType = thresholdType
+	return e
+}
+
+func (e *Event) NodeIp(ip string) *Event {
+	e.alarm.NodeIp = ip
+	return e
+}
+
+func (e *Event) Value(value uint64) *Event {
+	e.alarm.Value = value
+	return e
+}
+
+func (e *Event) Threshold(threshold uint64) *Event {
+	e.alarm.Threshold = threshold
+	return e
+}
+
+func (e *Event) HaCmd(cmd string) *Event {
+	e.alarm.HaCmd = cmd
+	return e
+}
+
+func (e *Event) MasterIp(ip string) *Event {
+	e.alarm.MasterIp = ip
+	return e
+}
+
+func (e *Event) SlaveIp(ip string) *Event {
+	e.alarm.SlaveIp = ip
+	return e
+}
+
+func (e *Event) SendMail(sendMail bool) *Event {
+	e.sendMail = sendMail
+	return e
+}
+
+//Publish saves the alarm and mails it in background, a duplicated alarm is
+//neither saved nor mailed
+func (e *Event) Publish() {
+	added, err := handler.AddAlarm(e.alarm)
+	if err != nil {
+		log.Warnf("publish alarm event %s failed: %s", e.alarm.Name, err.Error())
+		return
+	}
+
+	if added && e.sendMail {
+		go func() {
+			if err := handler.SendAlarmMail(e.alarm); err != nil {
+				log.Warnf("send alarm %s mail failed: %s", e.alarm.Name, err.Error())
+			}
+		}()
+	}
+}
+
+func newThresholdEvent(threshold *resource.Threshold) *Event {
+	return NewEvent().Name(threshold.Name).Level(threshold.Level).ThresholdType(threshold.ThresholdType).
+		Threshold(threshold.Value).SendMail(threshold.SendMail)
+}
+
+//PublishHATrigger is called by node monitor when pg ha switches master, it
+//does nothing if ha trigger threshold is disabled
+func PublishHATrigger(cmd, masterIp, slaveIp string) {
+	thresholds, err := handler.GetThresholds()
+	if err != nil {
+		log.Warnf("get ha trigger threshold failed: %s", err.Error())
+		return
+	}
+
+	if event := haTriggerEvent(thresholds, cmd, masterIp, slaveIp); event != nil {
+		event.Publish()
+	}
+}
+
+func haTriggerEvent(thresholds map[resource.ThresholdName]*resource.Threshold, cmd, masterIp, slaveIp string) *Event {
+	threshold, ok := thresholds[resource.ThresholdNameHATrigger]
+	if ok == false {
+		return nil
+	}
+
+	return newThresholdEvent(threshold).HaCmd(cmd).MasterIp(masterIp).SlaveIp(slaveIp)
+}
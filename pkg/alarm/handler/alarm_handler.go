@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	DefaultAlarmValidPeriod = 90 //day
+)
+
+var AlarmFilterNames = []string{"name", "level", "state"}
+
+var (
+	countLock    sync.Mutex
+	countCond    = sync.NewCond(&countLock)
+	countVersion uint64
+)
+
+type AlarmHandler struct {
+	validPeriod int
+}
+
+func NewAlarmHandler() *AlarmHandler {
+	h := &AlarmHandler{validPeriod: DefaultAlarmValidPeriod}
+	go h.run()
+	return h
+}
+
+func (h *AlarmHandler) run() {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+				return deleteExpiredAlarms(tx, time.Now(), h.validPeriod)
+			}); err != nil {
+				log.Warnf("delete expired alarm failed: %s", err.Error())
+			} else {
+				notifyUntreatedCount()
+			}
+		}
+	}
+}
+
+//deleteExpiredAlarms removes alarms created validPeriod days before now
+func deleteExpiredAlarms(tx restdb.Transaction, now time.Time, validPeriod int) error {
+	_, err := tx.Exec("delete from gr_alarm where create_time < $1", now.AddDate(0, 0, -validPeriod))
+	return err
+}
+
+func (h *AlarmHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	alarm := ctx.Resource.(*resource.Alarm)
+	var alarms []*resource.Alarm
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if _, err := tx.Update(resource.TableAlarm, map[string]interface{}{
+			"state": string(alarm.State),
+		}, map[string]interface{}{restdb.IDField: alarm.GetID()}); err != nil {
+			return fmt.Errorf("update alarm %s failed: %s", alarm.GetID(), err.Error())
+		}
+
+		return tx.Fill(map[string]interface{}{restdb.IDField: alarm.GetID()}, &alarms)
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	if len(alarms) == 0 {
+		return nil, resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("alarm %s is non-exists", alarm.GetID()))
+	}
+
+	notifyUntreatedCount()
+	return alarms[0], nil
+}
+
+func (h *AlarmHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	var alarms []*resource.Alarm
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		sql, args := util.GenSqlAndArgsByFileters(resource.TableAlarm, AlarmFilterNames, h.validPeriod, ctx.GetFilters())
+		return tx.FillEx(&alarms, sql, args...)
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("list alarm failed: %s", err.Error()))
+	}
+
+	return alarms, nil
+}
+
+//AddAlarm keeps one untreated alarm of an item for each node except ha
+//trigger, it returns false if the alarm is ignored as duplicated
+func AddAlarm(alarm *resource.Alarm) (bool, error) {
+	added := false
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		var err error
+		added, err = addAlarm(tx, alarm)
+		return err
+	}); err != nil {
+		return false, fmt.Errorf("insert alarm %s into db failed: %s", alarm.Name, err.Error())
+	}
+
+	if added {
+		notifyUntreatedCount()
+	}
+	return added, nil
+}
+
+func addAlarm(tx restdb.Transaction, alarm *resource.Alarm) (bool, error) {
+	alarm.State = resource.AlarmStateUntreated
+	if alarm.Name != resource.ThresholdNameHATrigger {
+		if exists, err := tx.Exists(resource.TableAlarm, map[string]interface{}{
+			"name":    string(alarm.Name),
+			"node_ip": alarm.NodeIp,
+			"state":   string(resource.AlarmStateUntreated),
+		}); err != nil {
+			return false, err
+		} else if exists {
+			return false, nil
+		}
+	}
+
+	if _, err := tx.Insert(alarm); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func GetUntreatedAlarmCount() (uint64, error) {
+	var count int64
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		c, err := tx.CountEx(resource.TableAlarm, "select count(1) from gr_alarm where state = $1",
+			string(resource.AlarmStateUntreated))
+		count = c
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("count untreated alarm failed: %s", err.Error())
+	}
+
+	return uint64(count), nil
+}
+
+func notifyUntreatedCount() {
+	countLock.Lock()
+	countVersion += 1
+	countLock.Unlock()
+	countCond.Broadcast()
+}
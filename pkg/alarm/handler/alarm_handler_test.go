@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+)
+
+//alarmTx keeps alarms in memory, only methods used by alarm handler are
+//implemented
+type alarmTx struct {
+	restdb.Transaction
+	alarms   []*resource.Alarm
+	execSql  string
+	execArgs []interface{}
+}
+
+func (tx *alarmTx) Exists(typ restdb.ResourceType, conds map[string]interface{}) (bool, error) {
+	for _, alarm := range tx.alarms {
+		if string(alarm.Name) == conds["name"] && alarm.NodeIp == conds["node_ip"] &&
+			string(alarm.State) == conds["state"] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (tx *alarmTx) Insert(r restresource.Resource) (restresource.Resource, error) {
+	alarm := *r.(*resource.Alarm)
+	tx.alarms = append(tx.alarms, &alarm)
+	return r, nil
+}
+
+func (tx *alarmTx) Exec(sql string, args ...interface{}) (int64, error) {
+	tx.execSql = sql
+	tx.execArgs = args
+	return 0, nil
+}
+
+func TestAddAlarmDedup(t *testing.T) {
+	tx := &alarmTx{}
+	cases := []struct {
+		alarm *resource.Alarm
+		added bool
+	}{
+		{&resource.Alarm{Name: resource.ThresholdNameCPUUsedRatio, NodeIp: "10.0.0.1", Value: 85}, true},
+		{&resource.Alarm{Name: resource.ThresholdNameCPUUsedRatio, NodeIp: "10.0.0.1", Value: 90}, false},
+		{&resource.Alarm{Name: resource.ThresholdNameCPUUsedRatio, NodeIp: "10.0.0.2", Value: 85}, true},
+		{&resource.Alarm{Name: resource.ThresholdNameMemoryUsedRatio, NodeIp: "10.0.0.1", Value: 85}, true},
+		{&resource.Alarm{Name: resource.ThresholdNameWebsiteAbnormal, Value: 2}, true},
+		{&resource.Alarm{Name: resource.ThresholdNameWebsiteAbnormal, Value: 3}, false},
+		{&resource.Alarm{Name: resource.ThresholdNameHATrigger, HaCmd: "master_up"}, true},
+		{&resource.Alarm{Name: resource.ThresholdNameHATrigger, HaCmd: "master_up"}, true},
+	}
+
+	for i, c := range cases {
+		added, err := addAlarm(tx, c.alarm)
+		if err != nil {
+			t.Fatalf("add alarm %d failed: %s", i, err.Error())
+		}
+
+		if added != c.added {
+			t.Errorf("alarm %d %s of %s expected added %v but get %v", i, c.alarm.Name, c.alarm.NodeIp, c.added, added)
+		}
+	}
+
+	if len(tx.alarms) != 6 {
+		t.Errorf("alarms expected 6 but get %d", len(tx.alarms))
+	}
+
+	tx.alarms[0].State = resource.AlarmStateSolved
+	if added, err := addAlarm(tx, &resource.Alarm{Name: resource.ThresholdNameCPUUsedRatio, NodeIp: "10.0.0.1"}); err != nil {
+		t.Fatalf("add alarm failed: %s", err.Error())
+	} else if added == false {
+		t.Errorf("alarm should be added after previous one is solved")
+	}
+
+	for _, alarm := range tx.alarms {
+		if alarm.State != resource.AlarmStateSolved && alarm.State != resource.AlarmStateUntreated {
+			t.Errorf("added alarm should be untreated but get %s", alarm.State)
+		}
+	}
+}
+
+type failedTx struct {
+	restdb.Transaction
+}
+
+func (tx *failedTx) Exists(typ restdb.ResourceType, conds map[string]interface{}) (bool, error) {
+	return false, fmt.Errorf("connection refused")
+}
+
+func TestAddAlarmFailed(t *testing.T) {
+	if added, err := addAlarm(&failedTx{}, &resource.Alarm{Name: resource.ThresholdNameNodeOffline}); err == nil || added {
+		t.Errorf("add alarm should fail when check duplication failed")
+	}
+}
+
+func TestDeleteExpiredAlarms(t *testing.T) {
+	tx := &alarmTx{}
+	now := time.Date(2020, 6, 30, 12, 0, 0, 0, time.UTC)
+	if err := deleteExpiredAlarms(tx, now, DefaultAlarmValidPeriod); err != nil {
+		t.Fatalf("delete expired alarms failed: %s", err.Error())
+	}
+
+	if tx.execSql != "delete from gr_alarm where create_time < $1" || len(tx.execArgs) != 1 {
+		t.Fatalf("unexpected sql %s with args %v", tx.execSql, tx.execArgs)
+	}
+
+	if before := tx.execArgs[0].(time.Time); before.Equal(time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)) == false {
+		t.Errorf("alarms before 90 days ago should be deleted but get %s", before)
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/mail"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	alarmMailAttempts = 3
+	alarmMailBackoff  = 10 * time.Second
+)
+
+var alarmMailTemplate = template.Must(template.New("alarm").Parse(`<table border="1" cellspacing="0" cellpadding="4">
+<tr><td>name</td><td>{{.Alarm.Name}}</td></tr>
+<tr><td>level</td><td>{{.Alarm.Level}}</td></tr>
+<tr><td>time</td><td>{{.Time}}</td></tr>
+{{if .Alarm.NodeIp}}<tr><td>nodeIp</td><td>{{.Alarm.NodeIp}}</td></tr>
+{{end}}{{if ne .Alarm.ThresholdType "trigger"}}<tr><td>value</td><td>{{.Alarm.Value}}</td></tr>
+<tr><td>threshold</td><td>{{.Alarm.Threshold}}</td></tr>
+{{end}}{{if .Alarm.HaCmd}}<tr><td>haCmd</td><td>{{.Alarm.HaCmd}}</td></tr>
+<tr><td>masterIp</td><td>{{.Alarm.MasterIp}}</td></tr>
+<tr><td>slaveIp</td><td>{{.Alarm.SlaveIp}}</td></tr>
+{{end}}</table>`))
+
+//SendAlarmMail sends alarm to all mail receivers by the enabled mail sender,
+//it does nothing if no mail sender is enabled or no receiver exists
+func SendAlarmMail(alarm *resource.Alarm) error {
+	conf, err := GetMailConfig()
+	if err != nil || conf == nil {
+		return err
+	}
+
+	receivers, err := getMailReceiverAddresses()
+	if err != nil || len(receivers) == 0 {
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := alarmMailTemplate.Execute(&body, map[string]interface{}{
+		"Alarm": alarm,
+		"Time":  time.Now().Format(util.TimeFormat),
+	}); err != nil {
+		return fmt.Errorf("render alarm %s mail failed: %s", alarm.Name, err.Error())
+	}
+
+	return mail.SendWithRetry(conf, &mail.Message{
+		To:       receivers,
+		Subject:  fmt.Sprintf("DDI %s alarm: %s", alarm.Level, alarm.Name),
+		HTMLBody: body.String(),
+	}, alarmMailAttempts, alarmMailBackoff)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+var (
+	WSUntreatedAlarmPath = "/apis/ws.linkingthing.com/v1/untreatedalarm"
+)
+
+func (h *AlarmHandler) RegisterWSHandler(router gin.IRoutes) {
+	router.GET(WSUntreatedAlarmPath, func(c *gin.Context) {
+		h.OpenUntreatedAlarm(c.Request, c.Writer)
+	})
+}
+
+//OpenUntreatedAlarm sends count of untreated alarms when connected and
+//whenever an alarm is added, updated or expired
+func (h *AlarmHandler) OpenUntreatedAlarm(r *http.Request, w http.ResponseWriter) {
+	conn, err := websocket.Upgrade(w, r, nil, 0, 0)
+	if err != nil {
+		log.Warnf("OpenUntreatedAlarm websocket upgrade failed %s", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	countLock.Lock()
+	version := countVersion
+	countLock.Unlock()
+	for {
+		if count, err := GetUntreatedAlarmCount(); err != nil {
+			log.Warnf("get untreated alarm count failed: %s", err.Error())
+		} else if err := conn.WriteJSON(&resource.UntreatedAlarm{Count: count}); err != nil {
+			if util.IsBrokenPipeErr(err) == false {
+				log.Warnf("send untreated alarm websocket failed: %s", err.Error())
+			}
+			break
+		}
+
+		countLock.Lock()
+		for version == countVersion {
+			countCond.Wait()
+		}
+		version = countVersion
+		countLock.Unlock()
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"fmt"
+	netmail "net/mail"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+)
+
+type MailReceiverHandler struct{}
+
+func NewMailReceiverHandler() *MailReceiverHandler {
+	return &MailReceiverHandler{}
+}
+
+func (h *MailReceiverHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	receiver := ctx.Resource.(*resource.MailReceiver)
+	if _, err := netmail.ParseAddress(receiver.Address); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat,
+			fmt.Sprintf("address %s is not a valid mail address", receiver.Address))
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Insert(receiver)
+		return err
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("insert mail receiver %s into db failed: %s", receiver.Name, err.Error()))
+	}
+
+	return receiver, nil
+}
+
+func (h *MailReceiverHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	receiver := ctx.Resource.(*resource.MailReceiver)
+	if _, err := netmail.ParseAddress(receiver.Address); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat,
+			fmt.Sprintf("address %s is not a valid mail address", receiver.Address))
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Update(resource.TableMailReceiver, map[string]interface{}{
+			"address": receiver.Address,
+		}, map[string]interface{}{restdb.IDField: receiver.GetID()})
+		return err
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("update mail receiver %s failed: %s", receiver.GetID(), err.Error()))
+	}
+
+	return receiver, nil
+}
+
+func (h *MailReceiverHandler) Delete(ctx *restresource.Context) *resterror.APIError {
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Delete(resource.TableMailReceiver, map[string]interface{}{restdb.IDField: ctx.Resource.GetID()})
+		return err
+	}); err != nil {
+		return resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("delete mail receiver %s from db failed: %s", ctx.Resource.GetID(), err.Error()))
+	}
+
+	return nil
+}
+
+func (h *MailReceiverHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	var receivers []*resource.MailReceiver
+	if err := db.GetResources(map[string]interface{}{restdb.IDField: ctx.Resource.GetID()}, &receivers); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("get mail receiver %s from db failed: %s", ctx.Resource.GetID(), err.Error()))
+	}
+
+	if len(receivers) == 0 {
+		return nil, resterror.NewAPIError(resterror.NotFound,
+			fmt.Sprintf("mail receiver %s is non-exists", ctx.Resource.GetID()))
+	}
+
+	return receivers[0], nil
+}
+
+func (h *MailReceiverHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	var receivers []*resource.MailReceiver
+	if err := db.GetResources(map[string]interface{}{"orderby": "create_time"}, &receivers); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("list mail receivers from db failed: %s", err.Error()))
+	}
+
+	return receivers, nil
+}
+
+func getMailReceiverAddresses() ([]string, error) {
+	var receivers []*resource.MailReceiver
+	if err := db.GetResources(map[string]interface{}{}, &receivers); err != nil {
+		return nil, fmt.Errorf("list mail receivers from db failed: %s", err.Error())
+	}
+
+	addresses := make([]string, 0, len(receivers))
+	for _, receiver := range receivers {
+		addresses = append(addresses, receiver.Address)
+	}
+	return addresses, nil
+}
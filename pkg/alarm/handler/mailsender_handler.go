@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"fmt"
+	netmail "net/mail"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/mail"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+//gMailSenderKey encrypts password of mail sender saved in db
+var gMailSenderKey []byte
+
+//InitMailSenderKey loads key of mail sender password from key file, it
+//should be called before any mail is sent
+func InitMailSenderKey(keyFile string) error {
+	key, err := util.LoadKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	gMailSenderKey = key
+	return nil
+}
+
+type MailSenderHandler struct{}
+
+func NewMailSenderHandler() *MailSenderHandler {
+	return &MailSenderHandler{}
+}
+
+//Create only one mail sender is allowed
+func (h *MailSenderHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	sender := ctx.Resource.(*resource.MailSender)
+	if err := checkMailSender(sender); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	password, err := util.EncryptWithNonce(gMailSenderKey, sender.Password)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("encrypt password failed: %s", err.Error()))
+	}
+
+	sender.Password = password
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if exists, err := tx.Exists(resource.TableMailSender, map[string]interface{}{}); err != nil {
+			return err
+		} else if exists {
+			return fmt.Errorf("mail sender already exists")
+		}
+
+		_, err := tx.Insert(sender)
+		return err
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("insert mail sender %s into db failed: %s", sender.Username, err.Error()))
+	}
+
+	sender.Password = ""
+	return sender, nil
+}
+
+func checkMailSender(sender *resource.MailSender) error {
+	if _, err := netmail.ParseAddress(sender.Username); err != nil {
+		return fmt.Errorf("username %s is not a valid mail address", sender.Username)
+	}
+
+	if sender.Port <= 0 || sender.Port > 65535 {
+		return fmt.Errorf("port %d is invalid", sender.Port)
+	}
+
+	return nil
+}
+
+func (h *MailSenderHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	sender := ctx.Resource.(*resource.MailSender)
+	if err := checkMailSender(sender); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	password, err := util.EncryptWithNonce(gMailSenderKey, sender.Password)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("encrypt password failed: %s", err.Error()))
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if c, err := tx.Update(resource.TableMailSender, map[string]interface{}{
+			"username": sender.Username,
+			"password": password,
+			"host":     sender.Host,
+			"port":     sender.Port,
+			"enabled":  sender.Enabled,
+		}, map[string]interface{}{restdb.IDField: sender.GetID()}); err != nil {
+			return err
+		} else if c == 0 {
+			return fmt.Errorf("mail sender is non-exists")
+		}
+
+		return nil
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("update mail sender %s failed: %s", sender.GetID(), err.Error()))
+	}
+
+	sender.Password = ""
+	return sender, nil
+}
+
+func (h *MailSenderHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	var senders []*resource.MailSender
+	if err := db.GetResources(map[string]interface{}{restdb.IDField: ctx.Resource.GetID()}, &senders); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("get mail sender %s from db failed: %s", ctx.Resource.GetID(), err.Error()))
+	}
+
+	if len(senders) == 0 {
+		return nil, resterror.NewAPIError(resterror.NotFound,
+			fmt.Sprintf("mail sender %s is non-exists", ctx.Resource.GetID()))
+	}
+
+	senders[0].Password = ""
+	return senders[0], nil
+}
+
+func (h *MailSenderHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	var senders []*resource.MailSender
+	if err := db.GetResources(map[string]interface{}{"orderby": "create_time"}, &senders); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("list mail senders from db failed: %s", err.Error()))
+	}
+
+	for _, sender := range senders {
+		sender.Password = ""
+	}
+	return senders, nil
+}
+
+func (h *MailSenderHandler) Action(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	switch ctx.Resource.GetAction().Name {
+	case resource.ActionTestMail:
+		return h.testMail(ctx)
+	default:
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("action %s is unknown", ctx.Resource.GetAction().Name))
+	}
+}
+
+//testMail sends mail by the saved sender whether it is enabled or not, so
+//settings can be verified before enabling
+func (h *MailSenderHandler) testMail(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	input := ctx.Resource.GetAction().Input.(*resource.TestMail)
+	var senders []*resource.MailSender
+	if err := db.GetResources(map[string]interface{}{restdb.IDField: ctx.Resource.GetID()}, &senders); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("get mail sender %s from db failed: %s", ctx.Resource.GetID(), err.Error()))
+	}
+
+	if len(senders) == 0 {
+		return nil, resterror.NewAPIError(resterror.NotFound,
+			fmt.Sprintf("mail sender %s is non-exists", ctx.Resource.GetID()))
+	}
+
+	conf, err := senderToMailConfig(senders[0])
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	receivers := []string{input.Receiver}
+	if input.Receiver == "" {
+		if receivers, err = getMailReceiverAddresses(); err != nil {
+			return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+		}
+	}
+
+	if err := mail.Send(conf, &mail.Message{
+		To:       receivers,
+		Subject:  "DDI test mail",
+		HTMLBody: "<p>This is a test mail to verify the mail sender settings.</p>",
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return input, nil
+}
+
+func senderToMailConfig(sender *resource.MailSender) (*mail.Config, error) {
+	password, err := util.DecryptWithNonce(gMailSenderKey, sender.Password)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt password of mail sender failed: %s", err.Error())
+	}
+
+	return &mail.Config{
+		Host:     sender.Host,
+		Port:     sender.Port,
+		Username: sender.Username,
+		Password: password,
+		From:     sender.Username,
+	}, nil
+}
+
+//GetMailConfig returns config of the enabled mail sender, nil if there is
+//no mail sender enabled
+func GetMailConfig() (*mail.Config, error) {
+	var senders []*resource.MailSender
+	if err := db.GetResources(map[string]interface{}{"enabled": true}, &senders); err != nil {
+		return nil, fmt.Errorf("get mail sender from db failed: %s", err.Error())
+	}
+
+	if len(senders) == 0 {
+		return nil, nil
+	}
+
+	return senderToMailConfig(senders[0])
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+)
+
+type ThresholdHandler struct{}
+
+func NewThresholdHandler() (*ThresholdHandler, error) {
+	if err := initDefaultThresholds(); err != nil {
+		return nil, err
+	}
+
+	return &ThresholdHandler{}, nil
+}
+
+func initDefaultThresholds() error {
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		for _, threshold := range resource.DefaultThresholds {
+			id := thresholdID(threshold.Name)
+			if exists, err := tx.Exists(resource.TableThreshold, map[string]interface{}{restdb.IDField: id}); err != nil {
+				return fmt.Errorf("check threshold %s failed: %s", threshold.Name, err.Error())
+			} else if exists {
+				continue
+			}
+
+			t := *threshold
+			t.SetID(id)
+			if _, err := tx.Insert(&t); err != nil {
+				return fmt.Errorf("insert threshold %s into db failed: %s", threshold.Name, err.Error())
+			}
+		}
+
+		return nil
+	})
+}
+
+func thresholdID(name resource.ThresholdName) string {
+	return strings.ToLower(string(name))
+}
+
+func (h *ThresholdHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	threshold := ctx.Resource.(*resource.Threshold)
+	defaultThreshold, ok := resource.GetDefaultThreshold(threshold.Name)
+	if ok == false {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, fmt.Sprintf("unknown threshold %s", threshold.Name))
+	}
+
+	threshold.Level = defaultThreshold.Level
+	threshold.ThresholdType = defaultThreshold.ThresholdType
+	if err := checkThresholdValue(threshold); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	threshold.SetID(thresholdID(threshold.Name))
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if _, err := tx.Insert(threshold); err != nil {
+			return fmt.Errorf("insert threshold %s into db failed: %s", threshold.Name, err.Error())
+		}
+
+		return nil
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return threshold, nil
+}
+
+func checkThresholdValue(threshold *resource.Threshold) error {
+	switch threshold.ThresholdType {
+	case resource.ThresholdTypeRatio:
+		if threshold.Value == 0 || threshold.Value > 100 {
+			return fmt.Errorf("value of threshold %s should be in [1, 100]", threshold.Name)
+		}
+	case resource.ThresholdTypeValues:
+		if threshold.Value == 0 {
+			return fmt.Errorf("value of threshold %s should be greater than 0", threshold.Name)
+		}
+	case resource.ThresholdTypeTrigger:
+		threshold.Value = 0
+	}
+
+	return nil
+}
+
+func (h *ThresholdHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	newThreshold := ctx.Resource.(*resource.Threshold)
+	threshold, err := getThreshold(newThreshold.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.NotFound, err.Error())
+	}
+
+	threshold.Value = newThreshold.Value
+	threshold.SendMail = newThreshold.SendMail
+	if err := checkThresholdValue(threshold); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if _, err := tx.Update(resource.TableThreshold, map[string]interface{}{
+			"value":     threshold.Value,
+			"send_mail": threshold.SendMail,
+		}, map[string]interface{}{restdb.IDField: threshold.GetID()}); err != nil {
+			return fmt.Errorf("update threshold %s failed: %s", threshold.GetID(), err.Error())
+		}
+
+		return nil
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return threshold, nil
+}
+
+//Delete disables the alarm item, it is enabled by creating threshold again
+func (h *ThresholdHandler) Delete(ctx *restresource.Context) *resterror.APIError {
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Delete(resource.TableThreshold, map[string]interface{}{restdb.IDField: ctx.Resource.GetID()})
+		return err
+	}); err != nil {
+		return resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("delete threshold %s from db failed: %s", ctx.Resource.GetID(), err.Error()))
+	}
+
+	return nil
+}
+
+func (h *ThresholdHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	threshold, err := getThreshold(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.NotFound, err.Error())
+	}
+
+	return threshold, nil
+}
+
+func (h *ThresholdHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	var thresholds []*resource.Threshold
+	if err := db.GetResources(map[string]interface{}{"orderby": "create_time"}, &thresholds); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("list thresholds from db failed: %s", err.Error()))
+	}
+
+	return thresholds, nil
+}
+
+func getThreshold(id string) (*resource.Threshold, error) {
+	var thresholds []*resource.Threshold
+	if err := db.GetResources(map[string]interface{}{restdb.IDField: id}, &thresholds); err != nil {
+		return nil, fmt.Errorf("get threshold %s from db failed: %s", id, err.Error())
+	}
+
+	if len(thresholds) == 0 {
+		return nil, fmt.Errorf("threshold %s is non-exists", id)
+	}
+
+	return thresholds[0], nil
+}
+
+//GetThresholds returns enabled thresholds by name
+func GetThresholds() (map[resource.ThresholdName]*resource.Threshold, error) {
+	var thresholds []*resource.Threshold
+	if err := db.GetResources(map[string]interface{}{}, &thresholds); err != nil {
+		return nil, fmt.Errorf("list thresholds from db failed: %s", err.Error())
+	}
+
+	thresholdMap := make(map[resource.ThresholdName]*resource.Threshold, len(thresholds))
+	for _, threshold := range thresholds {
+		thresholdMap[threshold.Name] = threshold
+	}
+
+	return thresholdMap, nil
+}
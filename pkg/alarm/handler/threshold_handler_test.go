@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm/resource"
+)
+
+func TestCheckThresholdValue(t *testing.T) {
+	cases := []struct {
+		thresholdType resource.ThresholdType
+		value         uint64
+		valid         bool
+		expected      uint64
+	}{
+		{resource.ThresholdTypeRatio, 0, false, 0},
+		{resource.ThresholdTypeRatio, 1, true, 1},
+		{resource.ThresholdTypeRatio, 100, true, 100},
+		{resource.ThresholdTypeRatio, 101, false, 101},
+		{resource.ThresholdTypeValues, 0, false, 0},
+		{resource.ThresholdTypeValues, 1000, true, 1000},
+		{resource.ThresholdTypeTrigger, 5, true, 0},
+	}
+
+	for _, c := range cases {
+		threshold := &resource.Threshold{Name: "test", ThresholdType: c.thresholdType, Value: c.value}
+		if err := checkThresholdValue(threshold); (err == nil) != c.valid {
+			t.Errorf("%s threshold with value %d expected valid %v but get %v", c.thresholdType, c.value, c.valid, err)
+		}
+
+		if threshold.Value != c.expected {
+			t.Errorf("%s threshold value expected %d but get %d", c.thresholdType, c.expected, threshold.Value)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package resource
+
+import (
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+type AlarmState string
+
+const (
+	AlarmStateUntreated AlarmState = "untreated"
+	AlarmStateSolved    AlarmState = "solved"
+	AlarmStateIgnored   AlarmState = "ignored"
+)
+
+//Alarm only state is updatable, value and threshold are percent for ratio
+//alarm and count for values alarm
+type Alarm struct {
+	restresource.ResourceBase `json:",inline"`
+	Name                      ThresholdName  `json:"name" rest:"description=readonly"`
+	Level                     ThresholdLevel `json:"level" rest:"description=readonly"`
+	ThresholdType             ThresholdType  `json:"thresholdType" rest:"description=readonly"`
+	State                     AlarmState     `json:"state" rest:"required=true,options=untreated|solved|ignored"`
+	NodeIp                    string         `json:"nodeIp" rest:"description=readonly"`
+	Value                     uint64         `json:"value" rest:"description=readonly"`
+	Threshold                 uint64         `json:"threshold" rest:"description=readonly"`
+	MasterIp                  string         `json:"masterIp" rest:"description=readonly"`
+	SlaveIp                   string         `json:"slaveIp" rest:"description=readonly"`
+	HaCmd                     string         `json:"haCmd" rest:"description=readonly"`
+}
+
+var TableAlarm = restdb.ResourceDBType(&Alarm{})
+
+type UntreatedAlarm struct {
+	Count uint64 `json:"untreatedCount"`
+}
@@ -0,0 +1,43 @@
+package resource
+
+import (
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+const ActionTestMail = "testmail"
+
+//MailSender password is stored encrypted and not returned, username is used
+//as the from address
+type MailSender struct {
+	restresource.ResourceBase `json:",inline"`
+	Username                  string `json:"username" rest:"required=true"`
+	Password                  string `json:"password" rest:"required=true"`
+	Host                      string `json:"host" rest:"required=true"`
+	Port                      int    `json:"port" rest:"required=true"`
+	Enabled                   bool   `json:"enabled"`
+}
+
+var TableMailSender = restdb.ResourceDBType(&MailSender{})
+
+//TestMail is sent to Receiver, or to all mail receivers if it is empty
+type TestMail struct {
+	Receiver string `json:"receiver"`
+}
+
+func (s MailSender) GetActions() []restresource.Action {
+	return []restresource.Action{
+		restresource.Action{
+			Name:  ActionTestMail,
+			Input: &TestMail{},
+		},
+	}
+}
+
+type MailReceiver struct {
+	restresource.ResourceBase `json:",inline"`
+	Name                      string `json:"name" rest:"required=true" db:"uk"`
+	Address                   string `json:"address" rest:"required=true"`
+}
+
+var TableMailReceiver = restdb.ResourceDBType(&MailReceiver{})
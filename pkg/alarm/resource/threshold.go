@@ -0,0 +1,68 @@
+package resource
+
+import (
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+type ThresholdName string
+
+const (
+	ThresholdNameCPUUsedRatio    ThresholdName = "cpuUsedRatio"
+	ThresholdNameMemoryUsedRatio ThresholdName = "memoryUsedRatio"
+	ThresholdNameNodeOffline     ThresholdName = "nodeOffline"
+	ThresholdNameHATrigger       ThresholdName = "haTrigger"
+	ThresholdNameWebsiteAbnormal ThresholdName = "websiteAbnormal"
+	ThresholdNameVIPUsedRatio    ThresholdName = "vipUsedRatio"
+)
+
+type ThresholdLevel string
+
+const (
+	ThresholdLevelCritical ThresholdLevel = "critical"
+	ThresholdLevelMajor    ThresholdLevel = "major"
+	ThresholdLevelMinor    ThresholdLevel = "minor"
+	ThresholdLevelWarning  ThresholdLevel = "warning"
+)
+
+type ThresholdType string
+
+const (
+	ThresholdTypeRatio   ThresholdType = "ratio"
+	ThresholdTypeValues  ThresholdType = "values"
+	ThresholdTypeTrigger ThresholdType = "trigger"
+)
+
+//Threshold id is the lowercase of name, so each alarm item has one threshold,
+//value of ratio threshold is a percent
+type Threshold struct {
+	restresource.ResourceBase `json:",inline"`
+	Name                      ThresholdName  `json:"name" rest:"required=true,options=cpuUsedRatio|memoryUsedRatio|nodeOffline|haTrigger|websiteAbnormal|vipUsedRatio"`
+	Level                     ThresholdLevel `json:"level" rest:"description=readonly"`
+	ThresholdType             ThresholdType  `json:"thresholdType" rest:"description=readonly"`
+	Value                     uint64         `json:"value"`
+	SendMail                  bool           `json:"sendMail"`
+}
+
+var TableThreshold = restdb.ResourceDBType(&Threshold{})
+
+//DefaultThresholds are created when controller starts if not exist, level and
+//type of threshold are fixed by its name
+var DefaultThresholds = []*Threshold{
+	&Threshold{Name: ThresholdNameCPUUsedRatio, Level: ThresholdLevelMajor, ThresholdType: ThresholdTypeRatio, Value: 80},
+	&Threshold{Name: ThresholdNameMemoryUsedRatio, Level: ThresholdLevelMajor, ThresholdType: ThresholdTypeRatio, Value: 80},
+	&Threshold{Name: ThresholdNameNodeOffline, Level: ThresholdLevelCritical, ThresholdType: ThresholdTypeTrigger},
+	&Threshold{Name: ThresholdNameHATrigger, Level: ThresholdLevelCritical, ThresholdType: ThresholdTypeTrigger},
+	&Threshold{Name: ThresholdNameWebsiteAbnormal, Level: ThresholdLevelMajor, ThresholdType: ThresholdTypeValues, Value: 1},
+	&Threshold{Name: ThresholdNameVIPUsedRatio, Level: ThresholdLevelMinor, ThresholdType: ThresholdTypeRatio, Value: 90},
+}
+
+func GetDefaultThreshold(name ThresholdName) (*Threshold, bool) {
+	for _, threshold := range DefaultThresholds {
+		if threshold.Name == name {
+			return threshold, true
+		}
+	}
+
+	return nil, false
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/authorization"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/handler"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
 )
 
 var (
@@ -41,6 +42,8 @@ func RegisterHandler(apiServer *gorest.Server, router gin.IRoutes) error {
 	apiServer.Schemas.MustImport(&Version, resource.Role{}, roleHandler)
 	apiServer.Schemas.MustImport(&Version, resource.UserGroup{}, handler.NewUserGroupHandler())
 	apiServer.Schemas.MustImport(&Version, resource.WhiteList{}, whiteListHandler)
+	apiServer.Schemas.MustImport(&Version, resource.Session{}, handler.NewSessionHandler())
+	apiServer.Schemas.MustImport(&Version, resource.ApiToken{}, handler.NewApiTokenHandler())
 	return nil
 }
 
@@ -51,5 +54,18 @@ func PersistentResources() []restresource.Resource {
 		&resource.UserGroup{},
 		&resource.UserRole{},
 		&resource.WhiteList{},
+		&resource.Session{},
+		&resource.ApiToken{},
+	}
+}
+
+//Migrations returns columns added to tables of old version
+func Migrations() []db.Migration {
+	return []db.Migration{
+		db.Migration{Resource: &resource.Ddiuser{}, Columns: []string{"must_change_password",
+			"password_history", "password_changed_time", "totp_enabled", "totp_secret", "totp_last_step",
+			"recovery_codes", "source"}},
+		db.Migration{Resource: &resource.Role{}, Columns: []string{"business_operations", "web_groups",
+			"require_two_factor"}},
 	}
 }
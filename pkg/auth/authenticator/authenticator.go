@@ -0,0 +1,126 @@
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials = errors.New("user or password is incorrect")
+	//ErrUnavailable means user can't be authenticated because of failure of
+	//db or external source, it isn't a failed login
+	ErrUnavailable = errors.New("authentication is unavailable, please retry later")
+)
+
+//Identity is the user verified by an authenticator, user groups and roles
+//are names, they are only used to provision external user
+type Identity struct {
+	UserName   string
+	Source     string
+	UserGroups []string
+	Roles      []string
+}
+
+//Authenticator returns ErrUserNotFound if it doesn't know the user, so the
+//next authenticator will be tried
+type Authenticator interface {
+	Name() string
+	Authenticate(userName, password string) (*Identity, error)
+}
+
+var gAuthenticators []Authenticator
+
+//Init creates authenticators by config, local users are always checked first,
+//oidc isn't in the chain since it doesn't use password
+func Init(conf *config.DDIControllerConfig) error {
+	authenticators := []Authenticator{&localAuthenticator{}}
+	if conf.LDAP.Enabled {
+		ldapAuthenticator, err := newLDAPAuthenticator(&conf.LDAP)
+		if err != nil {
+			return fmt.Errorf("init ldap authenticator failed: %s", err.Error())
+		}
+		authenticators = append(authenticators, ldapAuthenticator)
+	}
+
+	if conf.OIDC.Enabled {
+		oidcAuthenticator, err := newOIDCAuthenticator(&conf.OIDC)
+		if err != nil {
+			return fmt.Errorf("init oidc authenticator failed: %s", err.Error())
+		}
+		gOIDCAuthenticator = oidcAuthenticator
+	}
+
+	gAuthenticators = authenticators
+	return nil
+}
+
+//Authenticate tries authenticators in order, the user authenticated by an
+//external authenticator is created or updated in db. the reason of failure
+//isn't returned, so nobody can tell whether the user exists, but failure of
+//db or external source returns ErrUnavailable
+func Authenticate(userName, password string) error {
+	for _, authenticator := range gAuthenticators {
+		identity, err := authenticator.Authenticate(userName, password)
+		if err == ErrUserNotFound {
+			continue
+		} else if err == ErrInvalidCredentials {
+			return ErrInvalidCredentials
+		} else if err != nil {
+			log.Warnf("%s authenticate user %s failed: %s", authenticator.Name(), userName, err.Error())
+			return ErrUnavailable
+		}
+
+		if identity.Source != resource.UserSourceLocal {
+			if err := handler.ProvisionUser(identity.UserName, identity.Source,
+				identity.UserGroups, identity.Roles); err != nil {
+				log.Warnf("provision %s user %s failed: %s", identity.Source, userName, err.Error())
+				return ErrUnavailable
+			}
+		}
+
+		return nil
+	}
+
+	return ErrInvalidCredentials
+}
+
+//MapGroups returns user groups and roles of the external groups, group of
+//mapping matches either the whole group or its cn case insensitively
+func MapGroups(mappings []config.GroupMapping, groups []string, groupName func(string) string) ([]string, []string) {
+	var userGroups, roles []string
+	for _, group := range groups {
+		name := groupName(group)
+		for _, mapping := range mappings {
+			if strings.EqualFold(mapping.Group, group) || strings.EqualFold(mapping.Group, name) {
+				userGroups = appendUnique(userGroups, mapping.UserGroups...)
+				roles = appendUnique(roles, mapping.Roles...)
+			}
+		}
+	}
+
+	return userGroups, roles
+}
+
+func appendUnique(values []string, newValues ...string) []string {
+	for _, newValue := range newValues {
+		exists := false
+		for _, value := range values {
+			if value == newValue {
+				exists = true
+				break
+			}
+		}
+		if exists == false {
+			values = append(values, newValue)
+		}
+	}
+	return values
+}
@@ -0,0 +1,78 @@
+package authenticator
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authenticator/ldap"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+func TestMapGroups(t *testing.T) {
+	mappings := []config.GroupMapping{
+		{Group: "cn=ops,ou=groups,dc=linkingthing,dc=com", UserGroups: []string{"operators"}, Roles: []string{"dns"}},
+		{Group: "DEV", UserGroups: []string{"developers"}, Roles: []string{"dns", "dhcp"}},
+		{Group: "qa", UserGroups: []string{"testers"}},
+	}
+
+	userGroups, roles := MapGroups(mappings, []string{
+		"CN=ops,OU=groups,DC=linkingthing,DC=com",
+		"cn=dev,ou=groups,dc=linkingthing,dc=com",
+	}, ldap.GroupCN)
+
+	if expected := []string{"operators", "developers"}; reflect.DeepEqual(userGroups, expected) == false {
+		t.Errorf("user groups expected %v but get %v", expected, userGroups)
+	}
+
+	if expected := []string{"dns", "dhcp"}; reflect.DeepEqual(roles, expected) == false {
+		t.Errorf("roles expected %v but get %v", expected, roles)
+	}
+}
+
+type fakeAuthenticator struct {
+	err error
+}
+
+func (a *fakeAuthenticator) Name() string {
+	return "fake"
+}
+
+func (a *fakeAuthenticator) Authenticate(userName, password string) (*Identity, error) {
+	return nil, a.err
+}
+
+func TestAuthenticateFailures(t *testing.T) {
+	unavailable := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		errs     []error
+		expected error
+	}{
+		{"wrong password", []error{ErrInvalidCredentials}, ErrInvalidCredentials},
+		{"unknown user", []error{ErrUserNotFound, ErrUserNotFound}, ErrInvalidCredentials},
+		{"wrong password of external user", []error{ErrUserNotFound, ErrInvalidCredentials}, ErrInvalidCredentials},
+		{"db failed", []error{unavailable}, ErrUnavailable},
+		{"external source failed", []error{ErrUserNotFound, unavailable}, ErrUnavailable},
+	}
+
+	defer func(authenticators []Authenticator) { gAuthenticators = authenticators }(gAuthenticators)
+	for _, tt := range tests {
+		gAuthenticators = nil
+		for _, err := range tt.errs {
+			gAuthenticators = append(gAuthenticators, &fakeAuthenticator{err: err})
+		}
+
+		if err := Authenticate("alice", "secret"); err != tt.expected {
+			t.Errorf("%s: expected %v but get %v", tt.name, tt.expected, err)
+		}
+	}
+}
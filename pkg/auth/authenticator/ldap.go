@@ -0,0 +1,52 @@
+package authenticator
+
+import (
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authenticator/ldap"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+type ldapAuthenticator struct {
+	client   *ldap.Client
+	mappings []config.GroupMapping
+}
+
+func newLDAPAuthenticator(conf *config.LDAPConf) (*ldapAuthenticator, error) {
+	client, err := ldap.NewClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ldapAuthenticator{client: client, mappings: conf.GroupMappings}, nil
+}
+
+func (a *ldapAuthenticator) Name() string {
+	return resource.UserSourceLDAP
+}
+
+//Authenticate maps ldap groups by group mappings, and an ldap group is also
+//mapped to the user group with the same name as its cn
+func (a *ldapAuthenticator) Authenticate(userName, password string) (*Identity, error) {
+	user, err := a.client.Authenticate(userName, password)
+	switch err {
+	case nil:
+	case ldap.ErrUserNotFound:
+		return nil, ErrUserNotFound
+	case ldap.ErrInvalidCredentials:
+		return nil, ErrInvalidCredentials
+	default:
+		return nil, err
+	}
+
+	userGroups, roles := MapGroups(a.mappings, user.Groups, ldap.GroupCN)
+	for _, group := range user.Groups {
+		userGroups = appendUnique(userGroups, ldap.GroupCN(group))
+	}
+
+	return &Identity{
+		UserName:   userName,
+		Source:     resource.UserSourceLDAP,
+		UserGroups: userGroups,
+		Roles:      roles,
+	}, nil
+}
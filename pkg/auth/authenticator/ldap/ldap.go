@@ -0,0 +1,236 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+)
+
+const (
+	defaultTimeout        = 10 * time.Second
+	defaultUserFilter     = "(uid=%s)"
+	defaultGroupAttribute = "memberOf"
+	schemeLDAP            = "ldap"
+	schemeLDAPS           = "ldaps"
+)
+
+var (
+	ErrUserNotFound       = errors.New("ldap user not found")
+	ErrInvalidCredentials = errors.New("ldap user or password is incorrect")
+)
+
+type User struct {
+	DN     string
+	Groups []string
+}
+
+//Client connects to server for every authentication, so it doesn't care
+//about reconnection and is safe for concurrent use
+type Client struct {
+	conf      config.LDAPConf
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	startTLS  bool
+}
+
+//NewClient refuses ldap:// without start_tls, since password of user is sent
+//in plaintext by simple bind, unless allow_plaintext is set explicitly
+func NewClient(conf *config.LDAPConf) (*Client, error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("ldap url is empty")
+	}
+
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap url %s is invalid: %s", conf.URL, err.Error())
+	}
+
+	client := &Client{
+		conf:      *conf,
+		timeout:   defaultTimeout,
+		tlsConfig: &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: conf.InsecureSkipVerify},
+	}
+	if conf.CAFile != "" {
+		pem, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ldap ca file failed: %s", err.Error())
+		}
+
+		client.tlsConfig.RootCAs = x509.NewCertPool()
+		if client.tlsConfig.RootCAs.AppendCertsFromPEM(pem) == false {
+			return nil, fmt.Errorf("ldap ca file %s has no certificate", conf.CAFile)
+		}
+	}
+
+	switch u.Scheme {
+	case schemeLDAPS:
+		if conf.StartTLS {
+			return nil, fmt.Errorf("start_tls can't be used with ldaps url %s", conf.URL)
+		}
+	case schemeLDAP:
+		if conf.StartTLS {
+			client.startTLS = true
+		} else if conf.AllowPlaintext == false {
+			return nil, fmt.Errorf("ldap url %s is plaintext, enable start_tls or use ldaps", conf.URL)
+		}
+	default:
+		return nil, fmt.Errorf("ldap url %s should be ldap:// or ldaps://", conf.URL)
+	}
+
+	if conf.Timeout != 0 {
+		client.timeout = time.Duration(conf.Timeout) * time.Second
+	}
+	if client.conf.UserFilter == "" {
+		client.conf.UserFilter = defaultUserFilter
+	}
+	if client.conf.GroupAttribute == "" {
+		client.conf.GroupAttribute = defaultGroupAttribute
+	}
+	if client.conf.GroupBaseDN == "" {
+		client.conf.GroupBaseDN = client.conf.BaseDN
+	}
+
+	if err := checkFilter("user", client.conf.UserFilter); err != nil {
+		return nil, err
+	}
+	if client.conf.GroupFilter != "" {
+		if err := checkFilter("group", client.conf.GroupFilter); err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+func checkFilter(name, filter string) error {
+	if strings.Count(filter, "%s") != 1 {
+		return fmt.Errorf("ldap %s filter %s should contain one %%s", name, filter)
+	}
+
+	if _, err := goldap.CompileFilter(fmt.Sprintf(filter, "user")); err != nil {
+		return fmt.Errorf("ldap %s filter %s is invalid: %s", name, filter, err.Error())
+	}
+
+	return nil
+}
+
+//Authenticate searches user by the bind account then binds as the user, the
+//groups are searched by the bind account too, because user may not be
+//allowed to read groups
+func (c *Client) Authenticate(userName, password string) (*User, error) {
+	//empty password makes an unauthenticated bind which always succeeds
+	if userName == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := c.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+
+	entries, err := c.search(conn, c.conf.BaseDN,
+		fmt.Sprintf(c.conf.UserFilter, goldap.EscapeFilter(userName)), []string{c.conf.GroupAttribute})
+	if err != nil {
+		return nil, fmt.Errorf("search ldap user %s failed: %s", userName, err.Error())
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrUserNotFound
+	} else if len(entries) > 1 {
+		return nil, fmt.Errorf("ldap user %s matches %d entries", userName, len(entries))
+	}
+
+	user := &User{DN: entries[0].DN}
+	if err := conn.Bind(user.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("bind ldap user %s failed: %s", userName, err.Error())
+	}
+
+	if c.conf.GroupFilter == "" {
+		user.Groups = entries[0].GetEqualFoldAttributeValues(c.conf.GroupAttribute)
+		return user, nil
+	}
+
+	if err := c.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+
+	groups, err := c.search(conn, c.conf.GroupBaseDN,
+		fmt.Sprintf(c.conf.GroupFilter, goldap.EscapeFilter(user.DN)), []string{"cn"})
+	if err != nil {
+		return nil, fmt.Errorf("search groups of ldap user %s failed: %s", userName, err.Error())
+	}
+
+	for _, group := range groups {
+		user.Groups = append(user.Groups, group.DN)
+	}
+	return user, nil
+}
+
+func (c *Client) dial() (*goldap.Conn, error) {
+	conn, err := goldap.DialURL(c.conf.URL, goldap.DialWithDialer(&net.Dialer{Timeout: c.timeout}),
+		goldap.DialWithTLSConfig(c.tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("connect ldap server failed: %s", err.Error())
+	}
+
+	conn.SetTimeout(c.timeout)
+	if c.startTLS {
+		if err := conn.StartTLS(c.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls with ldap server failed: %s", err.Error())
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Client) search(conn *goldap.Conn, baseDN, filter string, attributes []string) ([]*goldap.Entry, error) {
+	result, err := conn.Search(goldap.NewSearchRequest(baseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		0, int(c.timeout/time.Second), false, filter, attributes, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Entries, nil
+}
+
+func (c *Client) bindServiceAccount(conn *goldap.Conn) error {
+	if c.conf.BindDN == "" {
+		return nil
+	}
+
+	if err := conn.Bind(c.conf.BindDN, c.conf.BindPassword); err != nil {
+		return fmt.Errorf("bind ldap account %s failed: %s", c.conf.BindDN, err.Error())
+	}
+
+	return nil
+}
+
+//GroupCN returns value of the first rdn of group dn, or the group itself if
+//it isn't a dn
+func GroupCN(group string) string {
+	dn, err := goldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return strings.TrimSpace(group)
+	}
+
+	return dn.RDNs[0].Attributes[0].Value
+}
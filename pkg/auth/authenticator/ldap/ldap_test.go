@@ -0,0 +1,427 @@
+package ldap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+)
+
+const (
+	testBaseDN      = "dc=linkingthing,dc=com"
+	testBindDN      = "cn=admin,dc=linkingthing,dc=com"
+	testBindPasswd  = "admin-secret"
+	testUserDN      = "uid=alice,ou=people,dc=linkingthing,dc=com"
+	testUserPasswd  = "alice-secret"
+	testOpsGroupDN  = "cn=ops,ou=groups,dc=linkingthing,dc=com"
+	testDevGroupDN  = "cn=dev,ou=groups,dc=linkingthing,dc=com"
+	testOtherUserDN = "uid=bob,ou=people,dc=linkingthing,dc=com"
+	startTLSOID     = "1.3.6.1.4.1.1466.20037"
+)
+
+type testEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+//testServer is an in-process ldap server which supports start tls, simple
+//bind and search with and, or, not, equality and present filters, only the
+//bind account can search
+type testServer struct {
+	listener  net.Listener
+	entries   []*testEntry
+	tlsConfig *tls.Config
+	caFile    string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %s", err.Error())
+	}
+
+	s := &testServer{
+		listener: listener,
+		entries: []*testEntry{
+			{dn: testBindDN, password: testBindPasswd, attributes: map[string][]string{"cn": {"admin"}}},
+			{dn: testUserDN, password: testUserPasswd, attributes: map[string][]string{
+				"objectclass": {"person"},
+				"uid":         {"alice"},
+				"memberof":    {testOpsGroupDN, testDevGroupDN},
+			}},
+			{dn: testOtherUserDN, password: "bob-secret", attributes: map[string][]string{
+				"objectclass": {"person"},
+				"uid":         {"bob"},
+			}},
+			{dn: testOpsGroupDN, attributes: map[string][]string{
+				"objectclass": {"groupOfNames"},
+				"cn":          {"ops"},
+				"member":      {testUserDN, testOtherUserDN},
+			}},
+			{dn: testDevGroupDN, attributes: map[string][]string{
+				"objectclass": {"groupOfNames"},
+				"cn":          {"dev"},
+				"member":      {testUserDN},
+			}},
+		},
+	}
+	s.tlsConfig, s.caFile = newTestCertificate(t)
+	go s.serve()
+	return s
+}
+
+//newTestCertificate returns tls config of a self signed certificate for
+//127.0.0.1 and the pem file of the certificate
+func newTestCertificate(t *testing.T) (*tls.Config, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate failed: %s", err.Error())
+	}
+
+	dir, err := ioutil.TempDir("", "ldap")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("write ca file failed: %s", err.Error())
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, caFile
+}
+
+func (s *testServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testServer) close() {
+	s.listener.Close()
+	os.RemoveAll(filepath.Dir(s.caFile))
+}
+
+func (s *testServer) serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+func (s *testServer) handle(c net.Conn) {
+	defer func() { c.Close() }()
+	var boundDN string
+	for {
+		message, err := ber.ReadPacket(c)
+		if err != nil || len(message.Children) < 2 {
+			return
+		}
+
+		id, _ := message.Children[0].Value.(int64)
+		op := message.Children[1]
+		switch op.Tag {
+		case goldap.ApplicationExtendedRequest:
+			if op.Children[0].Data.String() != startTLSOID {
+				return
+			}
+
+			s.reply(c, id, newTestResult(goldap.ApplicationExtendedResponse, goldap.LDAPResultSuccess, ""))
+			tlsConn := tls.Server(c, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			c = tlsConn
+		case goldap.ApplicationBindRequest:
+			dn, password := op.Children[1].Data.String(), op.Children[2].Data.String()
+			code := uint16(goldap.LDAPResultInvalidCredentials)
+			if entry := s.find(dn); entry != nil && entry.password != "" && entry.password == password {
+				code, boundDN = goldap.LDAPResultSuccess, dn
+			}
+			s.reply(c, id, newTestResult(goldap.ApplicationBindResponse, code, ""))
+		case goldap.ApplicationSearchRequest:
+			if boundDN != testBindDN {
+				s.reply(c, id, newTestResult(goldap.ApplicationSearchResultDone,
+					goldap.LDAPResultInsufficientAccessRights, "insufficient access rights"))
+				continue
+			}
+
+			baseDN, filter := strings.ToLower(op.Children[0].Data.String()), op.Children[6]
+			for _, entry := range s.entries {
+				if strings.HasSuffix(strings.ToLower(entry.dn), baseDN) && matchFilter(entry, filter) {
+					s.reply(c, id, encodeTestEntry(entry))
+				}
+			}
+			s.reply(c, id, newTestResult(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess, ""))
+		case goldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *testServer) find(dn string) *testEntry {
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (s *testServer) reply(c net.Conn, id int64, op *ber.Packet) {
+	message := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	message.AppendChild(op)
+	c.Write(message.Bytes())
+}
+
+func newTestResult(tag ber.Tag, code uint16, message string) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	result.AppendChild(newTestString(""))
+	result.AppendChild(newTestString(message))
+	return result
+}
+
+func newTestString(value string) *ber.Packet {
+	return ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "")
+}
+
+func encodeTestEntry(entry *testEntry) *ber.Packet {
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range entry.attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attribute.AppendChild(newTestString(name))
+		valueSet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, value := range values {
+			valueSet.AppendChild(newTestString(value))
+		}
+		attribute.AppendChild(valueSet)
+		attributes.AppendChild(attribute)
+	}
+
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "")
+	result.AppendChild(newTestString(entry.dn))
+	result.AppendChild(attributes)
+	return result
+}
+
+func matchFilter(entry *testEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) == false {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return matchFilter(entry, filter.Children[0]) == false
+	case goldap.FilterPresent:
+		return len(entry.attributes[strings.ToLower(filter.Data.String())]) != 0
+	case goldap.FilterEqualityMatch:
+		for _, value := range entry.attributes[strings.ToLower(filter.Children[0].Data.String())] {
+			if strings.EqualFold(value, filter.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func newTestClient(t *testing.T, server *testServer, groupFilter string) *Client {
+	client, err := NewClient(&config.LDAPConf{
+		URL:            server.url(),
+		AllowPlaintext: true,
+		BindDN:         testBindDN,
+		BindPassword:   testBindPasswd,
+		BaseDN:         testBaseDN,
+		UserFilter:     "(&(objectClass=person)(uid=%s))",
+		GroupFilter:    groupFilter,
+		Timeout:        5,
+	})
+	if err != nil {
+		t.Fatalf("create ldap client failed: %s", err.Error())
+	}
+	return client
+}
+
+func TestAuthenticateWithMemberOf(t *testing.T) {
+	server := newTestServer(t)
+	defer server.close()
+
+	client := newTestClient(t, server, "")
+	user, err := client.Authenticate("alice", testUserPasswd)
+	if err != nil {
+		t.Fatalf("authenticate alice failed: %s", err.Error())
+	}
+
+	if user.DN != testUserDN {
+		t.Errorf("user dn expected %s but get %s", testUserDN, user.DN)
+	}
+
+	groups := append([]string{}, user.Groups...)
+	sort.Strings(groups)
+	if expected := []string{testDevGroupDN, testOpsGroupDN}; reflect.DeepEqual(groups, expected) == false {
+		t.Errorf("groups expected %v but get %v", expected, groups)
+	}
+}
+
+func TestAuthenticateWithGroupFilter(t *testing.T) {
+	server := newTestServer(t)
+	defer server.close()
+
+	client := newTestClient(t, server, "(&(objectClass=groupOfNames)(member=%s))")
+	user, err := client.Authenticate("bob", "bob-secret")
+	if err != nil {
+		t.Fatalf("authenticate bob failed: %s", err.Error())
+	}
+
+	if reflect.DeepEqual(user.Groups, []string{testOpsGroupDN}) == false {
+		t.Errorf("groups expected [%s] but get %v", testOpsGroupDN, user.Groups)
+	}
+}
+
+func TestAuthenticateFailed(t *testing.T) {
+	server := newTestServer(t)
+	defer server.close()
+
+	client := newTestClient(t, server, "")
+	if _, err := client.Authenticate("alice", "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("wrong password expected %v but get %v", ErrInvalidCredentials, err)
+	}
+
+	if _, err := client.Authenticate("alice", ""); err != ErrInvalidCredentials {
+		t.Errorf("empty password expected %v but get %v", ErrInvalidCredentials, err)
+	}
+
+	if _, err := client.Authenticate("carol", "carol-secret"); err != ErrUserNotFound {
+		t.Errorf("unknown user expected %v but get %v", ErrUserNotFound, err)
+	}
+
+	if _, err := client.Authenticate("*", testUserPasswd); err != ErrUserNotFound {
+		t.Errorf("username should be escaped in filter but get %v", err)
+	}
+
+	client.conf.BindPassword = "wrong"
+	if _, err := client.Authenticate("alice", testUserPasswd); err == nil {
+		t.Errorf("authenticate should fail if bind account is wrong")
+	}
+}
+
+func TestStartTLS(t *testing.T) {
+	server := newTestServer(t)
+	defer server.close()
+
+	conf := &config.LDAPConf{
+		URL:          server.url(),
+		StartTLS:     true,
+		CAFile:       server.caFile,
+		BindDN:       testBindDN,
+		BindPassword: testBindPasswd,
+		BaseDN:       testBaseDN,
+		Timeout:      5,
+	}
+	client, err := NewClient(conf)
+	if err != nil {
+		t.Fatalf("create ldap client failed: %s", err.Error())
+	}
+
+	if user, err := client.Authenticate("alice", testUserPasswd); err != nil {
+		t.Errorf("authenticate alice with start tls failed: %s", err.Error())
+	} else if user.DN != testUserDN {
+		t.Errorf("user dn expected %s but get %s", testUserDN, user.DN)
+	}
+
+	conf.CAFile = ""
+	client, err = NewClient(conf)
+	if err != nil {
+		t.Fatalf("create ldap client failed: %s", err.Error())
+	}
+
+	if _, err := client.Authenticate("alice", testUserPasswd); err == nil {
+		t.Errorf("authenticate should fail if certificate of server isn't trusted")
+	}
+}
+
+func TestNewClientRefusePlaintext(t *testing.T) {
+	for _, conf := range []*config.LDAPConf{
+		{URL: "ldap://127.0.0.1:389"},
+		{URL: "ldaps://127.0.0.1:636", StartTLS: true},
+		{URL: "http://127.0.0.1:389", AllowPlaintext: true},
+		{URL: "ldap://127.0.0.1:389", StartTLS: true, UserFilter: "(uid=%s"},
+		{URL: "ldap://127.0.0.1:389", StartTLS: true, GroupFilter: "(member=*)"},
+	} {
+		if _, err := NewClient(conf); err == nil {
+			t.Errorf("ldap config %+v should be refused", conf)
+		}
+	}
+
+	for _, conf := range []*config.LDAPConf{
+		{URL: "ldap://127.0.0.1:389", StartTLS: true},
+		{URL: "ldap://127.0.0.1:389", AllowPlaintext: true},
+		{URL: "ldaps://127.0.0.1:636"},
+	} {
+		if _, err := NewClient(conf); err != nil {
+			t.Errorf("ldap config %+v should be accepted but get %s", conf, err.Error())
+		}
+	}
+}
+
+func TestGroupCN(t *testing.T) {
+	for group, cn := range map[string]string{
+		testOpsGroupDN:                      "ops",
+		"CN=Domain Admins,CN=Users,DC=corp": "Domain Admins",
+		"ops":                               "ops",
+		"cn=ops\\, eu,ou=groups,dc=corp":    "ops, eu",
+	} {
+		if GroupCN(group) != cn {
+			t.Errorf("cn of %s expected %s but get %s", group, cn, GroupCN(group))
+		}
+	}
+}
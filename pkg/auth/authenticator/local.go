@@ -0,0 +1,24 @@
+package authenticator
+
+import (
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+type localAuthenticator struct{}
+
+func (a *localAuthenticator) Name() string {
+	return resource.UserSourceLocal
+}
+
+func (a *localAuthenticator) Authenticate(userName, password string) (*Identity, error) {
+	if err := handler.CheckPassword(userName, password); err == handler.ErrUserNotFound {
+		return nil, ErrUserNotFound
+	} else if err == handler.ErrPasswordIncorrect {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &Identity{UserName: userName, Source: resource.UserSourceLocal}, nil
+}
@@ -0,0 +1,78 @@
+package authenticator
+
+import (
+	"errors"
+
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authenticator/oidc"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+const OIDCLoginTimeout = oidc.LoginTimeout
+
+var (
+	ErrOIDCDisabled    = errors.New("oidc login isn't enabled")
+	ErrOIDCLoginFailed = errors.New("oidc login failed")
+)
+
+type oidcAuthenticator struct {
+	provider *oidc.Provider
+	mappings []config.GroupMapping
+}
+
+var gOIDCAuthenticator *oidcAuthenticator
+
+func newOIDCAuthenticator(conf *config.OIDCConf) (*oidcAuthenticator, error) {
+	provider, err := oidc.NewProvider(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcAuthenticator{provider: provider, mappings: conf.GroupMappings}, nil
+}
+
+//OIDCLoginURL returns url of identity provider to start oidc login and the
+//state which should be bound to browser of the user
+func OIDCLoginURL() (string, string, error) {
+	if gOIDCAuthenticator == nil {
+		return "", "", ErrOIDCDisabled
+	}
+
+	loginURL, state, err := gOIDCAuthenticator.provider.AuthCodeURL()
+	if err != nil {
+		log.Warnf("get oidc login url failed: %s", err.Error())
+		return "", "", ErrOIDCLoginFailed
+	}
+
+	return loginURL, state, nil
+}
+
+//AuthenticateOIDC exchanges code returned by identity provider, then creates
+//or updates the user like ldap, returns name of the user
+func AuthenticateOIDC(code, state, boundState string) (string, error) {
+	if gOIDCAuthenticator == nil {
+		return "", ErrOIDCDisabled
+	}
+
+	identity, err := gOIDCAuthenticator.provider.Exchange(code, state, boundState)
+	if err == oidc.ErrInvalidState {
+		return "", err
+	} else if err != nil {
+		log.Warnf("oidc authenticate failed: %s", err.Error())
+		return "", ErrOIDCLoginFailed
+	}
+
+	userGroups, roles := MapGroups(gOIDCAuthenticator.mappings, identity.Groups, func(group string) string {
+		return group
+	})
+	userGroups = appendUnique(userGroups, identity.Groups...)
+	if err := handler.ProvisionUser(identity.UserName, resource.UserSourceOIDC, userGroups, roles); err != nil {
+		log.Warnf("provision oidc user %s failed: %s", identity.UserName, err.Error())
+		return "", ErrOIDCLoginFailed
+	}
+
+	return identity.UserName, nil
+}
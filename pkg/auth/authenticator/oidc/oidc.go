@@ -0,0 +1,282 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+)
+
+const (
+	LoginTimeout = 10 * time.Minute
+
+	defaultTimeout          = 10 * time.Second
+	defaultUsernameClaim    = "preferred_username"
+	defaultGroupsClaim      = "groups"
+	maxPendingLogins        = 10000
+	codeChallengeMethodS256 = "S256"
+)
+
+var ErrInvalidState = errors.New("oidc login state is invalid or expired")
+
+type Identity struct {
+	Subject  string
+	UserName string
+	Groups   []string
+}
+
+type pendingLogin struct {
+	nonce        string
+	codeVerifier string
+	expireTime   time.Time
+}
+
+//Provider discovers issuer at the first login, so controller can start even
+//if identity provider is unavailable. nonce and pkce code verifier of every
+//login are kept in memory until the code is exchanged
+type Provider struct {
+	conf config.OIDCConf
+	ctx  context.Context
+
+	lock          sync.Mutex
+	oauth2Config  *oauth2.Config
+	verifier      *gooidc.IDTokenVerifier
+	pendingLogins map[string]*pendingLogin
+}
+
+func NewProvider(conf *config.OIDCConf) (*Provider, error) {
+	if conf.Issuer == "" || conf.ClientID == "" || conf.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc issuer, client id and redirect url are required")
+	}
+
+	timeout := defaultTimeout
+	if conf.Timeout != 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+
+	provider := &Provider{
+		conf:          *conf,
+		ctx:           gooidc.ClientContext(context.Background(), &http.Client{Timeout: timeout}),
+		pendingLogins: make(map[string]*pendingLogin),
+	}
+	if provider.conf.UsernameClaim == "" {
+		provider.conf.UsernameClaim = defaultUsernameClaim
+	}
+	if provider.conf.GroupsClaim == "" {
+		provider.conf.GroupsClaim = defaultGroupsClaim
+	}
+	if len(provider.conf.Scopes) == 0 {
+		provider.conf.Scopes = []string{gooidc.ScopeOpenID}
+	} else if hasScope(provider.conf.Scopes, gooidc.ScopeOpenID) == false {
+		provider.conf.Scopes = append([]string{gooidc.ScopeOpenID}, provider.conf.Scopes...)
+	}
+
+	return provider, nil
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//AuthCodeURL returns url of identity provider which user should be
+//redirected to and the state in it, the state should be bound to browser of
+//the user, it is valid for LoginTimeout
+func (p *Provider) AuthCodeURL() (string, string, error) {
+	oauth2Config, _, err := p.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	var values [3]string
+	for i := range values {
+		if values[i], err = randomString(); err != nil {
+			return "", "", err
+		}
+	}
+
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+	if err := p.addPendingLogin(state, nonce, codeVerifier); err != nil {
+		return "", "", err
+	}
+
+	return oauth2Config.AuthCodeURL(state, gooidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge(codeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", codeChallengeMethodS256)), state, nil
+}
+
+func (p *Provider) addPendingLogin(state, nonce, codeVerifier string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	for s, login := range p.pendingLogins {
+		if now.After(login.expireTime) {
+			delete(p.pendingLogins, s)
+		}
+	}
+
+	if len(p.pendingLogins) >= maxPendingLogins {
+		return fmt.Errorf("too many pending oidc logins")
+	}
+
+	p.pendingLogins[state] = &pendingLogin{
+		nonce:        nonce,
+		codeVerifier: codeVerifier,
+		expireTime:   now.Add(LoginTimeout),
+	}
+	return nil
+}
+
+//takePendingLogin returns the login of state, a state can only be used once
+func (p *Provider) takePendingLogin(state string) (*pendingLogin, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	login, ok := p.pendingLogins[state]
+	if ok == false {
+		return nil, ErrInvalidState
+	}
+
+	delete(p.pendingLogins, state)
+	if time.Now().After(login.expireTime) {
+		return nil, ErrInvalidState
+	}
+	return login, nil
+}
+
+//Exchange redeems code at token endpoint with the pkce code verifier and
+//verifies the returned id token. boundState is the state saved in browser
+//which starts the login, so code of another login can't be used by the user
+func (p *Provider) Exchange(code, state, boundState string) (*Identity, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(boundState)) != 1 {
+		return nil, ErrInvalidState
+	}
+
+	login, err := p.takePendingLogin(state)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == "" {
+		return nil, fmt.Errorf("oidc code is empty")
+	}
+
+	oauth2Config, verifier, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth2Config.Exchange(p.ctx, code, oauth2.SetAuthURLParam("code_verifier", login.codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc token request failed: %s", err.Error())
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("oidc token response has no id token")
+	}
+
+	return p.verifyIDToken(verifier, rawIDToken, login.nonce)
+}
+
+//verifyIDToken checks signature, issuer, audience and expiration by go-oidc,
+//and checks nonce and authorized party which go-oidc leaves to caller
+func (p *Provider) verifyIDToken(verifier *gooidc.IDTokenVerifier, rawIDToken, nonce string) (*Identity, error) {
+	idToken, err := verifier.Verify(p.ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %s", err.Error())
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("id token nonce mismatch")
+	}
+
+	claims := make(map[string]interface{})
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("parse claims of id token failed: %s", err.Error())
+	}
+
+	if azp, ok := claims["azp"].(string); ok && azp != p.conf.ClientID {
+		return nil, fmt.Errorf("id token authorized party %s is unexpected", azp)
+	}
+
+	identity := &Identity{Subject: idToken.Subject}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("id token has no subject")
+	}
+
+	identity.UserName, _ = claims[p.conf.UsernameClaim].(string)
+	if identity.UserName == "" {
+		return nil, fmt.Errorf("id token has no claim %s", p.conf.UsernameClaim)
+	}
+
+	switch groups := claims[p.conf.GroupsClaim].(type) {
+	case string:
+		identity.Groups = []string{groups}
+	case []interface{}:
+		for _, group := range groups {
+			if g, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, g)
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+//discover requires issuer in metadata is the same as the configured one,
+//only asymmetric algorithms the issuer supports are accepted by verifier
+func (p *Provider) discover() (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.oauth2Config != nil {
+		return p.oauth2Config, p.verifier, nil
+	}
+
+	provider, err := gooidc.NewProvider(p.ctx, p.conf.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery failed: %s", err.Error())
+	}
+
+	p.oauth2Config = &oauth2.Config{
+		ClientID:     p.conf.ClientID,
+		ClientSecret: p.conf.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.conf.RedirectURL,
+		Scopes:       p.conf.Scopes,
+	}
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.conf.ClientID})
+	return p.oauth2Config, p.verifier, nil
+}
+
+//codeChallenge is the S256 pkce challenge of code verifier
+func codeChallenge(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+//randomString returns 43 url safe characters, which is also a valid pkce
+//code verifier
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate random string failed: %s", err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,353 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+)
+
+const (
+	testClientID     = "ddi"
+	testClientSecret = "ddi-secret"
+	testRedirectURL  = "https://ddi.example.com/oidc/callback"
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+//authCode is issued by authorize, the id token of it has claims and can
+//only be redeemed with code verifier of the challenge
+type authCode struct {
+	claims        jwt.MapClaims
+	codeChallenge string
+}
+
+type testSigningKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+}
+
+//mockProvider is a local oidc provider, every code issued by authorize
+//returns id token of claims with the nonce of the authorize request
+type mockProvider struct {
+	server *httptest.Server
+	lock   sync.Mutex
+	keys   []*testSigningKey
+	codes  map[string]*authCode
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	m := &mockProvider{codes: make(map[string]*authCode)}
+	m.addRSAKey(t, "rsa-1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	return m
+}
+
+func (m *mockProvider) close() {
+	m.server.Close()
+}
+
+func (m *mockProvider) addRSAKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key failed: %s", err.Error())
+	}
+	m.lock.Lock()
+	m.keys = append(m.keys, &testSigningKey{kid: kid, method: jwt.SigningMethodRS256, private: key})
+	m.lock.Unlock()
+}
+
+func (m *mockProvider) addECKey(t *testing.T, kid string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key failed: %s", err.Error())
+	}
+	m.lock.Lock()
+	m.keys = append(m.keys, &testSigningKey{kid: kid, method: jwt.SigningMethodES256, private: key})
+	m.lock.Unlock()
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                m.server.URL,
+		"authorization_endpoint":                m.server.URL + "/authorize",
+		"token_endpoint":                        m.server.URL + "/token",
+		"jwks_uri":                              m.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256", "ES256", "HS256"},
+	})
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	keySet := &jsonWebKeySet{}
+	for _, key := range m.keys {
+		switch private := key.private.(type) {
+		case *rsa.PrivateKey:
+			keySet.Keys = append(keySet.Keys, jsonWebKey{Kty: "RSA", Kid: key.kid, Use: "sig",
+				N: encodeBigInt(private.N), E: encodeBigInt(big.NewInt(int64(private.E)))})
+		case *ecdsa.PrivateKey:
+			keySet.Keys = append(keySet.Keys, jsonWebKey{Kty: "EC", Kid: key.kid, Crv: "P-256",
+				X: encodeCoordinate(private.X), Y: encodeCoordinate(private.Y)})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keySet)
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok == false {
+		clientID, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if clientID != testClientID || clientSecret != testClientSecret ||
+		r.FormValue("grant_type") != "authorization_code" || r.FormValue("redirect_uri") != testRedirectURL {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.lock.Lock()
+	code, ok := m.codes[r.FormValue("code")]
+	delete(m.codes, r.FormValue("code"))
+	key := m.keys[len(m.keys)-1]
+	m.lock.Unlock()
+	if ok == false || codeChallenge(r.FormValue("code_verifier")) != code.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(key.method, code.claims)
+	token.Header["kid"] = key.kid
+	idToken, _ := token.SignedString(key.private)
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+}
+
+//authorize acts as user login at provider, returns code and state
+func (m *mockProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (string, string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth url %s", authURL)
+	}
+
+	query := u.Query()
+	if query.Get("client_id") != testClientID || query.Get("redirect_uri") != testRedirectURL ||
+		query.Get("response_type") != "code" || strings.Contains(query.Get("scope"), "openid") == false ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+
+	if _, ok := claims["nonce"]; ok == false {
+		claims["nonce"] = query.Get("nonce")
+	}
+	code := "code-" + query.Get("state")
+	m.lock.Lock()
+	m.codes[code] = &authCode{claims: claims, codeChallenge: query.Get("code_challenge")}
+	m.lock.Unlock()
+	return code, query.Get("state")
+}
+
+func (m *mockProvider) claims(userName string, groups ...string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                m.server.URL,
+		"sub":                "id-" + userName,
+		"aud":                testClientID,
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"preferred_username": userName,
+		"groups":             groups,
+	}
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+//encodeCoordinate pads coordinate of P-256 to 32 bytes as jwk requires
+func encodeCoordinate(i *big.Int) string {
+	b := make([]byte, 32)
+	coordinate := i.Bytes()
+	copy(b[len(b)-len(coordinate):], coordinate)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newTestProvider(t *testing.T, m *mockProvider) *Provider {
+	p, err := NewProvider(&config.OIDCConf{
+		Issuer:       m.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"profile", "groups"},
+	})
+	if err != nil {
+		t.Fatalf("create provider failed: %s", err.Error())
+	}
+	return p
+}
+
+func login(t *testing.T, p *Provider, m *mockProvider, claims jwt.MapClaims) (*Identity, error) {
+	authURL, boundState, err := p.AuthCodeURL()
+	if err != nil {
+		t.Fatalf("get auth url failed: %s", err.Error())
+	}
+
+	code, state := m.authorize(t, authURL, claims)
+	return p.Exchange(code, state, boundState)
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.close()
+	p := newTestProvider(t, m)
+
+	identity, err := login(t, p, m, m.claims("alice", "ops", "dev"))
+	if err != nil {
+		t.Fatalf("login failed: %s", err.Error())
+	}
+
+	expected := &Identity{Subject: "id-alice", UserName: "alice", Groups: []string{"ops", "dev"}}
+	if reflect.DeepEqual(identity, expected) == false {
+		t.Errorf("identity expected %v but get %v", expected, identity)
+	}
+
+	authURL, boundState, _ := p.AuthCodeURL()
+	code, state := m.authorize(t, authURL, m.claims("alice"))
+	if _, err := p.Exchange(code, "unknown", "unknown"); err != ErrInvalidState {
+		t.Errorf("unknown state expected %v but get %v", ErrInvalidState, err)
+	}
+	if _, err := p.Exchange(code, state, ""); err != ErrInvalidState {
+		t.Errorf("state without cookie expected %v but get %v", ErrInvalidState, err)
+	}
+	if _, err := p.Exchange(code, state, boundState); err != nil {
+		t.Errorf("login failed: %s", err.Error())
+	}
+	if _, err := p.Exchange(code, state, boundState); err != ErrInvalidState {
+		t.Errorf("state should only be used once but get %v", err)
+	}
+}
+
+func TestExchangeStateBoundToBrowser(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.close()
+	p := newTestProvider(t, m)
+
+	//attacker starts a login and makes victim post the code of attacker
+	_, victimState, _ := p.AuthCodeURL()
+	authURL, _, _ := p.AuthCodeURL()
+	code, state := m.authorize(t, authURL, m.claims("attacker"))
+	if _, err := p.Exchange(code, state, victimState); err != ErrInvalidState {
+		t.Errorf("state not bound to browser expected %v but get %v", ErrInvalidState, err)
+	}
+}
+
+func TestExchangePKCE(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.close()
+	p := newTestProvider(t, m)
+
+	authURL, boundState, _ := p.AuthCodeURL()
+	code, state := m.authorize(t, authURL, m.claims("alice"))
+	p.pendingLogins[state].codeVerifier = "intercepted-code-without-verifier-aaaaaaaaa"
+	if _, err := p.Exchange(code, state, boundState); err == nil {
+		t.Errorf("code redeemed with wrong verifier should be refused")
+	}
+}
+
+func TestExchangeInvalidIDToken(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.close()
+	p := newTestProvider(t, m)
+
+	for name, modify := range map[string]func(jwt.MapClaims){
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = []string{"other"} },
+		"wrong azp":      func(c jwt.MapClaims) { c["aud"], c["azp"] = []string{testClientID, "other"}, "other" },
+		"wrong nonce":    func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"no expiration":  func(c jwt.MapClaims) { delete(c, "exp") },
+		"no username":    func(c jwt.MapClaims) { delete(c, "preferred_username") },
+	} {
+		claims := m.claims("alice")
+		modify(claims)
+		if _, err := login(t, p, m, claims); err == nil {
+			t.Errorf("id token with %s should be refused", name)
+		}
+	}
+
+	claims := m.claims("alice")
+	claims["aud"] = []string{"other", testClientID}
+	claims["azp"] = testClientID
+	if _, err := login(t, p, m, claims); err != nil {
+		t.Errorf("id token with multiple audiences should be accepted: %s", err.Error())
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.close()
+	p := newTestProvider(t, m)
+
+	if _, err := login(t, p, m, m.claims("alice")); err != nil {
+		t.Fatalf("login failed: %s", err.Error())
+	}
+
+	m.addECKey(t, "ec-1")
+	identity, err := login(t, p, m, m.claims("bob"))
+	if err != nil {
+		t.Fatalf("login after key rotation failed: %s", err.Error())
+	}
+
+	if identity.UserName != "bob" {
+		t.Errorf("user name expected bob but get %s", identity.UserName)
+	}
+}
+
+func TestRefuseSymmetricAlgorithm(t *testing.T) {
+	m := newMockProvider(t)
+	defer m.close()
+	p := newTestProvider(t, m)
+
+	rsaKey := m.keys[0].private.(*rsa.PrivateKey)
+	m.keys = append(m.keys, &testSigningKey{kid: "rsa-1", method: jwt.SigningMethodHS256,
+		private: []byte(encodeBigInt(rsaKey.N))})
+	if _, err := login(t, p, m, m.claims("alice")); err == nil {
+		t.Errorf("id token signed by HS256 should be refused")
+	}
+}
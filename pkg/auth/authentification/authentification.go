@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	resterror "github.com/zdnscloud/gorest/error"
 	restresource "github.com/zdnscloud/gorest/resource"
 
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authenticator"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/handler"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
 	"github.com/trymanytimes/UpdateWeb/pkg/db"
@@ -23,16 +25,25 @@ import (
 )
 
 var (
-	signingKey = []byte("Linking-ddi-cooper")
-	ViewKey    = "authViewList"
-	PrefixKey  = "authPlanList"
-	AuthKey    = "authorization"
-	AuthUser   = resource.AuthUser
+	ViewKey   = "authViewList"
+	PrefixKey = "authPlanList"
+	GroupKey  = "authWebGroupList"
+	AuthKey   = "authorization"
+	AuthUser  = resource.AuthUser
 )
 
+const (
+	bearerPrefix    = "Bearer "
+	oidcStateCookie = "ddi_oidc_state"
+)
+
+//LinkingClaims with PreAuth is only used to verify two factor code in login,
+//it isn't accepted as access token
 type LinkingClaims struct {
 	*jwt.StandardClaims
-	UserName string
+	UserName  string
+	SessionID string
+	PreAuth   bool `json:",omitempty"`
 }
 
 func JWTMiddleWare() gorest.HandlerFunc {
@@ -42,86 +53,314 @@ func JWTMiddleWare() gorest.HandlerFunc {
 }
 
 func Login(ctx *gin.Context) {
-	if errorCode, err := checkLogin(ctx); err != nil {
+	response, errorCode, err := checkLogin(ctx)
+	if err != nil {
 		ctx.JSON(errorCode.Status,
 			resource.LoginResponse{Code: errorCode.Status, Message: err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, resource.LoginResponse{Code: http.StatusOK})
+	ctx.JSON(http.StatusOK, response)
 }
 
-func checkLogin(ctx *gin.Context) (resterror.ErrorCode, error) {
+//checkLogin returns a pre-auth token instead of access token if user has
+//enabled totp, the code should be verified by LoginTotp then
+func checkLogin(ctx *gin.Context) (*resource.LoginResponse, resterror.ErrorCode, error) {
 	if err := checkWhiteList(ctx.ClientIP()); err != nil {
-		return resterror.Unauthorized, err
+		return nil, resterror.Unauthorized, err
 	}
 
 	body, err := ioutil.ReadAll(ctx.Request.Body)
 	if err != nil {
-		return resterror.ServerError, fmt.Errorf("bad data")
+		return nil, resterror.ServerError, fmt.Errorf("bad data")
 	}
 
 	login := &resource.LoginRequest{}
 	if err = json.Unmarshal(body, login); err != nil {
-		return resterror.ServerError, fmt.Errorf("bad data")
+		return nil, resterror.ServerError, fmt.Errorf("bad data")
 	}
 
-	if err := handler.CheckPassword(login.Username, login.Password); err != nil {
-		return resterror.ServerError, err
+	if retryAfter, err := handler.CheckLoginAllowed(login.Username, ctx.ClientIP()); err != nil {
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		return nil, resterror.Unauthorized, err
+	}
+
+	if err := authenticator.Authenticate(login.Username, login.Password); err != nil {
+		if err == authenticator.ErrInvalidCredentials {
+			handler.LoginFailed(login.Username, ctx.ClientIP())
+		}
+		return nil, resterror.ServerError, err
 	}
 
-	tokenString, err := createToken(login.Username)
+	return completeLogin(ctx, login.Username)
+}
+
+//completeLogin is called after user is authenticated by any way, it starts
+//session unless the totp code is required
+func completeLogin(ctx *gin.Context, userName string) (*resource.LoginResponse, resterror.ErrorCode, error) {
+	user, err := handler.GetUserInfo(userName)
 	if err != nil {
-		return resterror.ServerError, fmt.Errorf("authorization error")
+		return nil, resterror.ServerError, fmt.Errorf("authorization error")
 	}
 
-	user, err := handler.GetUserInfo(login.Username)
+	if user.TotpEnabled {
+		preAuthToken, err := gKeyRing.sign(&LinkingClaims{
+			StandardClaims: &jwt.StandardClaims{},
+			UserName:       userName,
+			PreAuth:        true,
+		}, preAuthTokenLifetime)
+		if err != nil {
+			return nil, resterror.ServerError, fmt.Errorf("authorization error")
+		}
+
+		return &resource.LoginResponse{
+			Code:              http.StatusOK,
+			TwoFactorRequired: true,
+			PreAuthToken:      preAuthToken,
+		}, resterror.ErrorCode{}, nil
+	}
+
+	handler.LoginSucceeded(userName)
+	return startSession(ctx, user)
+}
+
+//LoginOIDC redirects user to identity provider, which redirects user back
+//to the configured redirect url with code and state
+func LoginOIDC(ctx *gin.Context) {
+	if err := checkWhiteList(ctx.ClientIP()); err != nil {
+		ctx.JSON(http.StatusUnauthorized,
+			resource.LoginResponse{Code: http.StatusUnauthorized, Message: err.Error()})
+		return
+	}
+
+	loginURL, state, err := authenticator.OIDCLoginURL()
 	if err != nil {
-		return resterror.ServerError, fmt.Errorf("authorization error")
+		ctx.JSON(http.StatusInternalServerError,
+			resource.LoginResponse{Code: http.StatusInternalServerError, Message: err.Error()})
+		return
 	}
-	handler.ActivityUsers.Store(login.Username, user)
+
+	setoidcStateCookie(ctx, state, int(authenticator.OIDCLoginTimeout/time.Second))
+	ctx.Redirect(http.StatusFound, loginURL)
+}
+
+//setoidcStateCookie binds state of oidc login to the browser which starts
+//the login, negative maxAge deletes the cookie
+func setoidcStateCookie(ctx *gin.Context, state string, maxAge int) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/login/oidc",
+		MaxAge:   maxAge,
+		Secure:   ctx.Request.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+//LoginOIDCCallback completes oidc login with code and state posted by the
+//redirect url page, the response is the same as Login
+func LoginOIDCCallback(ctx *gin.Context) {
+	response, errorCode, err := checkLoginOIDC(ctx)
+	if err != nil {
+		ctx.JSON(errorCode.Status,
+			resource.LoginResponse{Code: errorCode.Status, Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func checkLoginOIDC(ctx *gin.Context) (*resource.LoginResponse, resterror.ErrorCode, error) {
+	if err := checkWhiteList(ctx.ClientIP()); err != nil {
+		return nil, resterror.Unauthorized, err
+	}
+
+	body, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, resterror.ServerError, fmt.Errorf("bad data")
+	}
+
+	login := &resource.OIDCLoginRequest{}
+	if err = json.Unmarshal(body, login); err != nil {
+		return nil, resterror.ServerError, fmt.Errorf("bad data")
+	}
+
+	boundState, _ := ctx.Cookie(oidcStateCookie)
+	setoidcStateCookie(ctx, "", -1)
+	userName, err := authenticator.AuthenticateOIDC(login.Code, login.State, boundState)
+	if err != nil {
+		return nil, resterror.Unauthorized, err
+	}
+
+	return completeLogin(ctx, userName)
+}
+
+//LoginTotp is the second step of login for user who has enabled totp
+func LoginTotp(ctx *gin.Context) {
+	response, errorCode, err := checkLoginTotp(ctx)
+	if err != nil {
+		ctx.JSON(errorCode.Status,
+			resource.LoginResponse{Code: errorCode.Status, Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func checkLoginTotp(ctx *gin.Context) (*resource.LoginResponse, resterror.ErrorCode, error) {
+	if err := checkWhiteList(ctx.ClientIP()); err != nil {
+		return nil, resterror.Unauthorized, err
+	}
+
+	body, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, resterror.ServerError, fmt.Errorf("bad data")
+	}
+
+	login := &resource.TotpLoginRequest{}
+	if err = json.Unmarshal(body, login); err != nil {
+		return nil, resterror.ServerError, fmt.Errorf("bad data")
+	}
+
+	claims := &LinkingClaims{}
+	if token, err := jwt.ParseWithClaims(login.PreAuthToken, claims, gKeyRing.keyFunc); err != nil ||
+		token.Valid == false || claims.PreAuth == false {
+		return nil, resterror.Unauthorized, fmt.Errorf("pre-auth token invalid or expired")
+	}
+
+	if retryAfter, err := handler.CheckLoginAllowed(claims.UserName, ctx.ClientIP()); err != nil {
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		return nil, resterror.Unauthorized, err
+	}
+
+	if err := handler.VerifyTwoFactor(claims.UserName, login.Code); err == handler.ErrTwoFactorCodeIncorrect {
+		handler.LoginFailed(claims.UserName, ctx.ClientIP())
+		return nil, resterror.Unauthorized, err
+	} else if err != nil {
+		return nil, resterror.ServerError, err
+	}
+
+	handler.LoginSucceeded(claims.UserName)
+	user, err := handler.GetUserInfo(claims.UserName)
+	if err != nil {
+		return nil, resterror.ServerError, fmt.Errorf("authorization error")
+	}
+
+	return startSession(ctx, user)
+}
+
+func startSession(ctx *gin.Context, user *resource.Ddiuser) (*resource.LoginResponse, resterror.ErrorCode, error) {
+	session, refreshToken, err := handler.CreateSession(user.Name, ctx.ClientIP(),
+		ctx.Request.UserAgent(), gKeyRing.refreshTokenLifetime)
+	if err != nil {
+		return nil, resterror.ServerError, fmt.Errorf("authorization error")
+	}
+
+	tokenString, err := createToken(user.Name, session.GetID())
+	if err != nil {
+		return nil, resterror.ServerError, fmt.Errorf("authorization error")
+	}
+
+	handler.ActivityUsers.Store(user.Name, user)
 	ctx.Header(AuthKey, tokenString)
+	return &resource.LoginResponse{Code: http.StatusOK, RefreshToken: refreshToken}, resterror.ErrorCode{}, nil
+}
+
+//Refresh issues a new access token and a new refresh token, the old refresh
+//token is invalid after that
+func Refresh(ctx *gin.Context) {
+	refreshToken, errorCode, err := refreshSession(ctx)
+	if err != nil {
+		ctx.JSON(errorCode.Status,
+			resource.LoginResponse{Code: errorCode.Status, Message: err.Error()})
+		return
+	}
 
-	return resterror.ErrorCode{}, nil
+	ctx.JSON(http.StatusOK, resource.LoginResponse{Code: http.StatusOK, RefreshToken: refreshToken})
 }
 
-func createToken(userName string) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodHS256,
-		&LinkingClaims{
-			StandardClaims: &jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(time.Hour * 24).Unix()},
-			UserName: userName,
-		}).SignedString(signingKey)
+func refreshSession(ctx *gin.Context) (string, resterror.ErrorCode, error) {
+	if err := checkWhiteList(ctx.ClientIP()); err != nil {
+		return "", resterror.Unauthorized, err
+	}
+
+	body, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return "", resterror.ServerError, fmt.Errorf("bad data")
+	}
+
+	refresh := &resource.RefreshRequest{}
+	if err = json.Unmarshal(body, refresh); err != nil || refresh.RefreshToken == "" {
+		return "", resterror.InvalidFormat, fmt.Errorf("bad data")
+	}
+
+	session, refreshToken, err := handler.RefreshSession(refresh.RefreshToken, ctx.ClientIP(),
+		ctx.Request.UserAgent())
+	if err != nil {
+		return "", resterror.Unauthorized, err
+	}
+
+	if _, ok := handler.ActivityUsers.Load(session.Username); ok == false {
+		user, err := handler.GetUserInfo(session.Username)
+		if err != nil {
+			return "", resterror.Unauthorized, fmt.Errorf("authorization error")
+		}
+		handler.ActivityUsers.Store(session.Username, user)
+	}
+
+	tokenString, err := createToken(session.Username, session.GetID())
+	if err != nil {
+		return "", resterror.ServerError, fmt.Errorf("authorization error")
+	}
+
+	ctx.Header(AuthKey, tokenString)
+	return refreshToken, resterror.ErrorCode{}, nil
+}
+
+func createToken(userName, sessionID string) (string, error) {
+	return gKeyRing.sign(&LinkingClaims{
+		StandardClaims: &jwt.StandardClaims{},
+		UserName:       userName,
+		SessionID:      sessionID,
+	}, gKeyRing.tokenLifetime)
 }
 
 func authentification(ctx *restresource.Context) *resterror.APIError {
 	if err := checkWhiteList(getClientIP(ctx.Request.RemoteAddr)); err != nil {
 		return resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:%s", err.Error()))
 	}
-	tokenString := ctx.Request.Header.Get(AuthKey)
+	tokenString := strings.TrimPrefix(ctx.Request.Header.Get(AuthKey), bearerPrefix)
 	if tokenString == "" {
 		return resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:token not exists"))
 	}
-	tokenOrigin, err := jwt.ParseWithClaims(tokenString, &LinkingClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return signingKey, nil
-		})
-	if err != nil || !tokenOrigin.Valid {
-		return resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:token invalid"))
-	}
 
-	claims, ok := tokenOrigin.Claims.(*LinkingClaims)
-	if !ok {
-		return resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:token invalid"))
+	var userName string
+	var apiErr *resterror.APIError
+	if handler.IsApiToken(tokenString) {
+		userName, apiErr = authenticateApiToken(ctx, tokenString)
+	} else {
+		userName, apiErr = authenticateAccessToken(ctx, tokenString)
+	}
+	if apiErr != nil {
+		return apiErr
 	}
 
-	user, ok := handler.ActivityUsers.Load(claims.UserName)
+	user, ok := handler.ActivityUsers.Load(userName)
 	if !ok {
 		return resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:token not exists"))
 	}
 
-	ctx.Set(AuthUser, claims.UserName)
-	if claims.UserName != handler.Admin {
+	ctx.Set(AuthUser, userName)
+	if user.(*resource.Ddiuser).MustChangePassword && isAllowedBeforePasswordChange(ctx) == false {
+		return resterror.NewAPIError(resterror.PermissionDenied, fmt.Sprintf("forbidden:password must be changed"))
+	}
+
+	if user.(*resource.Ddiuser).MustEnrollTotp() && isAllowedBeforeTotpEnrollment(ctx) == false {
+		return resterror.NewAPIError(resterror.PermissionDenied, fmt.Sprintf("forbidden:totp must be enabled"))
+	}
+
+	if userName != handler.Admin {
 		if err := checkAuthority(ctx, user.(*resource.Ddiuser)); err != nil {
 			return resterror.NewAPIError(resterror.PermissionDenied, err.Error())
 		}
@@ -130,12 +369,88 @@ func authentification(ctx *restresource.Context) *resterror.APIError {
 	return nil
 }
 
+func authenticateAccessToken(ctx *restresource.Context, tokenString string) (string, *resterror.APIError) {
+	tokenOrigin, err := jwt.ParseWithClaims(tokenString, &LinkingClaims{}, gKeyRing.keyFunc)
+	if err != nil || !tokenOrigin.Valid {
+		return "", resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:token invalid"))
+	}
+
+	claims, ok := tokenOrigin.Claims.(*LinkingClaims)
+	if !ok || claims.PreAuth {
+		return "", resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:token invalid"))
+	}
+
+	session, err := handler.GetSession(claims.SessionID)
+	if err != nil {
+		return "", resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	if session == nil || session.Username != claims.UserName || session.ExpireTime.Before(time.Now()) {
+		return "", resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:session revoked or expired"))
+	}
+
+	ctx.Set(resource.AuthSession, claims.SessionID)
+	return claims.UserName, nil
+}
+
+//authenticateApiToken limits request to scopes of api token, authority of
+//its user is still checked
+func authenticateApiToken(ctx *restresource.Context, tokenString string) (string, *resterror.APIError) {
+	apiToken, err := handler.VerifyApiToken(tokenString)
+	if err != nil {
+		return "", resterror.NewAPIError(resterror.Unauthorized, fmt.Sprintf("forbidden:%s", err.Error()))
+	}
+
+	if handler.ApiTokenAllows(apiToken, ctx.Resource.GetType(), ctx.Method,
+		ctx.Resource.GetAction() != nil) == false {
+		return "", resterror.NewAPIError(resterror.PermissionDenied, fmt.Sprintf("forbidden:out of api token scopes"))
+	}
+
+	ctx.Set(resource.AuthApiToken, apiToken)
+	return apiToken.Username, nil
+}
+
+//isAllowedBeforePasswordChange returns whether the request is allowed for user
+//who must change password before doing anything else
+func isAllowedBeforePasswordChange(ctx *restresource.Context) bool {
+	return isUserAction(ctx, resource.ActionChangePassword, resource.ActionCurrentUser, resource.ActionLogout)
+}
+
+//isAllowedBeforeTotpEnrollment returns whether the request is allowed for
+//user whose role requires two factor authentication but hasn't enabled totp
+func isAllowedBeforeTotpEnrollment(ctx *restresource.Context) bool {
+	return isUserAction(ctx, resource.ActionEnrollTotp, resource.ActionActivateTotp,
+		resource.ActionChangePassword, resource.ActionCurrentUser, resource.ActionLogout)
+}
+
+func isUserAction(ctx *restresource.Context, names ...string) bool {
+	if ctx.Resource.GetType() != restresource.DefaultKindName(resource.Ddiuser{}) {
+		return false
+	}
+
+	action := ctx.Resource.GetAction()
+	if action == nil {
+		return false
+	}
+
+	for _, name := range names {
+		if action.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+//checkAuthority matches action with ACTION rather than POST, so authority of
+//creating a resource doesn't allow actions of it
 func checkAuthority(ctx *restresource.Context, user *resource.Ddiuser) error {
 	haveAuthority := false
+	requestOperation := resource.RequestOperation(ctx.Method, ctx.Resource.GetAction() != nil)
 	for _, roleAuthority := range user.RoleAuthority {
 		if roleAuthority.Resource == ctx.Resource.GetType() {
 			for _, operation := range roleAuthority.Operations {
-				if string(operation) == ctx.Method {
+				if operation == requestOperation {
 					haveAuthority = true
 					break
 				}
@@ -143,6 +458,7 @@ func checkAuthority(ctx *restresource.Context, user *resource.Ddiuser) error {
 
 			if haveAuthority {
 				ctx.Set(PrefixKey, roleAuthority.Plans)
+				ctx.Set(GroupKey, roleAuthority.WebGroups)
 				haveAuthority = checkView(ctx, roleAuthority)
 				if haveAuthority {
 					ctx.Set(ViewKey, roleAuthority.Views)
@@ -204,6 +520,32 @@ func ViewFilter(ctx *restresource.Context, viewId string) bool {
 	return false
 }
 
+//WebGroupFilter returns whether data of web group is visible to the user,
+//it is only used by business resource with filter
+func WebGroupFilter(ctx *restresource.Context, groupId string) bool {
+	user, ok := ctx.Get(AuthUser)
+	if !ok {
+		return false
+	}
+
+	if user == handler.Admin {
+		return true
+	}
+
+	checkGroups, ok := ctx.Get(GroupKey)
+	if !ok || len(checkGroups.([]string)) == 0 {
+		return false
+	}
+
+	for _, g := range checkGroups.([]string) {
+		if g == groupId {
+			return true
+		}
+	}
+
+	return false
+}
+
 func PrefixFilter(ctx *restresource.Context, subnetOrIps ...string) bool {
 	user, ok := ctx.Get(AuthUser)
 	if !ok {
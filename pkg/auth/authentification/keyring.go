@@ -0,0 +1,327 @@
+package authentification
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/zdnscloud/cement/log"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+
+	defaultTokenLifetime        = 15 * time.Minute
+	defaultRefreshTokenLifetime = 24 * time.Hour
+	preAuthTokenLifetime        = 5 * time.Minute
+	minSecretLen                = 32
+	keyFileReloadInterval       = time.Minute
+	randomKeyID                 = "random"
+)
+
+//jwtKey without signKey is only used to verify token
+type jwtKey struct {
+	id          string
+	method      jwt.SigningMethod
+	signKey     interface{}
+	verifyKey   interface{}
+	verifyUntil time.Time
+}
+
+func (k *jwtKey) expired(now time.Time) bool {
+	return k.verifyUntil.IsZero() == false && now.After(k.verifyUntil)
+}
+
+//keyRing signs token by the signing key and verifies token by the key its
+//kid refers to, so an old key still verifies tokens signed before rotation
+//until it is removed or its verify_until passes
+type keyRing struct {
+	lock                 sync.RWMutex
+	signingKey           *jwtKey
+	keys                 map[string]*jwtKey
+	tokenLifetime        time.Duration
+	refreshTokenLifetime time.Duration
+	keyFile              string
+	keyFileModTime       time.Time
+}
+
+var gKeyRing *keyRing
+
+//Init loads jwt keys from config, a random HS256 key is generated if no key
+//is configured, then tokens are invalid after controller restarts
+func Init(conf *config.DDIControllerConfig) error {
+	ring, err := newKeyRing(&conf.JWT)
+	if err != nil {
+		return err
+	}
+
+	gKeyRing = ring
+	if ring.keyFile != "" {
+		go ring.watchKeyFile()
+	}
+	return nil
+}
+
+func newKeyRing(conf *config.JWTConf) (*keyRing, error) {
+	ring := &keyRing{
+		tokenLifetime:        defaultTokenLifetime,
+		refreshTokenLifetime: defaultRefreshTokenLifetime,
+		keyFile:              conf.KeyFile,
+	}
+	if conf.TokenLifetime != 0 {
+		ring.tokenLifetime = time.Duration(conf.TokenLifetime) * time.Second
+	}
+	if conf.RefreshTokenLifetime != 0 {
+		ring.refreshTokenLifetime = time.Duration(conf.RefreshTokenLifetime) * time.Second
+	}
+
+	if ring.keyFile != "" {
+		keySet, modTime, err := loadKeyFile(ring.keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		ring.keyFileModTime = modTime
+		return ring, ring.load(keySet)
+	}
+
+	if len(conf.Keys) == 0 {
+		log.Warnf("no jwt key is configured, use random key")
+		return ring, ring.loadRandomKey()
+	}
+
+	return ring, ring.load(&config.JWTKeySet{SigningKeyID: conf.SigningKeyID, Keys: conf.Keys})
+}
+
+func loadKeyFile(path string) (*config.JWTKeySet, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("stat jwt key file %s failed: %s", path, err.Error())
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("read jwt key file %s failed: %s", path, err.Error())
+	}
+
+	var keySet config.JWTKeySet
+	if err := yaml.Unmarshal(content, &keySet); err != nil {
+		return nil, time.Time{}, fmt.Errorf("parse jwt key file %s failed: %s", path, err.Error())
+	}
+
+	return &keySet, info.ModTime(), nil
+}
+
+func (r *keyRing) loadRandomKey() error {
+	secret := make([]byte, minSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("generate random jwt key failed: %s", err.Error())
+	}
+
+	key := &jwtKey{id: randomKeyID, method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+	r.lock.Lock()
+	r.signingKey = key
+	r.keys = map[string]*jwtKey{key.id: key}
+	r.lock.Unlock()
+	return nil
+}
+
+func (r *keyRing) load(keySet *config.JWTKeySet) error {
+	now := time.Now()
+	keys := make(map[string]*jwtKey, len(keySet.Keys))
+	for _, keyConf := range keySet.Keys {
+		if _, ok := keys[keyConf.ID]; ok {
+			return fmt.Errorf("duplicate jwt key %s", keyConf.ID)
+		}
+
+		key, err := parseJWTKey(keyConf)
+		if err != nil {
+			return err
+		}
+
+		if key.expired(now) == false {
+			keys[key.id] = key
+		}
+	}
+
+	signingKey, ok := keys[keySet.SigningKeyID]
+	if ok == false {
+		return fmt.Errorf("jwt signing key %s is not found or expired", keySet.SigningKeyID)
+	} else if signingKey.signKey == nil {
+		return fmt.Errorf("jwt signing key %s has no private key", keySet.SigningKeyID)
+	}
+
+	r.lock.Lock()
+	r.signingKey = signingKey
+	r.keys = keys
+	r.lock.Unlock()
+	return nil
+}
+
+func parseJWTKey(conf config.JWTKey) (*jwtKey, error) {
+	if conf.ID == "" {
+		return nil, fmt.Errorf("jwt key id is empty")
+	}
+
+	key := &jwtKey{id: conf.ID}
+	if conf.VerifyUntil != "" {
+		verifyUntil, err := time.ParseInLocation(util.TimeFormat, conf.VerifyUntil, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("parse verify_until of jwt key %s failed: %s", conf.ID, err.Error())
+		}
+		key.verifyUntil = verifyUntil
+	}
+
+	var err error
+	switch conf.Algorithm {
+	case "", AlgorithmHS256:
+		if len(conf.Secret) < minSecretLen {
+			return nil, fmt.Errorf("secret of jwt key %s should be at least %d bytes", conf.ID, minSecretLen)
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(conf.Secret)
+		key.verifyKey = key.signKey
+	case AlgorithmRS256:
+		key.method = jwt.SigningMethodRS256
+		err = loadPEMKeys(key, conf, func(data []byte) (interface{}, interface{}, error) {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return privateKey, &privateKey.PublicKey, nil
+		}, func(data []byte) (interface{}, error) {
+			return jwt.ParseRSAPublicKeyFromPEM(data)
+		})
+	case AlgorithmES256:
+		key.method = jwt.SigningMethodES256
+		err = loadPEMKeys(key, conf, func(data []byte) (interface{}, interface{}, error) {
+			privateKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return privateKey, &privateKey.PublicKey, nil
+		}, func(data []byte) (interface{}, error) {
+			return jwt.ParseECPublicKeyFromPEM(data)
+		})
+	default:
+		return nil, fmt.Errorf("algorithm %s of jwt key %s is unsupported", conf.Algorithm, conf.ID)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("load jwt key %s failed: %s", conf.ID, err.Error())
+	}
+
+	return key, nil
+}
+
+//loadPEMKeys loads private key if it is set, public key is derived from
+//private key unless public key file is set
+func loadPEMKeys(key *jwtKey, conf config.JWTKey,
+	parsePrivateKey func([]byte) (interface{}, interface{}, error),
+	parsePublicKey func([]byte) (interface{}, error)) error {
+	if conf.PrivateKeyFile == "" && conf.PublicKeyFile == "" {
+		return fmt.Errorf("private_key_file or public_key_file should be set")
+	}
+
+	if conf.PrivateKeyFile != "" {
+		data, err := ioutil.ReadFile(conf.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+
+		if key.signKey, key.verifyKey, err = parsePrivateKey(data); err != nil {
+			return err
+		}
+	}
+
+	if conf.PublicKeyFile != "" {
+		data, err := ioutil.ReadFile(conf.PublicKeyFile)
+		if err != nil {
+			return err
+		}
+
+		if key.verifyKey, err = parsePublicKey(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//watchKeyFile reloads keys when key file is modified, the old keys are kept
+//if the new file is invalid
+func (r *keyRing) watchKeyFile() {
+	ticker := time.NewTicker(keyFileReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(r.keyFile)
+			if err != nil {
+				log.Warnf("stat jwt key file %s failed: %s", r.keyFile, err.Error())
+				continue
+			}
+
+			if info.ModTime().Equal(r.keyFileModTime) {
+				continue
+			}
+
+			keySet, modTime, err := loadKeyFile(r.keyFile)
+			if err == nil {
+				err = r.load(keySet)
+			}
+
+			if err != nil {
+				log.Warnf("reload jwt key file %s failed: %s", r.keyFile, err.Error())
+			} else {
+				log.Infof("reload jwt key file %s succeed", r.keyFile)
+			}
+			r.keyFileModTime = modTime
+		}
+	}
+}
+
+func (r *keyRing) sign(claims *LinkingClaims, lifetime time.Duration) (string, error) {
+	r.lock.RLock()
+	key := r.signingKey
+	r.lock.RUnlock()
+
+	now := time.Now()
+	claims.StandardClaims.IssuedAt = now.Unix()
+	claims.StandardClaims.ExpiresAt = now.Add(lifetime).Unix()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.signKey)
+}
+
+//keyFunc refuses token whose algorithm differs from its key, so a public key
+//can't be used as HS256 secret
+func (r *keyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	r.lock.RLock()
+	key, ok := r.keys[kid]
+	r.lock.RUnlock()
+	if ok == false {
+		return nil, fmt.Errorf("unknown key %s", kid)
+	}
+
+	if key.expired(time.Now()) {
+		return nil, fmt.Errorf("key %s is expired", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	return key.verifyKey, nil
+}
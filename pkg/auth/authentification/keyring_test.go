@@ -0,0 +1,278 @@
+package authentification
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+type testKeyFiles struct {
+	rsaPrivate string
+	rsaPublic  string
+	ecPrivate  string
+	invalid    string
+}
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatalf("write %s failed: %s", path, err.Error())
+	}
+	return path
+}
+
+func genTestKeyFiles(t *testing.T, dir string) *testKeyFiles {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key failed: %s", err.Error())
+	}
+
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal rsa public key failed: %s", err.Error())
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key failed: %s", err.Error())
+	}
+
+	ecPrivate, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("marshal ec private key failed: %s", err.Error())
+	}
+
+	return &testKeyFiles{
+		rsaPrivate: writePEM(t, dir, "rsa.key", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+		rsaPublic:  writePEM(t, dir, "rsa.pub", "PUBLIC KEY", rsaPublic),
+		ecPrivate:  writePEM(t, dir, "ec.key", "EC PRIVATE KEY", ecPrivate),
+		invalid:    writePEM(t, dir, "invalid.key", "RSA PRIVATE KEY", []byte("invalid")),
+	}
+}
+
+func TestParseJWTKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwtkey")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	files := genTestKeyFiles(t, dir)
+
+	cases := []struct {
+		name     string
+		conf     config.JWTKey
+		err      string
+		alg      string
+		canSign  bool
+		verifyAt bool
+	}{
+		{"empty id", config.JWTKey{Secret: testSecret}, "id is empty", "", false, false},
+		{"default hs256", config.JWTKey{ID: "k1", Secret: testSecret}, "", AlgorithmHS256, true, false},
+		{"short secret", config.JWTKey{ID: "k1", Secret: "short"}, "at least", "", false, false},
+		{"unknown algorithm", config.JWTKey{ID: "k1", Algorithm: "none"}, "unsupported", "", false, false},
+		{"rs256 without file", config.JWTKey{ID: "k1", Algorithm: AlgorithmRS256}, "should be set", "", false, false},
+		{"rs256 private", config.JWTKey{ID: "k1", Algorithm: AlgorithmRS256, PrivateKeyFile: files.rsaPrivate}, "", AlgorithmRS256, true, false},
+		{"rs256 public only", config.JWTKey{ID: "k1", Algorithm: AlgorithmRS256, PublicKeyFile: files.rsaPublic}, "", AlgorithmRS256, false, false},
+		{"rs256 invalid pem", config.JWTKey{ID: "k1", Algorithm: AlgorithmRS256, PrivateKeyFile: files.invalid}, "load jwt key", "", false, false},
+		{"rs256 missing file", config.JWTKey{ID: "k1", Algorithm: AlgorithmRS256, PrivateKeyFile: filepath.Join(dir, "none")}, "load jwt key", "", false, false},
+		{"es256 private", config.JWTKey{ID: "k1", Algorithm: AlgorithmES256, PrivateKeyFile: files.ecPrivate}, "", AlgorithmES256, true, false},
+		{"es256 with rsa key", config.JWTKey{ID: "k1", Algorithm: AlgorithmES256, PrivateKeyFile: files.rsaPrivate}, "load jwt key", "", false, false},
+		{"verify until", config.JWTKey{ID: "k1", Secret: testSecret, VerifyUntil: "2030-01-02 03:04:05"}, "", AlgorithmHS256, true, true},
+		{"invalid verify until", config.JWTKey{ID: "k1", Secret: testSecret, VerifyUntil: "2030-01-02"}, "verify_until", "", false, false},
+	}
+
+	for _, c := range cases {
+		key, err := parseJWTKey(c.conf)
+		if c.err != "" {
+			if err == nil || strings.Contains(err.Error(), c.err) == false {
+				t.Errorf("%s: expected error with %q but get %v", c.name, c.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: parse key failed: %s", c.name, err.Error())
+			continue
+		}
+
+		if key.method.Alg() != c.alg || (key.signKey != nil) != c.canSign || key.verifyKey == nil {
+			t.Errorf("%s: unexpected key alg %s sign key %v", c.name, key.method.Alg(), key.signKey != nil)
+		}
+
+		if key.verifyUntil.IsZero() == c.verifyAt {
+			t.Errorf("%s: unexpected verify until %s", c.name, key.verifyUntil)
+		}
+	}
+}
+
+func newTestKeyRing(t *testing.T, keySet *config.JWTKeySet) *keyRing {
+	ring := &keyRing{tokenLifetime: defaultTokenLifetime, refreshTokenLifetime: defaultRefreshTokenLifetime}
+	if err := ring.load(keySet); err != nil {
+		t.Fatalf("load keys failed: %s", err.Error())
+	}
+	return ring
+}
+
+func verifyToken(ring *keyRing, tokenString string) (*LinkingClaims, error) {
+	claims := &LinkingClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, ring.keyFunc)
+	return claims, err
+}
+
+func TestKeyRingLoad(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).Format(util.TimeFormat)
+	cases := []struct {
+		name   string
+		keySet *config.JWTKeySet
+	}{
+		{"signing key not found", &config.JWTKeySet{SigningKeyID: "k2",
+			Keys: []config.JWTKey{{ID: "k1", Secret: testSecret}}}},
+		{"duplicate key", &config.JWTKeySet{SigningKeyID: "k1",
+			Keys: []config.JWTKey{{ID: "k1", Secret: testSecret}, {ID: "k1", Secret: testSecret}}}},
+		{"expired signing key", &config.JWTKeySet{SigningKeyID: "k1",
+			Keys: []config.JWTKey{{ID: "k1", Secret: testSecret, VerifyUntil: expired}}}},
+	}
+
+	for _, c := range cases {
+		ring := &keyRing{}
+		if err := ring.load(c.keySet); err == nil {
+			t.Errorf("%s: load should fail", c.name)
+		}
+	}
+}
+
+func TestKeyFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwtkey")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	files := genTestKeyFiles(t, dir)
+
+	ring := newTestKeyRing(t, &config.JWTKeySet{
+		SigningKeyID: "rsa",
+		Keys: []config.JWTKey{
+			{ID: "rsa", Algorithm: AlgorithmRS256, PrivateKeyFile: files.rsaPrivate},
+			{ID: "hs", Secret: testSecret},
+		},
+	})
+
+	valid, err := ring.sign(&LinkingClaims{StandardClaims: &jwt.StandardClaims{}, UserName: "admin"}, time.Minute)
+	if err != nil {
+		t.Fatalf("sign token failed: %s", err.Error())
+	}
+
+	if claims, err := verifyToken(ring, valid); err != nil || claims.UserName != "admin" {
+		t.Fatalf("verify token failed: %v", err)
+	}
+
+	publicKey, err := ioutil.ReadFile(files.rsaPublic)
+	if err != nil {
+		t.Fatalf("read public key failed: %s", err.Error())
+	}
+
+	signWith := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, &LinkingClaims{StandardClaims: &jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Minute).Unix()}, UserName: "admin"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("sign token failed: %s", err.Error())
+		}
+		return s
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"public key as hs256 secret", signWith(jwt.SigningMethodHS256, "rsa", publicKey), "unexpected signing method"},
+		{"wrong algorithm of hs key", signWith(jwt.SigningMethodHS512, "hs", []byte(testSecret)), "unexpected signing method"},
+		{"unknown kid", signWith(jwt.SigningMethodHS256, "unknown", []byte(testSecret)), "unknown key"},
+		{"without kid", signWith(jwt.SigningMethodHS256, "", []byte(testSecret)), "unknown key"},
+		{"wrong secret", signWith(jwt.SigningMethodHS256, "hs", []byte(testSecret+"x")), "signature is invalid"},
+	}
+
+	for _, c := range cases {
+		if _, err := verifyToken(ring, c.token); err == nil || strings.Contains(err.Error(), c.err) == false {
+			t.Errorf("%s: expected error with %q but get %v", c.name, c.err, err)
+		}
+	}
+
+	ring.keys["hs"].verifyUntil = time.Now().Add(-time.Second)
+	if _, err := verifyToken(ring, signWith(jwt.SigningMethodHS256, "hs", []byte(testSecret))); err == nil ||
+		strings.Contains(err.Error(), "expired") == false {
+		t.Errorf("token of expired key should be refused but get %v", err)
+	}
+}
+
+func TestSignVerifyAcrossRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwtkey")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	files := genTestKeyFiles(t, dir)
+
+	oldKey := config.JWTKey{ID: "old", Secret: testSecret}
+	newKey := config.JWTKey{ID: "new", Algorithm: AlgorithmES256, PrivateKeyFile: files.ecPrivate}
+	ring := newTestKeyRing(t, &config.JWTKeySet{SigningKeyID: "old", Keys: []config.JWTKey{oldKey}})
+	oldToken, err := ring.sign(&LinkingClaims{StandardClaims: &jwt.StandardClaims{}, UserName: "admin"}, time.Minute)
+	if err != nil {
+		t.Fatalf("sign token failed: %s", err.Error())
+	}
+
+	oldKey.VerifyUntil = time.Now().Add(time.Hour).Format(util.TimeFormat)
+	if err := ring.load(&config.JWTKeySet{SigningKeyID: "new", Keys: []config.JWTKey{oldKey, newKey}}); err != nil {
+		t.Fatalf("rotate keys failed: %s", err.Error())
+	}
+
+	newToken, err := ring.sign(&LinkingClaims{StandardClaims: &jwt.StandardClaims{}, UserName: "admin"}, time.Minute)
+	if err != nil {
+		t.Fatalf("sign token failed: %s", err.Error())
+	}
+
+	token, err := jwt.ParseWithClaims(newToken, &LinkingClaims{}, ring.keyFunc)
+	if err != nil {
+		t.Fatalf("verify token signed by new key failed: %s", err.Error())
+	}
+
+	if kid := token.Header["kid"]; kid != "new" || token.Method.Alg() != AlgorithmES256 {
+		t.Errorf("token should be signed by new key but get %v %s", kid, token.Method.Alg())
+	}
+
+	if _, err := verifyToken(ring, oldToken); err != nil {
+		t.Errorf("token signed before rotation should be verified: %s", err.Error())
+	}
+
+	if err := ring.load(&config.JWTKeySet{SigningKeyID: "new", Keys: []config.JWTKey{newKey}}); err != nil {
+		t.Fatalf("remove old key failed: %s", err.Error())
+	}
+
+	if _, err := verifyToken(ring, oldToken); err == nil {
+		t.Errorf("token of removed key should be refused")
+	}
+
+	if _, err := verifyToken(ring, newToken); err != nil {
+		t.Errorf("token signed by new key should be verified: %s", err.Error())
+	}
+}
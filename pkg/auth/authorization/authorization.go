@@ -2,13 +2,17 @@ package authorization
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/trymanytimes/UpdateWeb/config"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
 )
 
+const businessOperationSeparator = ":"
+
 var (
 	roleTemplate *resource.RoleTemplate
 )
@@ -44,6 +48,7 @@ func CreateBaseAuthority() map[string]resource.RoleAuthority {
 
 	CreateViewAuthority([]string{}, roleAuthorityMap)
 	CreateDhcpAuthority([]string{}, roleAuthorityMap)
+	CreateBusinessAuthority([]string{}, []string{}, roleAuthorityMap)
 	return roleAuthorityMap
 }
 
@@ -82,3 +87,66 @@ func CreateDhcpAuthority(plans []string, roleAuthority map[string]resource.RoleA
 		roleAuthority[authority.Resource] = authority
 	}
 }
+
+//CreateBusinessAuthority adds operations granted by roles to the base
+//operations of business resources, operation is like website:POST. data of
+//business resource with filter is limited to the web groups of roles
+func CreateBusinessAuthority(operations, webGroups []string, roleAuthority map[string]resource.RoleAuthority) {
+	for _, authority := range roleTemplate.Role.Normal.BusinessAuthority {
+		if authority.Filter {
+			authority.WebGroups = webGroups
+		}
+		authority.Operations = append([]resource.OperationsType{}, authority.Operations...)
+		for _, operation := range operations {
+			fields := strings.SplitN(operation, businessOperationSeparator, 2)
+			if len(fields) == 2 && fields[0] == authority.Resource &&
+				hasOperation(authority.Operations, resource.OperationsType(fields[1])) == false {
+				authority.Operations = append(authority.Operations, resource.OperationsType(fields[1]))
+			}
+		}
+		roleAuthority[authority.Resource] = authority
+	}
+}
+
+//ValidateBusinessOperations requires resource of operation is a business
+//resource in role config
+func ValidateBusinessOperations(operations []string) error {
+	for _, operation := range operations {
+		fields := strings.SplitN(operation, businessOperationSeparator, 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("business operation %s should be resource%soperation",
+				operation, businessOperationSeparator)
+		}
+
+		if isBusinessResource(fields[0]) == false {
+			return fmt.Errorf("unknown business resource %s", fields[0])
+		}
+
+		switch resource.OperationsType(fields[1]) {
+		case resource.OperationsTypeGET, resource.OperationsTypePUT, resource.OperationsTypePOST,
+			resource.OperationsTypeDELETE, resource.OperationsTypeACTION:
+		default:
+			return fmt.Errorf("unknown operation %s of business resource %s", fields[1], fields[0])
+		}
+	}
+
+	return nil
+}
+
+func isBusinessResource(name string) bool {
+	for _, authority := range roleTemplate.Role.Normal.BusinessAuthority {
+		if authority.Resource == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOperation(operations []resource.OperationsType, operation resource.OperationsType) bool {
+	for _, o := range operations {
+		if o == operation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package authorization
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+func setTestRoleTemplate() {
+	roleTemplate = &resource.RoleTemplate{Role: resource.RoleTemplateType{
+		Normal: resource.RoleAuthorityClass{
+			BusinessAuthority: []resource.RoleAuthority{
+				{Resource: "website", Operations: []resource.OperationsType{resource.OperationsTypeGET}},
+				{Resource: "vipinterval", Operations: []resource.OperationsType{resource.OperationsTypeGET}},
+				{Resource: "groupdashboard", Filter: true, Operations: []resource.OperationsType{resource.OperationsTypeGET}},
+			},
+		},
+	}}
+}
+
+func TestCreateBusinessAuthority(t *testing.T) {
+	setTestRoleTemplate()
+	roleAuthority := CreateBaseAuthority()
+	if expected := []resource.OperationsType{resource.OperationsTypeGET}; reflect.DeepEqual(
+		roleAuthority["website"].Operations, expected) == false {
+		t.Errorf("base operations of website expected %v but get %v", expected, roleAuthority["website"].Operations)
+	}
+
+	CreateBusinessAuthority([]string{"website:POST", "website:PUT", "website:POST", "website:GET", "cluster:PUT"},
+		[]string{"group1"}, roleAuthority)
+	if expected := []resource.OperationsType{resource.OperationsTypeGET, resource.OperationsTypePOST,
+		resource.OperationsTypePUT}; reflect.DeepEqual(roleAuthority["website"].Operations, expected) == false {
+		t.Errorf("operations of website expected %v but get %v", expected, roleAuthority["website"].Operations)
+	}
+
+	if len(roleAuthority["vipinterval"].Operations) != 1 {
+		t.Errorf("operations of vipinterval should not be changed")
+	}
+
+	if _, ok := roleAuthority["cluster"]; ok {
+		t.Errorf("cluster isn't a business resource of template")
+	}
+
+	if expected := []string{"group1"}; reflect.DeepEqual(roleAuthority["groupdashboard"].WebGroups, expected) == false {
+		t.Errorf("web groups of groupdashboard expected %v but get %v", expected, roleAuthority["groupdashboard"].WebGroups)
+	}
+
+	if len(roleAuthority["website"].WebGroups) != 0 {
+		t.Errorf("website without filter should not be limited to web groups")
+	}
+
+	if len(roleTemplate.Role.Normal.BusinessAuthority[0].Operations) != 1 {
+		t.Errorf("role template should not be changed")
+	}
+}
+
+func TestValidateBusinessOperations(t *testing.T) {
+	setTestRoleTemplate()
+	if err := ValidateBusinessOperations([]string{"website:POST", "vipinterval:ACTION"}); err != nil {
+		t.Errorf("business operations should be valid: %s", err.Error())
+	}
+
+	for _, operation := range []string{"website", "cluster:GET", "website:PATCH", ":GET"} {
+		if err := ValidateBusinessOperations([]string{operation}); err == nil {
+			t.Errorf("business operation %s should be invalid", operation)
+		}
+	}
+}
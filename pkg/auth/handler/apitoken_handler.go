@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	ApiTokenPrefix           = "ddi_"
+	apiTokenIDLen            = 24
+	apiTokenSecretLen        = 48
+	apiTokenSeparator        = "."
+	apiTokenScopeSeparator   = ":"
+	apiTokenLastUsedInterval = time.Minute
+)
+
+var (
+	TableApiToken = restdb.ResourceDBType(&resource.ApiToken{})
+	apiTokenKind  = restresource.DefaultKindName(resource.ApiToken{})
+
+	apiTokenScopeMethods = []resource.OperationsType{
+		resource.OperationsTypeGET, resource.OperationsTypePUT,
+		resource.OperationsTypePOST, resource.OperationsTypeDELETE,
+		resource.OperationsTypeACTION}
+)
+
+type ApiTokenHandler struct{}
+
+func NewApiTokenHandler() *ApiTokenHandler {
+	return &ApiTokenHandler{}
+}
+
+//Create returns the token only once, only hash of its secret is saved
+func (h *ApiTokenHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	currentUser, ok := ctx.Get(resource.AuthUser)
+	if !ok {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("unknown user"))
+	}
+
+	apiToken := ctx.Resource.(*resource.ApiToken)
+	if err := validateApiTokenScopes(apiToken.Scopes); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	if apiToken.ExpireTime.After(time.Now()) == false {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat,
+			fmt.Sprintf("expire time of api token should be in the future"))
+	}
+
+	secret := util.CreateRandomString(apiTokenSecretLen)
+	apiToken.Username = currentUser.(string)
+	apiToken.LastUsedTime = time.Time{}
+	apiToken.SecretHash = hashApiTokenSecret(secret)
+	apiToken.SetID(util.CreateRandomString(apiTokenIDLen))
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if exists, err := tx.Exists(TableApiToken, map[string]interface{}{
+			"username": apiToken.Username, "name": apiToken.Name}); err != nil {
+			return err
+		} else if exists {
+			return fmt.Errorf("api token %s already exists", apiToken.Name)
+		}
+
+		_, err := tx.Insert(apiToken)
+		return err
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("create api token %s failed: %s", apiToken.Name, err.Error()))
+	}
+
+	//ctx.Resource is written into audit log, so the token is only in the copy
+	created := *apiToken
+	created.Token = ApiTokenPrefix + apiToken.GetID() + apiTokenSeparator + secret
+	return &created, nil
+}
+
+//List returns tokens of current user, admin gets tokens of all users
+func (h *ApiTokenHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	currentUser, ok := ctx.Get(resource.AuthUser)
+	if !ok {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("unknown user"))
+	}
+
+	conditions := map[string]interface{}{"orderby": "create_time"}
+	if currentUser.(string) != Admin {
+		conditions["username"] = currentUser.(string)
+	}
+
+	var apiTokens []*resource.ApiToken
+	if err := db.GetResources(conditions, &apiTokens); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("list api tokens from db failed: %s", err.Error()))
+	}
+
+	return apiTokens, nil
+}
+
+func (h *ApiTokenHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	apiToken, apiErr := getApiTokenOfCurrentUser(ctx)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return apiToken, nil
+}
+
+//Delete revokes api token, it is refused at once
+func (h *ApiTokenHandler) Delete(ctx *restresource.Context) *resterror.APIError {
+	apiToken, apiErr := getApiTokenOfCurrentUser(ctx)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Delete(TableApiToken, map[string]interface{}{restdb.IDField: apiToken.GetID()})
+		return err
+	}); err != nil {
+		return resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("delete api token %s from db failed: %s", apiToken.GetID(), err.Error()))
+	}
+
+	return nil
+}
+
+//getApiTokenOfCurrentUser treats token of other user as non-exists unless
+//current user is admin
+func getApiTokenOfCurrentUser(ctx *restresource.Context) (*resource.ApiToken, *resterror.APIError) {
+	currentUser, ok := ctx.Get(resource.AuthUser)
+	if !ok {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("unknown user"))
+	}
+
+	apiToken, err := getApiToken(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	if apiToken == nil || (currentUser.(string) != Admin && apiToken.Username != currentUser.(string)) {
+		return nil, resterror.NewAPIError(resterror.NotFound,
+			fmt.Sprintf("api token %s is non-exists", ctx.Resource.GetID()))
+	}
+
+	return apiToken, nil
+}
+
+func getApiToken(id string) (*resource.ApiToken, error) {
+	var apiTokens []*resource.ApiToken
+	if err := db.GetResources(map[string]interface{}{restdb.IDField: id}, &apiTokens); err != nil {
+		return nil, fmt.Errorf("get api token %s from db failed: %s", id, err.Error())
+	}
+
+	if len(apiTokens) == 0 {
+		return nil, nil
+	}
+
+	return apiTokens[0], nil
+}
+
+//IsApiToken tells api token from access token
+func IsApiToken(token string) bool {
+	return strings.HasPrefix(token, ApiTokenPrefix)
+}
+
+//VerifyApiToken returns the api token if it is valid, last used time is
+//updated at most once per minute to avoid writing db for every request
+func VerifyApiToken(token string) (*resource.ApiToken, error) {
+	fields := strings.SplitN(strings.TrimPrefix(token, ApiTokenPrefix), apiTokenSeparator, 2)
+	if IsApiToken(token) == false || len(fields) != 2 {
+		return nil, fmt.Errorf("api token invalid")
+	}
+
+	apiToken, err := getApiToken(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if apiToken == nil ||
+		subtle.ConstantTimeCompare([]byte(apiToken.SecretHash), []byte(hashApiTokenSecret(fields[1]))) != 1 {
+		return nil, fmt.Errorf("api token invalid")
+	}
+
+	now := time.Now()
+	if apiToken.ExpireTime.Before(now) {
+		return nil, fmt.Errorf("api token expired")
+	}
+
+	if now.Sub(apiToken.LastUsedTime) >= apiTokenLastUsedInterval {
+		apiToken.LastUsedTime = now
+		if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+			_, err := tx.Update(TableApiToken, map[string]interface{}{"last_used_time": now},
+				map[string]interface{}{restdb.IDField: apiToken.GetID()})
+			return err
+		}); err != nil {
+			log.Warnf("update last used time of api token %s failed: %s", apiToken.GetID(), err.Error())
+		}
+	}
+
+	return apiToken, nil
+}
+
+//ApiTokenAllows checks whether method on resource kind is in scopes of token,
+//action is only allowed by scope of ACTION even though it is posted
+func ApiTokenAllows(apiToken *resource.ApiToken, kind, method string, isAction bool) bool {
+	if kind == apiTokenKind {
+		return false
+	}
+
+	operation := string(resource.RequestOperation(method, isAction))
+	for _, scope := range apiToken.Scopes {
+		fields := strings.SplitN(scope, apiTokenScopeSeparator, 2)
+		if fields[0] != resource.ApiTokenScopeAll && fields[0] != kind {
+			continue
+		}
+
+		if len(fields) == 1 || fields[1] == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateApiTokenScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("scopes of api token should not be empty")
+	}
+
+	for _, scope := range scopes {
+		fields := strings.SplitN(scope, apiTokenScopeSeparator, 2)
+		if fields[0] == "" {
+			return fmt.Errorf("scope %s has no resource kind", scope)
+		}
+
+		if fields[0] == apiTokenKind {
+			return fmt.Errorf("api token can't manage api tokens")
+		}
+
+		if len(fields) == 2 && isApiTokenScopeMethod(fields[1]) == false {
+			return fmt.Errorf("scope %s has unknown method %s", scope, fields[1])
+		}
+	}
+
+	return nil
+}
+
+func isApiTokenScopeMethod(method string) bool {
+	for _, m := range apiTokenScopeMethods {
+		if string(m) == method {
+			return true
+		}
+	}
+	return false
+}
+
+func hashApiTokenSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+//deleteUserApiTokens revokes all api tokens of user
+func deleteUserApiTokens(userName string, tx restdb.Transaction) error {
+	_, err := tx.Delete(TableApiToken, map[string]interface{}{"username": userName})
+	return err
+}
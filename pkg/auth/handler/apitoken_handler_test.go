@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+func TestValidateApiTokenScopes(t *testing.T) {
+	if err := validateApiTokenScopes([]string{"website", "vip:GET", "*:ACTION"}); err != nil {
+		t.Errorf("scopes should be valid: %s", err.Error())
+	}
+
+	for _, scopes := range [][]string{nil, {""}, {":GET"}, {"website:PATCH"}, {"apitoken"}, {"apitoken:GET"}} {
+		if err := validateApiTokenScopes(scopes); err == nil {
+			t.Errorf("scopes %v should be invalid", scopes)
+		}
+	}
+}
+
+func TestApiTokenAllows(t *testing.T) {
+	apiToken := &resource.ApiToken{Scopes: []string{"website", "vip:GET", "*:ACTION", "node:POST"}}
+	for _, c := range []struct {
+		kind     string
+		method   string
+		isAction bool
+		allowed  bool
+	}{
+		{"website", "POST", false, true},
+		{"website", "DELETE", false, true},
+		{"vip", "GET", false, true},
+		{"vip", "PUT", false, false},
+		{"cluster", "GET", false, false},
+		{"cluster", "POST", true, true},
+		{"node", "POST", false, true},
+		{"apitoken", "POST", true, false},
+	} {
+		if ApiTokenAllows(apiToken, c.kind, c.method, c.isAction) != c.allowed {
+			t.Errorf("%s %s action %v expected allowed %v", c.method, c.kind, c.isAction, c.allowed)
+		}
+	}
+
+	apiToken = &resource.ApiToken{Scopes: []string{"cluster:POST"}}
+	if ApiTokenAllows(apiToken, "cluster", "POST", true) {
+		t.Errorf("scope of POST should not allow action")
+	}
+}
+
+func TestIsApiToken(t *testing.T) {
+	if IsApiToken(ApiTokenPrefix+"id.secret") == false {
+		t.Errorf("token with prefix should be api token")
+	}
+
+	if IsApiToken("eyJhbGciOiJIUzI1NiJ9.e30.sig") {
+		t.Errorf("jwt should not be api token")
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+)
+
+//ProvisionUser creates or updates user authenticated by external source at
+//login, its user groups and roles are replaced by the ones which exist in
+//userGroupNames and roleNames, a local user with the same name isn't touched
+func ProvisionUser(userName, source string, userGroupNames, roleNames []string) error {
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		return provisionUser(tx, userName, source, userGroupNames, roleNames)
+	})
+}
+
+func provisionUser(tx restdb.Transaction, userName, source string, userGroupNames, roleNames []string) error {
+	userGroupIds, err := getUserGroupIdsByNames(tx, userGroupNames)
+	if err != nil {
+		return err
+	}
+
+	roleIds, err := getRoleIdsByNames(tx, roleNames)
+	if err != nil {
+		return err
+	}
+
+	var users []*resource.Ddiuser
+	if err := tx.Fill(map[string]interface{}{restdb.IDField: userName}, &users); err != nil {
+		return err
+	}
+
+	if len(users) == 0 {
+		user := &resource.Ddiuser{
+			Name:                userName,
+			RoleType:            resource.RoleTypeNORMAL,
+			Source:              source,
+			UserGroupIds:        userGroupIds,
+			RoleIds:             roleIds,
+			PasswordHistory:     []string{},
+			PasswordChangedTime: time.Now(),
+		}
+		user.SetID(userName)
+		_, err := tx.Insert(user)
+		return err
+	}
+
+	if users[0].Source != source {
+		return fmt.Errorf("user %s already exists with source %s", userName, users[0].Source)
+	}
+
+	return updateUserToDB(userName, map[string]interface{}{
+		"user_group_ids": userGroupIds,
+		"role_ids":       roleIds}, tx)
+}
+
+func getUserGroupIdsByNames(tx restdb.Transaction, names []string) ([]string, error) {
+	ids := []string{}
+	for _, name := range names {
+		var userGroups []*resource.UserGroup
+		if err := tx.Fill(map[string]interface{}{"name": name}, &userGroups); err != nil {
+			return nil, err
+		}
+		if len(userGroups) != 0 {
+			ids = append(ids, userGroups[0].GetID())
+		}
+	}
+	return ids, nil
+}
+
+func getRoleIdsByNames(tx restdb.Transaction, names []string) ([]string, error) {
+	ids := []string{}
+	for _, name := range names {
+		var roles []*resource.Role
+		if err := tx.Fill(map[string]interface{}{"name": name}, &roles); err != nil {
+			return nil, err
+		}
+		if len(roles) != 0 {
+			ids = append(ids, roles[0].GetID())
+		}
+	}
+	return ids, nil
+}
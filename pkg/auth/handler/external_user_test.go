@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"testing"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authorization"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+//userTx keeps users in memory, there is no user group or role
+type userTx struct {
+	restdb.Transaction
+	users map[string]*resource.Ddiuser
+}
+
+func (tx *userTx) Fill(conds map[string]interface{}, out interface{}) error {
+	users, ok := out.(*[]*resource.Ddiuser)
+	if ok == false {
+		return nil
+	}
+
+	for id, user := range tx.users {
+		if conds == nil || conds[restdb.IDField] == id {
+			*users = append(*users, user)
+		}
+	}
+	return nil
+}
+
+func (tx *userTx) FillEx(out interface{}, sql string, args ...interface{}) error {
+	return nil
+}
+
+func (tx *userTx) Insert(r restresource.Resource) (restresource.Resource, error) {
+	user := r.(*resource.Ddiuser)
+	tx.users[user.GetID()] = user
+	return r, nil
+}
+
+func (tx *userTx) Update(typ restdb.ResourceType, nv map[string]interface{}, conds map[string]interface{}) (int64, error) {
+	user, ok := tx.users[conds[restdb.IDField].(string)]
+	if ok == false {
+		return 0, nil
+	}
+
+	for field, value := range nv {
+		switch field {
+		case "password":
+			user.Password = value.(string)
+		case "comment":
+			user.Comment = value.(string)
+		case "user_group_ids":
+			user.UserGroupIds = value.([]string)
+		case "role_ids":
+			user.RoleIds = value.([]string)
+		}
+	}
+	return 1, nil
+}
+
+func loadRoleTemplate(t *testing.T) {
+	if _, err := config.LoadConfig("../../../etc/web-controller.conf"); err != nil {
+		t.Fatalf("load config failed: %s", err.Error())
+	}
+
+	config.GetConfig().Server.RoleConf = "../../../etc/ddi-role.json"
+	if err := authorization.InitAuthorization(); err != nil {
+		t.Fatalf("load role config failed: %s", err.Error())
+	}
+}
+
+func TestLoadUsersAfterProvision(t *testing.T) {
+	loadRoleTemplate(t)
+	tx := &userTx{users: make(map[string]*resource.Ddiuser)}
+	if err := loadUsers(tx); err != nil {
+		t.Fatalf("load users failed: %s", err.Error())
+	}
+
+	for name, source := range map[string]string{"alice": resource.UserSourceLDAP, "bob": resource.UserSourceOIDC} {
+		if err := provisionUser(tx, name, source, []string{"group"}, []string{"role"}); err != nil {
+			t.Fatalf("provision user %s failed: %s", name, err.Error())
+		}
+	}
+
+	if err := provisionUser(tx, Admin, resource.UserSourceLDAP, nil, nil); err == nil {
+		t.Errorf("provision user with the name of local user should fail")
+	}
+
+	//controller restarted
+	if err := loadUsers(tx); err != nil {
+		t.Fatalf("load users with external users failed: %s", err.Error())
+	}
+
+	if len(tx.users) != 3 || isPasswordHashed(tx.users[Admin].Password) == false {
+		t.Errorf("admin should be created once with hashed password")
+	}
+
+	for _, name := range []string{"alice", "bob"} {
+		if user := tx.users[name]; user.Password != "" {
+			t.Errorf("password of external user %s should be empty but get %s", name, user.Password)
+		}
+	}
+}
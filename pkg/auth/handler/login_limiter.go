@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	logresource "github.com/trymanytimes/UpdateWeb/pkg/log/resource"
+)
+
+const (
+	defaultMaxUserFailures = 5
+	defaultMaxIPFailures   = 20
+	defaultBaseBackoff     = time.Second
+	defaultMaxBackoff      = time.Minute
+	defaultLockoutDuration = 15 * time.Minute
+	defaultFailureWindow   = 15 * time.Minute
+	loginAuditLogPeriod    = 180 //day
+
+	loginAuditMethodLockout = "lockout"
+	loginAuditKindLogin     = "login"
+)
+
+type loginFailure struct {
+	count        uint32
+	lastFailure  time.Time
+	blockedUntil time.Time
+	locked       bool
+}
+
+//loginLimiter counts failed logins per username and per source ip, every
+//failure blocks the next login by exponential backoff, too many failures
+//lock the username or source ip for lockout duration
+type loginLimiter struct {
+	lock            sync.Mutex
+	users           map[string]*loginFailure
+	ips             map[string]*loginFailure
+	maxUserFailures uint32
+	maxIPFailures   uint32
+	baseBackoff     time.Duration
+	maxBackoff      time.Duration
+	lockoutDuration time.Duration
+	failureWindow   time.Duration
+}
+
+var gLoginLimiter = newLoginLimiter(&config.LoginLimitConf{})
+
+func initLoginLimiter(conf *config.LoginLimitConf) {
+	gLoginLimiter = newLoginLimiter(conf)
+	go gLoginLimiter.run()
+}
+
+func newLoginLimiter(conf *config.LoginLimitConf) *loginLimiter {
+	limiter := &loginLimiter{
+		users:           make(map[string]*loginFailure),
+		ips:             make(map[string]*loginFailure),
+		maxUserFailures: defaultMaxUserFailures,
+		maxIPFailures:   defaultMaxIPFailures,
+		baseBackoff:     defaultBaseBackoff,
+		maxBackoff:      defaultMaxBackoff,
+		lockoutDuration: defaultLockoutDuration,
+		failureWindow:   defaultFailureWindow,
+	}
+
+	if conf.MaxUserFailures != 0 {
+		limiter.maxUserFailures = conf.MaxUserFailures
+	}
+	if conf.MaxIPFailures != 0 {
+		limiter.maxIPFailures = conf.MaxIPFailures
+	}
+	if conf.BaseBackoff != 0 {
+		limiter.baseBackoff = time.Duration(conf.BaseBackoff) * time.Second
+	}
+	if conf.MaxBackoff != 0 {
+		limiter.maxBackoff = time.Duration(conf.MaxBackoff) * time.Second
+	}
+	if conf.LockoutDuration != 0 {
+		limiter.lockoutDuration = time.Duration(conf.LockoutDuration) * time.Second
+	}
+	if conf.FailureWindow != 0 {
+		limiter.failureWindow = time.Duration(conf.FailureWindow) * time.Second
+	}
+	return limiter
+}
+
+//run drops outdated failures, so failures of usernames which never login
+//again don't stay in memory
+func (l *loginLimiter) run() {
+	ticker := time.NewTicker(l.failureWindow)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		l.lock.Lock()
+		for _, failures := range []map[string]*loginFailure{l.users, l.ips} {
+			for key := range failures {
+				l.getFailure(failures, key, now)
+			}
+		}
+		l.lock.Unlock()
+	}
+}
+
+//CheckLoginAllowed returns the seconds to wait if the username or source ip
+//is in backoff or locked, password shouldn't be checked then
+func CheckLoginAllowed(userName, sourceIp string) (int, error) {
+	return gLoginLimiter.check(userName, sourceIp, time.Now())
+}
+
+//LoginFailed records a failed login, the username and source ip are locked
+//when their failures reach the threshold
+func LoginFailed(userName, sourceIp string) {
+	gLoginLimiter.fail(userName, sourceIp, time.Now())
+}
+
+//LoginSucceeded clears failures of the username, failures of the source ip
+//are kept until failure window passes, so one valid account can't be used
+//to hide guessing of others
+func LoginSucceeded(userName string) {
+	gLoginLimiter.lock.Lock()
+	delete(gLoginLimiter.users, userName)
+	gLoginLimiter.lock.Unlock()
+}
+
+//UnlockUser clears failures and lockout of the username, it returns false if
+//the username isn't locked or in backoff
+func UnlockUser(userName string) bool {
+	gLoginLimiter.lock.Lock()
+	defer gLoginLimiter.lock.Unlock()
+	_, ok := gLoginLimiter.users[userName]
+	delete(gLoginLimiter.users, userName)
+	return ok
+}
+
+func (l *loginLimiter) check(userName, sourceIp string, now time.Time) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if failure := l.getFailure(l.ips, sourceIp, now); failure != nil && now.Before(failure.blockedUntil) {
+		seconds := retryAfter(failure.blockedUntil, now)
+		return seconds, fmt.Errorf("too many failed logins from %s, retry after %d seconds", sourceIp, seconds)
+	}
+
+	if failure := l.getFailure(l.users, userName, now); failure != nil && now.Before(failure.blockedUntil) {
+		seconds := retryAfter(failure.blockedUntil, now)
+		if failure.locked {
+			return seconds, fmt.Errorf("user %s is locked, retry after %d seconds", userName, seconds)
+		}
+
+		return seconds, fmt.Errorf("too many failed logins, retry after %d seconds", seconds)
+	}
+
+	return 0, nil
+}
+
+//getFailure drops the failure whose lockout is over or which is out of
+//failure window
+func (l *loginLimiter) getFailure(failures map[string]*loginFailure, key string, now time.Time) *loginFailure {
+	failure, ok := failures[key]
+	if ok == false {
+		return nil
+	}
+
+	if now.Before(failure.blockedUntil) == false &&
+		(failure.locked || now.Sub(failure.lastFailure) > l.failureWindow) {
+		delete(failures, key)
+		return nil
+	}
+
+	return failure
+}
+
+func (l *loginLimiter) fail(userName, sourceIp string, now time.Time) {
+	l.lock.Lock()
+	userLocked := l.addFailure(l.users, userName, l.maxUserFailures, now)
+	ipLocked := l.addFailure(l.ips, sourceIp, l.maxIPFailures, now)
+	l.lock.Unlock()
+
+	if userLocked {
+		log.Warnf("user %s is locked for %s after %d failed logins, last from %s",
+			userName, l.lockoutDuration, l.maxUserFailures, sourceIp)
+		l.addAuditLog(userName, sourceIp, restresource.DefaultKindName(resource.Ddiuser{}), userName, l.maxUserFailures)
+	}
+
+	if ipLocked {
+		log.Warnf("source ip %s is locked for %s after %d failed logins", sourceIp, l.lockoutDuration, l.maxIPFailures)
+		l.addAuditLog(userName, sourceIp, loginAuditKindLogin, sourceIp, l.maxIPFailures)
+	}
+}
+
+//addFailure returns true when the key is locked by this failure
+func (l *loginLimiter) addFailure(failures map[string]*loginFailure, key string, maxFailures uint32, now time.Time) bool {
+	failure := l.getFailure(failures, key, now)
+	if failure == nil {
+		failure = &loginFailure{}
+		failures[key] = failure
+	}
+
+	failure.count += 1
+	failure.lastFailure = now
+	if failure.count >= maxFailures {
+		failure.blockedUntil = now.Add(l.lockoutDuration)
+		if failure.locked == false {
+			failure.locked = true
+			return true
+		}
+		return false
+	}
+
+	backoff := l.baseBackoff << (failure.count - 1)
+	if backoff > l.maxBackoff || backoff <= 0 {
+		backoff = l.maxBackoff
+	}
+	failure.blockedUntil = now.Add(backoff)
+	return false
+}
+
+func retryAfter(blockedUntil, now time.Time) int {
+	seconds := int(blockedUntil.Sub(now) / time.Second)
+	if blockedUntil.Sub(now)%time.Second != 0 {
+		seconds += 1
+	}
+	return seconds
+}
+
+func (l *loginLimiter) addAuditLog(userName, sourceIp, kind, id string, failures uint32) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"failures":        failures,
+		"lockoutDuration": uint32(l.lockoutDuration / time.Second),
+	})
+	auditLog := &logresource.AuditLog{
+		Username:     userName,
+		SourceIp:     sourceIp,
+		Method:       loginAuditMethodLockout,
+		ResourceKind: kind,
+		ResourceId:   id,
+		Parameters:   string(data),
+		Succeed:      true,
+		Expire:       time.Now().AddDate(0, 0, loginAuditLogPeriod),
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Insert(auditLog)
+		return err
+	}); err != nil {
+		log.Warnf("insert lockout auditlog of %s failed: %s", id, err.Error())
+	}
+}
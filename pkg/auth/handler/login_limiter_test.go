@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+)
+
+func TestLoginLimiterBackoffAndLockout(t *testing.T) {
+	limiter := newLoginLimiter(&config.LoginLimitConf{
+		MaxUserFailures: 3,
+		MaxIPFailures:   10,
+		BaseBackoff:     1,
+		MaxBackoff:      60,
+		LockoutDuration: 600,
+		FailureWindow:   900,
+	})
+
+	now := time.Now()
+	if limiter.addFailure(limiter.users, "user1", limiter.maxUserFailures, now) {
+		t.Errorf("user should not be locked after 1 failure")
+	}
+
+	if seconds, err := limiter.check("user1", "10.0.0.1", now); err == nil || seconds != 1 {
+		t.Errorf("login should be delayed 1 second after 1 failure but get %d", seconds)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := limiter.check("user1", "10.0.0.1", now); err != nil {
+		t.Errorf("login should be allowed after backoff: %s", err.Error())
+	}
+
+	limiter.addFailure(limiter.users, "user1", limiter.maxUserFailures, now)
+	if seconds, _ := limiter.check("user1", "10.0.0.1", now); seconds != 2 {
+		t.Errorf("login should be delayed 2 seconds after 2 failures but get %d", seconds)
+	}
+
+	now = now.Add(2 * time.Second)
+	if limiter.addFailure(limiter.users, "user1", limiter.maxUserFailures, now) == false {
+		t.Errorf("user should be locked after 3 failures")
+	}
+
+	if seconds, err := limiter.check("user1", "10.0.0.2", now.Add(time.Minute)); err == nil || seconds != 540 {
+		t.Errorf("locked user should wait 540 seconds but get %d", seconds)
+	}
+
+	if _, err := limiter.check("user2", "10.0.0.1", now); err != nil {
+		t.Errorf("lockout of user1 should not affect user2: %s", err.Error())
+	}
+
+	if _, err := limiter.check("user1", "10.0.0.1", now.Add(10*time.Minute)); err != nil {
+		t.Errorf("login should be allowed after lockout: %s", err.Error())
+	}
+
+	if _, ok := limiter.users["user1"]; ok {
+		t.Errorf("failures should be cleared after lockout")
+	}
+}
+
+func TestLoginLimiterFailureWindow(t *testing.T) {
+	limiter := newLoginLimiter(&config.LoginLimitConf{MaxUserFailures: 2, FailureWindow: 60})
+	now := time.Now()
+	limiter.addFailure(limiter.users, "user1", limiter.maxUserFailures, now)
+	if limiter.addFailure(limiter.users, "user1", limiter.maxUserFailures, now.Add(2*time.Minute)) {
+		t.Errorf("failure out of window should be forgotten")
+	}
+}
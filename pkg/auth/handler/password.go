@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const bcryptHashPrefix = "$2"
+
+var (
+	//legacyAESKey is only used to migrate passwords saved by util.Encrypt
+	legacyAESKey = []byte("linkingthing.com")
+	//dummyPasswordHash is compared when user doesn't exist, so checking an
+	//unknown user costs the same time as a known one
+	dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("linkingthing.com"), bcrypt.DefaultCost)
+)
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func isPasswordHashed(password string) bool {
+	return strings.HasPrefix(password, bcryptHashPrefix)
+}
+
+func comparePassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+//migrateLegacyPassword returns bcrypt hash and plain text of the password
+//saved by util.Encrypt
+func migrateLegacyPassword(encrypted string) (string, string, error) {
+	password, err := util.Decrypt(legacyAESKey, encrypted)
+	if err != nil {
+		return "", "", err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return "", "", err
+	}
+
+	return hash, password, nil
+}
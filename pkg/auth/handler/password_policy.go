@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	defaultPasswordMaxLength = 64
+	//bcrypt ignores bytes of password after the first 72 bytes
+	bcryptMaxPasswordLength = 72
+)
+
+//passwordPolicy history count includes the current password, max age is
+//disabled if it is zero, min length is in characters and max length is in
+//bytes which is limited by bcrypt
+type passwordPolicy struct {
+	minLength        int
+	maxLength        int
+	requireUppercase bool
+	requireLowercase bool
+	requireDigit     bool
+	requireSpecial   bool
+	historyCount     int
+	maxAge           time.Duration
+}
+
+var gPasswordPolicy = newPasswordPolicy(&config.PasswordPolicyConf{})
+
+func newPasswordPolicy(conf *config.PasswordPolicyConf) *passwordPolicy {
+	policy := &passwordPolicy{
+		minLength:        defaultPasswordMinLength,
+		maxLength:        defaultPasswordMaxLength,
+		requireUppercase: conf.RequireUppercase,
+		requireLowercase: conf.RequireLowercase,
+		requireDigit:     conf.RequireDigit,
+		requireSpecial:   conf.RequireSpecial,
+		historyCount:     int(conf.HistoryCount),
+		maxAge:           time.Duration(conf.MaxAge) * 24 * time.Hour,
+	}
+
+	if conf.MinLength != 0 {
+		policy.minLength = int(conf.MinLength)
+	}
+	if conf.MaxLength != 0 {
+		policy.maxLength = int(conf.MaxLength)
+	}
+	return policy
+}
+
+func checkPasswordPolicyConf(conf *config.PasswordPolicyConf) error {
+	policy := newPasswordPolicy(conf)
+	if policy.maxLength > bcryptMaxPasswordLength {
+		return fmt.Errorf("password max length %d should not be more than %d bytes",
+			policy.maxLength, bcryptMaxPasswordLength)
+	}
+
+	if policy.minLength > policy.maxLength {
+		return fmt.Errorf("password min length %d should not be more than max length %d",
+			policy.minLength, policy.maxLength)
+	}
+
+	return nil
+}
+
+func (p *passwordPolicy) validate(password string) error {
+	if len([]rune(password)) < p.minLength {
+		return fmt.Errorf("password should have at least %d characters", p.minLength)
+	}
+
+	if len(password) > p.maxLength {
+		return fmt.Errorf("password should not be more than %d bytes", p.maxLength)
+	}
+
+	var hasUppercase, hasLowercase, hasDigit, hasSpecial bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUppercase = true
+		case unicode.IsLower(c):
+			hasLowercase = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			hasSpecial = true
+		}
+	}
+
+	var missing []string
+	if p.requireUppercase && hasUppercase == false {
+		missing = append(missing, "uppercase letter")
+	}
+	if p.requireLowercase && hasLowercase == false {
+		missing = append(missing, "lowercase letter")
+	}
+	if p.requireDigit && hasDigit == false {
+		missing = append(missing, "digit")
+	}
+	if p.requireSpecial && hasSpecial == false {
+		missing = append(missing, "special character")
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("password should contain at least one %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+//checkHistory refuses password which is same as the current one or the ones
+//before it, history count passwords are checked
+func (p *passwordPolicy) checkHistory(user *resource.Ddiuser, password string) error {
+	if p.historyCount == 0 {
+		return nil
+	}
+
+	for i, hash := range append([]string{user.Password}, user.PasswordHistory...) {
+		if i >= p.historyCount {
+			break
+		}
+
+		if hash != "" && comparePassword(hash, password) {
+			return fmt.Errorf("password should not be the same as the last %d ones", p.historyCount)
+		}
+	}
+
+	return nil
+}
+
+//pushHistory returns the previous passwords after the current password is
+//replaced, newest first, at most history count - 1 ones are kept
+func (p *passwordPolicy) pushHistory(user *resource.Ddiuser) []string {
+	if p.historyCount <= 1 || user.Password == "" {
+		return []string{}
+	}
+
+	history := append([]string{user.Password}, user.PasswordHistory...)
+	if len(history) > p.historyCount-1 {
+		history = history[:p.historyCount-1]
+	}
+	return history
+}
+
+func (p *passwordPolicy) isExpired(user *resource.Ddiuser, now time.Time) bool {
+	return p.maxAge != 0 && user.PasswordChangedTime.IsZero() == false &&
+		now.Sub(user.PasswordChangedTime) > p.maxAge
+}
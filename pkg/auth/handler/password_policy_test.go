@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := newPasswordPolicy(&config.PasswordPolicyConf{
+		MinLength:        8,
+		MaxLength:        16,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSpecial:   true,
+	})
+
+	for _, password := range []string{"Linking@123", "Aa1!aaaa", "测试Pass_word9"} {
+		if err := policy.validate(password); err != nil {
+			t.Errorf("password %s should be valid: %s", password, err.Error())
+		}
+	}
+
+	for _, password := range []string{"", "Aa1!aaa", "Linking@123456789", "linking@123", "LINKING@123",
+		"Linking@abc", "Linking1234", "测试测试Pass_word9"} {
+		if err := policy.validate(password); err == nil {
+			t.Errorf("password %s should be invalid", password)
+		}
+	}
+}
+
+func TestCheckPasswordPolicyConf(t *testing.T) {
+	tests := []struct {
+		conf  config.PasswordPolicyConf
+		valid bool
+	}{
+		{config.PasswordPolicyConf{}, true},
+		{config.PasswordPolicyConf{MaxLength: 72}, true},
+		{config.PasswordPolicyConf{MaxLength: 73}, false},
+		{config.PasswordPolicyConf{MinLength: 65}, false},
+		{config.PasswordPolicyConf{MinLength: 16, MaxLength: 12}, false},
+	}
+
+	for _, tt := range tests {
+		if err := checkPasswordPolicyConf(&tt.conf); (err == nil) != tt.valid {
+			t.Errorf("password policy %+v expected valid %v but get %v", tt.conf, tt.valid, err)
+		}
+	}
+}
+
+func TestPasswordPolicyHistory(t *testing.T) {
+	policy := newPasswordPolicy(&config.PasswordPolicyConf{HistoryCount: 3})
+	user := &resource.Ddiuser{Password: "hash3", PasswordHistory: []string{"hash2", "hash1"}}
+	history := policy.pushHistory(user)
+	if len(history) != 2 || history[0] != "hash3" || history[1] != "hash2" {
+		t.Errorf("history should keep the last 2 replaced passwords but get %v", history)
+	}
+
+	hash, _ := hashPassword("Linking@123")
+	user = &resource.Ddiuser{Password: hash}
+	if err := policy.checkHistory(user, "Linking@123"); err == nil {
+		t.Errorf("current password should not be reused")
+	}
+
+	user = &resource.Ddiuser{PasswordHistory: []string{hash}}
+	if err := policy.checkHistory(user, "Linking@123"); err == nil {
+		t.Errorf("password in history should not be reused")
+	}
+
+	if err := policy.checkHistory(user, "Linking@456"); err != nil {
+		t.Errorf("password not in history should be allowed: %s", err.Error())
+	}
+}
+
+func TestPasswordPolicyExpired(t *testing.T) {
+	now := time.Now()
+	user := &resource.Ddiuser{PasswordChangedTime: now.AddDate(0, 0, -91)}
+	if newPasswordPolicy(&config.PasswordPolicyConf{}).isExpired(user, now) {
+		t.Errorf("password should never expire if max age is zero")
+	}
+
+	policy := newPasswordPolicy(&config.PasswordPolicyConf{MaxAge: 90})
+	if policy.isExpired(user, now) == false {
+		t.Errorf("password changed 91 days ago should be expired")
+	}
+
+	user.PasswordChangedTime = now.AddDate(0, 0, -89)
+	if policy.isExpired(user, now) {
+		t.Errorf("password changed 89 days ago should not be expired")
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := hashPassword("Linking@123")
+	if err != nil {
+		t.Fatalf("hash password failed: %s", err.Error())
+	}
+
+	if isPasswordHashed(hash) == false {
+		t.Errorf("%s should be a bcrypt hash", hash)
+	}
+
+	if comparePassword(hash, "Linking@123") == false {
+		t.Errorf("compare with right password should succeed")
+	}
+
+	if comparePassword(hash, "linking@123") {
+		t.Errorf("compare with wrong password should fail")
+	}
+
+	if another, _ := hashPassword("Linking@123"); another == hash {
+		t.Errorf("hash of same password should be salted")
+	}
+}
+
+func TestMigrateLegacyPassword(t *testing.T) {
+	encrypted, err := util.Encrypt(legacyAESKey, "admin")
+	if err != nil {
+		t.Fatalf("encrypt password failed: %s", err.Error())
+	}
+
+	if isPasswordHashed(encrypted) {
+		t.Fatalf("%s should not be a bcrypt hash", encrypted)
+	}
+
+	hash, password, err := migrateLegacyPassword(encrypted)
+	if err != nil {
+		t.Fatalf("migrate password failed: %s", err.Error())
+	}
+
+	if password != "admin" || comparePassword(hash, "admin") == false {
+		t.Errorf("migrated password should be admin but get %s", password)
+	}
+
+	for _, encrypted := range []string{"", "not hex", encrypted[:10]} {
+		if _, _, err := migrateLegacyPassword(encrypted); err == nil {
+			t.Errorf("migrate invalid password %q should fail", encrypted)
+		}
+	}
+}
+
+func TestMigrateUserPasswordSkipped(t *testing.T) {
+	encrypted, _ := util.Encrypt(legacyAESKey, "admin")
+	for _, user := range []*resource.Ddiuser{
+		&resource.Ddiuser{Name: "u1", Source: resource.UserSourceLocal},
+		&resource.Ddiuser{Name: "u2"},
+		&resource.Ddiuser{Name: "u3", Source: resource.UserSourceLDAP},
+		&resource.Ddiuser{Name: "u4", Source: resource.UserSourceOIDC, Password: encrypted},
+	} {
+		//nil tx panics if password is updated
+		if err := migrateUserPassword(user, nil); err != nil {
+			t.Errorf("migrate password of user %s should be skipped but get %s", user.Name, err.Error())
+		}
+	}
+}
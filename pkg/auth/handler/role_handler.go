@@ -7,6 +7,7 @@ import (
 	resterror "github.com/zdnscloud/gorest/error"
 	restresource "github.com/zdnscloud/gorest/resource"
 
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authorization"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
 	"github.com/trymanytimes/UpdateWeb/pkg/db"
 )
@@ -24,6 +25,10 @@ func NewRoleHandler() (*RoleHandler, error) {
 
 func (h *RoleHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	role := ctx.Resource.(*resource.Role)
+	if err := authorization.ValidateBusinessOperations(role.BusinessOperations); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
 	role.SetID(role.Name)
 	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
 		_, err := tx.Insert(role)
@@ -38,11 +43,18 @@ func (h *RoleHandler) Create(ctx *restresource.Context) (restresource.Resource,
 
 func (h *RoleHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	role := ctx.Resource.(*resource.Role)
+	if err := authorization.ValidateBusinessOperations(role.BusinessOperations); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
 	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
 		_, err := tx.Update(TableRole, map[string]interface{}{
-			"comment": role.Comment,
-			"views":   role.Views,
-			"plans":   role.Plans,
+			"comment":             role.Comment,
+			"views":               role.Views,
+			"plans":               role.Plans,
+			"business_operations": role.BusinessOperations,
+			"web_groups":          role.WebGroups,
+			"require_two_factor":  role.RequireTwoFactor,
 		}, map[string]interface{}{restdb.IDField: role.GetID()})
 		if err != nil {
 			return fmt.Errorf("update role %s err:%s", role.Name, err.Error())
@@ -114,6 +126,7 @@ func updateRoleAuthority(roleID string, tx restdb.Transaction) error {
 			"user_group_ids": user.UserGroupIds}, tx); err != nil {
 			return err
 		}
+		ActivityUsers.Store(user.Name, user)
 	}
 
 	return nil
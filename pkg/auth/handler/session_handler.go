@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	sessionIDLen          = 32
+	refreshSecretLen      = 48
+	refreshTokenSeparator = "."
+)
+
+var TableSession = restdb.ResourceDBType(&resource.Session{})
+
+var errRefreshTokenReused = errors.New("refresh token invalid")
+
+type SessionHandler struct{}
+
+func NewSessionHandler() *SessionHandler {
+	h := &SessionHandler{}
+	go h.run()
+	return h
+}
+
+func (h *SessionHandler) run() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+				_, err := tx.Exec("delete from gr_session where expire_time < now()")
+				return err
+			}); err != nil {
+				log.Warnf("delete expired session failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (h *SessionHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	var sessions []*resource.Session
+	if err := db.GetResources(map[string]interface{}{"orderby": "create_time"}, &sessions); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("list sessions from db failed: %s", err.Error()))
+	}
+
+	now := time.Now()
+	var actives []*resource.Session
+	for _, session := range sessions {
+		if session.ExpireTime.After(now) {
+			actives = append(actives, session)
+		}
+	}
+
+	return actives, nil
+}
+
+func (h *SessionHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	session, err := GetSession(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	if session == nil {
+		return nil, resterror.NewAPIError(resterror.NotFound,
+			fmt.Sprintf("session %s is non-exists", ctx.Resource.GetID()))
+	}
+
+	return session, nil
+}
+
+//Delete revokes session, its access token is refused at once
+func (h *SessionHandler) Delete(ctx *restresource.Context) *resterror.APIError {
+	if err := DeleteSession(ctx.Resource.GetID()); err != nil {
+		return resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return nil
+}
+
+//CreateSession returns the session and its refresh token, only hash of the
+//refresh token is saved
+func CreateSession(userName, sourceIp, userAgent string, lifetime time.Duration) (*resource.Session, string, error) {
+	secret := util.CreateRandomString(refreshSecretLen)
+	now := time.Now()
+	session := &resource.Session{
+		Username:         userName,
+		SourceIp:         sourceIp,
+		UserAgent:        userAgent,
+		LastRefreshTime:  now,
+		ExpireTime:       now.Add(lifetime),
+		RefreshTokenHash: hashRefreshSecret(secret),
+	}
+	session.SetID(util.CreateRandomString(sessionIDLen))
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Insert(session)
+		return err
+	}); err != nil {
+		return nil, "", fmt.Errorf("insert session of user %s into db failed: %s", userName, err.Error())
+	}
+
+	return session, session.GetID() + refreshTokenSeparator + secret, nil
+}
+
+//RefreshSession replaces refresh token of the session, the expire time of
+//session is not extended, a refresh token used twice revokes its session
+func RefreshSession(refreshToken, sourceIp, userAgent string) (*resource.Session, string, error) {
+	var session *resource.Session
+	var newRefreshToken string
+	var refreshErr error
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		var err error
+		session, newRefreshToken, err = refreshSession(refreshToken, sourceIp, userAgent, time.Now(), tx)
+		if err == errRefreshTokenReused {
+			//commit the revocation of session
+			refreshErr = err
+			return nil
+		}
+		return err
+	}); err != nil {
+		return nil, "", err
+	}
+
+	if refreshErr != nil {
+		return nil, "", refreshErr
+	}
+
+	return session, newRefreshToken, nil
+}
+
+func refreshSession(refreshToken, sourceIp, userAgent string, now time.Time, tx restdb.Transaction) (*resource.Session, string, error) {
+	fields := strings.SplitN(refreshToken, refreshTokenSeparator, 2)
+	if len(fields) != 2 {
+		return nil, "", fmt.Errorf("refresh token invalid")
+	}
+
+	session, err := getSessionFromDB(fields[0], tx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if session == nil || session.ExpireTime.Before(now) {
+		return nil, "", fmt.Errorf("session expired")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(session.RefreshTokenHash), []byte(hashRefreshSecret(fields[1]))) != 1 {
+		if _, err := tx.Delete(TableSession, map[string]interface{}{restdb.IDField: session.GetID()}); err != nil {
+			return nil, "", fmt.Errorf("revoke session %s of reused refresh token failed: %s", session.GetID(), err.Error())
+		}
+		return nil, "", errRefreshTokenReused
+	}
+
+	secret := util.CreateRandomString(refreshSecretLen)
+	session.RefreshTokenHash = hashRefreshSecret(secret)
+	session.LastRefreshTime = now
+	session.SourceIp = sourceIp
+	session.UserAgent = userAgent
+	if _, err := tx.Update(TableSession, map[string]interface{}{
+		"refresh_token_hash": session.RefreshTokenHash,
+		"last_refresh_time":  session.LastRefreshTime,
+		"source_ip":          session.SourceIp,
+		"user_agent":         session.UserAgent,
+	}, map[string]interface{}{restdb.IDField: session.GetID()}); err != nil {
+		return nil, "", fmt.Errorf("update session %s failed: %s", session.GetID(), err.Error())
+	}
+
+	return session, session.GetID() + refreshTokenSeparator + secret, nil
+}
+
+func hashRefreshSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+//GetSession returns nil if session doesn't exist
+func GetSession(id string) (*resource.Session, error) {
+	var session *resource.Session
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		var err error
+		session, err = getSessionFromDB(id, tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func getSessionFromDB(id string, tx restdb.Transaction) (*resource.Session, error) {
+	var sessions []*resource.Session
+	if err := tx.Fill(map[string]interface{}{restdb.IDField: id}, &sessions); err != nil {
+		return nil, fmt.Errorf("get session %s from db failed: %s", id, err.Error())
+	}
+
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	return sessions[0], nil
+}
+
+func DeleteSession(id string) error {
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Delete(TableSession, map[string]interface{}{restdb.IDField: id})
+		return err
+	}); err != nil {
+		return fmt.Errorf("delete session %s from db failed: %s", id, err.Error())
+	}
+
+	return nil
+}
+
+//deleteUserSessions revokes sessions of user except the one kept
+func deleteUserSessions(userName, keptSessionID string, tx restdb.Transaction) error {
+	_, err := tx.Exec("delete from gr_session where username = $1 and id != $2", userName, keptSessionID)
+	return err
+}
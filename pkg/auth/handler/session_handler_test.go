@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+const deleteUserSessionsSql = "delete from gr_session where username = $1 and id != $2"
+
+//sessionTx keeps sessions in memory, only methods used by session handler
+//are implemented
+type sessionTx struct {
+	restdb.Transaction
+	sessions map[string]*resource.Session
+}
+
+func newSessionTx() *sessionTx {
+	return &sessionTx{sessions: make(map[string]*resource.Session)}
+}
+
+func (tx *sessionTx) Fill(conds map[string]interface{}, out interface{}) error {
+	sessions := out.(*[]*resource.Session)
+	if session, ok := tx.sessions[conds[restdb.IDField].(string)]; ok {
+		s := *session
+		*sessions = append(*sessions, &s)
+	}
+	return nil
+}
+
+func (tx *sessionTx) Update(typ restdb.ResourceType, nv map[string]interface{}, conds map[string]interface{}) (int64, error) {
+	session, ok := tx.sessions[conds[restdb.IDField].(string)]
+	if ok == false {
+		return 0, nil
+	}
+
+	session.RefreshTokenHash = nv["refresh_token_hash"].(string)
+	session.LastRefreshTime = nv["last_refresh_time"].(time.Time)
+	session.SourceIp = nv["source_ip"].(string)
+	session.UserAgent = nv["user_agent"].(string)
+	return 1, nil
+}
+
+func (tx *sessionTx) Delete(typ restdb.ResourceType, conds map[string]interface{}) (int64, error) {
+	id := conds[restdb.IDField].(string)
+	if _, ok := tx.sessions[id]; ok == false {
+		return 0, nil
+	}
+
+	delete(tx.sessions, id)
+	return 1, nil
+}
+
+func (tx *sessionTx) Exec(sql string, args ...interface{}) (int64, error) {
+	if sql != deleteUserSessionsSql {
+		return 0, fmt.Errorf("unexpected sql %s", sql)
+	}
+
+	var count int64
+	for id, session := range tx.sessions {
+		if session.Username == args[0] && id != args[1] {
+			delete(tx.sessions, id)
+			count += 1
+		}
+	}
+	return count, nil
+}
+
+func (tx *sessionTx) addSession(id, userName, secret string, expireTime time.Time) string {
+	session := &resource.Session{Username: userName, ExpireTime: expireTime, RefreshTokenHash: hashRefreshSecret(secret)}
+	session.SetID(id)
+	tx.sessions[id] = session
+	return id + refreshTokenSeparator + secret
+}
+
+func TestRefreshSessionRotation(t *testing.T) {
+	tx := newSessionTx()
+	now := time.Now()
+	expireTime := now.Add(time.Hour)
+	token := tx.addSession("s1", "admin", "secret1", expireTime)
+
+	session, newToken, err := refreshSession(token, "10.0.0.2", "curl", now, tx)
+	if err != nil {
+		t.Fatalf("refresh session failed: %s", err.Error())
+	}
+
+	if newToken == token || strings.HasPrefix(newToken, "s1"+refreshTokenSeparator) == false {
+		t.Errorf("refresh token should be rotated in the same session but get %s", newToken)
+	}
+
+	if session.ExpireTime.Equal(expireTime) == false {
+		t.Errorf("expire time of session should not be extended")
+	}
+
+	saved := tx.sessions["s1"]
+	if saved.RefreshTokenHash != hashRefreshSecret(strings.SplitN(newToken, refreshTokenSeparator, 2)[1]) ||
+		saved.SourceIp != "10.0.0.2" || saved.UserAgent != "curl" || saved.LastRefreshTime.Equal(now) == false {
+		t.Errorf("session should be updated but get %+v", saved)
+	}
+
+	if _, next, err := refreshSession(newToken, "10.0.0.2", "curl", now, tx); err != nil {
+		t.Errorf("rotated refresh token should be accepted: %s", err.Error())
+	} else if next == newToken {
+		t.Errorf("refresh token should be rotated again")
+	}
+}
+
+func TestRefreshSessionReuseRevokes(t *testing.T) {
+	tx := newSessionTx()
+	now := time.Now()
+	token := tx.addSession("s1", "admin", "secret1", now.Add(time.Hour))
+	tx.addSession("s2", "admin", "secret2", now.Add(time.Hour))
+
+	_, newToken, err := refreshSession(token, "10.0.0.2", "curl", now, tx)
+	if err != nil {
+		t.Fatalf("refresh session failed: %s", err.Error())
+	}
+
+	if _, _, err := refreshSession(token, "10.0.0.3", "curl", now, tx); err != errRefreshTokenReused {
+		t.Fatalf("reused refresh token should be refused but get %v", err)
+	}
+
+	if _, ok := tx.sessions["s1"]; ok {
+		t.Errorf("session of reused refresh token should be revoked")
+	}
+
+	if _, ok := tx.sessions["s2"]; ok == false {
+		t.Errorf("other session of user should be kept")
+	}
+
+	if _, _, err := refreshSession(newToken, "10.0.0.2", "curl", now, tx); err == nil {
+		t.Errorf("refresh token of revoked session should be refused")
+	}
+}
+
+func TestRefreshSessionInvalid(t *testing.T) {
+	tx := newSessionTx()
+	now := time.Now()
+	expired := tx.addSession("s1", "admin", "secret1", now.Add(-time.Second))
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"without separator", "s1secret1"},
+		{"unknown session", "s9" + refreshTokenSeparator + "secret1"},
+		{"expired session", expired},
+	}
+
+	for _, c := range cases {
+		if _, _, err := refreshSession(c.token, "10.0.0.2", "curl", now, tx); err == nil || err == errRefreshTokenReused {
+			t.Errorf("%s: refresh should fail without revoking but get %v", c.name, err)
+		}
+	}
+
+	if _, ok := tx.sessions["s1"]; ok == false {
+		t.Errorf("expired session is deleted by cleanup instead of refresh")
+	}
+}
+
+func TestDeleteUserSessions(t *testing.T) {
+	tx := newSessionTx()
+	expireTime := time.Now().Add(time.Hour)
+	tx.addSession("current", "admin", "secret1", expireTime)
+	tx.addSession("other", "admin", "secret2", expireTime)
+	tx.addSession("another", "user1", "secret3", expireTime)
+
+	if err := deleteUserSessions("admin", "current", tx); err != nil {
+		t.Fatalf("delete user sessions failed: %s", err.Error())
+	}
+
+	if _, ok := tx.sessions["current"]; ok == false {
+		t.Errorf("current session should be kept")
+	}
+
+	if _, ok := tx.sessions["other"]; ok {
+		t.Errorf("other session of user should be deleted")
+	}
+
+	if _, ok := tx.sessions["another"]; ok == false {
+		t.Errorf("session of other user should be kept")
+	}
+
+	if err := deleteUserSessions("admin", "", tx); err != nil {
+		t.Fatalf("delete user sessions failed: %s", err.Error())
+	}
+
+	if len(tx.sessions) != 1 {
+		t.Errorf("all sessions of user should be deleted without kept session but get %d", len(tx.sessions))
+	}
+}
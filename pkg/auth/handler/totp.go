@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	totpIssuer        = "linkingthing"
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	recoveryCodeLen   = 10
+)
+
+//gTotpSecretKey encrypts totp secret saved in db, it is loaded from key file
+//of secret_key
+var gTotpSecretKey []byte
+
+//enrollTotp generates a new secret for current user, totp isn't enabled
+//until a code of the secret is verified by activateTotp
+func (h *UserHandler) enrollTotp(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	userName, ok := ctx.Get(resource.AuthUser)
+	if !ok {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("unknown user"))
+	}
+
+	secret, err := util.GenerateTotpSecret()
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("generate totp secret failed: %s", err.Error()))
+	}
+
+	encrypted, err := util.EncryptWithNonce(gTotpSecretKey, secret)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("encrypt totp secret failed: %s", err.Error()))
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		user, err := getUserFromDB(userName.(string), tx)
+		if err != nil {
+			return err
+		}
+
+		if user.TotpEnabled {
+			return fmt.Errorf("totp is enabled, disable it before enroll again")
+		}
+
+		return updateUserToDB(user.GetID(), map[string]interface{}{"totp_secret": encrypted}, tx)
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("enroll totp failed: %s", err.Error()))
+	}
+
+	return &resource.TotpEnrollment{
+		Secret: secret,
+		Uri:    util.TotpURI(totpIssuer, userName.(string), secret),
+	}, nil
+}
+
+//activateTotp enables totp and returns recovery codes, the codes are only
+//shown this time
+func (h *UserHandler) activateTotp(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	userName, ok := ctx.Get(resource.AuthUser)
+	if !ok {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("unknown user"))
+	}
+
+	input := ctx.Resource.GetAction().Input.(*resource.TotpRequest)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = util.CreateRandomString(recoveryCodeLen)
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		user, err := getUserFromDB(userName.(string), tx)
+		if err != nil {
+			return err
+		}
+
+		if user.TotpEnabled {
+			return fmt.Errorf("totp is already enabled")
+		} else if user.TotpSecret == "" {
+			return fmt.Errorf("totp isn't enrolled")
+		}
+
+		step, err := validateTotpCode(user, input.Code)
+		if err != nil {
+			return err
+		}
+
+		return updateUserToDB(user.GetID(), map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+			"recovery_codes": hashes}, tx)
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("activate totp failed: %s", err.Error()))
+	}
+
+	updateCachedUser(userName.(string), func(user *resource.Ddiuser) {
+		user.TotpEnabled = true
+	})
+	return &resource.RecoveryCodes{Codes: codes}, nil
+}
+
+//disableTotp needs a totp or recovery code when user disables own totp,
+//admin disables totp of other users without code
+func (h *UserHandler) disableTotp(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	userName, ok := ctx.Get(resource.AuthUser)
+	if !ok {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("unknown user"))
+	}
+
+	input := ctx.Resource.GetAction().Input.(*resource.TotpRequest)
+	if input.Username == "" {
+		input.Username = userName.(string)
+	}
+
+	if userName != Admin && userName != input.Username {
+		return nil, resterror.NewAPIError(resterror.PermissionDenied,
+			fmt.Sprintf("can't disable totp of other user"))
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if userName == input.Username {
+			if err := verifyTwoFactor(input.Username, input.Code, tx); err != nil {
+				return err
+			}
+		}
+
+		return updateUserToDB(input.Username, map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+			"recovery_codes": []string{}}, tx)
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("disable totp failed: %s", err.Error()))
+	}
+
+	updateCachedUser(input.Username, func(user *resource.Ddiuser) {
+		user.TotpEnabled = false
+	})
+	input.Code = ""
+	return input, nil
+}
+
+//VerifyTwoFactor is the second step of login, a totp code can't be used
+//twice and a recovery code is removed once it is used
+func VerifyTwoFactor(userName, code string) error {
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		return verifyTwoFactor(userName, code, tx)
+	})
+}
+
+func verifyTwoFactor(userName, code string, tx restdb.Transaction) error {
+	user, err := getUserFromDB(userName, tx)
+	if err != nil {
+		return err
+	}
+
+	if user.TotpEnabled == false {
+		return fmt.Errorf("totp isn't enabled")
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == util.TotpDigits {
+		step, err := validateTotpCode(user, code)
+		if err != nil {
+			return err
+		}
+
+		return updateUserToDB(userName, map[string]interface{}{"totp_last_step": step}, tx)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, recoveryCode := range user.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(hash)) == 1 {
+			codes := append(append([]string{}, user.RecoveryCodes[:i]...), user.RecoveryCodes[i+1:]...)
+			return updateUserToDB(userName, map[string]interface{}{"recovery_codes": codes}, tx)
+		}
+	}
+
+	return ErrTwoFactorCodeIncorrect
+}
+
+func validateTotpCode(user *resource.Ddiuser, code string) (int64, error) {
+	secret, err := util.DecryptWithNonce(gTotpSecretKey, user.TotpSecret)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt totp secret failed: %s", err.Error())
+	}
+
+	step, ok := util.ValidateTotp(secret, code, time.Now(), totpSkew)
+	if ok == false || step <= user.TotpLastStep {
+		return 0, ErrTwoFactorCodeIncorrect
+	}
+
+	return step, nil
+}
+
+func hashRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
+
+func TestVerifyTwoFactorFailures(t *testing.T) {
+	user := &resource.Ddiuser{Name: "alice", TotpEnabled: true, RecoveryCodes: []string{hashRecoveryCode("recovery01")}}
+	user.SetID("alice")
+	tx := &userTx{users: map[string]*resource.Ddiuser{"alice": user}}
+
+	if err := verifyTwoFactor("alice", "recovery02", tx); err != ErrTwoFactorCodeIncorrect {
+		t.Errorf("wrong recovery code expected %v but get %v", ErrTwoFactorCodeIncorrect, err)
+	}
+
+	if err := verifyTwoFactor("bob", "recovery01", tx); err == nil || err == ErrTwoFactorCodeIncorrect {
+		t.Errorf("unknown user shouldn't be taken as wrong code but get %v", err)
+	}
+}
@@ -1,14 +1,18 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"sync"
+	"time"
 
 	restdb "github.com/zdnscloud/gorest/db"
 	resterror "github.com/zdnscloud/gorest/error"
 	restresource "github.com/zdnscloud/gorest/resource"
 
+	"github.com/trymanytimes/UpdateWeb/config"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/authorization"
 	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
 	"github.com/trymanytimes/UpdateWeb/pkg/db"
@@ -18,49 +22,117 @@ import (
 var (
 	TableUser      = restdb.ResourceDBType(&resource.Ddiuser{})
 	Admin          = "admin"
-	aesKey         = []byte("linkingthing.com")
 	AuditlogIgnore = "auditlogIgnore"
 	ActivityUsers  = sync.Map{}
+
+	//ErrUserNotFound is returned by CheckPassword when user doesn't exist or
+	//isn't a local user
+	ErrUserNotFound = errors.New("user not found")
+	//ErrPasswordIncorrect and ErrTwoFactorCodeIncorrect are the only errors
+	//counted as failed logins, others are failures of db
+	ErrPasswordIncorrect      = errors.New("user or password is incorrect")
+	ErrTwoFactorCodeIncorrect = errors.New("two factor code is incorrect")
+
+	errCurrentPasswordIncorrect = errors.New("current password is incorrect")
+	errUpdateAuthorityDenied    = errors.New("only admin can update user groups and roles")
 )
 
 type UserHandler struct{}
 
 func NewUserHandler() (*UserHandler, error) {
-	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
-		var users []*resource.Ddiuser
-		if err := tx.Fill(nil, &users); err != nil {
+	initLoginLimiter(&config.GetConfig().LoginLimit)
+	if err := checkPasswordPolicyConf(&config.GetConfig().PasswordPolicy); err != nil {
+		return nil, err
+	}
+
+	gPasswordPolicy = newPasswordPolicy(&config.GetConfig().PasswordPolicy)
+	totpSecretKey, err := util.LoadKey(config.GetConfig().SecretKey.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load secret key failed: %s", err.Error())
+	}
+
+	gTotpSecretKey = totpSecretKey
+	if err := restdb.WithTx(db.GetDB(), loadUsers); err != nil {
+		return nil, fmt.Errorf("could not insert admin and password:%s", err.Error())
+	}
+
+	return &UserHandler{}, nil
+}
+
+//loadUsers migrates and caches all users, admin is created if missing
+func loadUsers(tx restdb.Transaction) error {
+	var users []*resource.Ddiuser
+	if err := tx.Fill(nil, &users); err != nil {
+		return err
+	}
+
+	haveAdmin := false
+	for _, user := range users {
+		if err := migrateUserPassword(user, tx); err != nil {
 			return err
 		}
 
-		haveAdmin := false
-		for _, user := range users {
-			if err := reloadUserAuthority(user, tx); err != nil {
-				return err
+		if user.PasswordChangedTime.IsZero() || user.Source == "" {
+			if user.PasswordChangedTime.IsZero() {
+				user.PasswordChangedTime = time.Now()
 			}
-			ActivityUsers.Store(user.Name, user)
-			if user.Name == Admin {
-				haveAdmin = true
+			if user.Source == "" {
+				user.Source = resource.UserSourceLocal
 			}
-		}
-
-		if !haveAdmin {
-			encryptText, err := util.Encrypt(aesKey, Admin)
-			if err != nil {
-				return err
-			}
-			user := &resource.Ddiuser{Name: Admin, Password: encryptText, RoleType: resource.RoleTypeSUPER}
-			user.SetID(Admin)
-			if _, err = tx.Insert(user); err != nil {
+			if err := updateUserToDB(user.GetID(), map[string]interface{}{
+				"password_changed_time": user.PasswordChangedTime,
+				"source":                user.Source}, tx); err != nil {
 				return err
 			}
-			ActivityUsers.Store(user.Name, user)
 		}
+
+		if err := reloadUserAuthority(user, tx); err != nil {
+			return err
+		}
+		ActivityUsers.Store(user.Name, user)
+		if user.Name == Admin {
+			haveAdmin = true
+		}
+	}
+
+	if !haveAdmin {
+		hash, err := hashPassword(Admin)
+		if err != nil {
+			return err
+		}
+		user := &resource.Ddiuser{Name: Admin, Password: hash, RoleType: resource.RoleTypeSUPER,
+			MustChangePassword: true, PasswordChangedTime: time.Now(), Source: resource.UserSourceLocal}
+		user.SetID(Admin)
+		if _, err = tx.Insert(user); err != nil {
+			return err
+		}
+		ActivityUsers.Store(user.Name, user)
+	}
+	return nil
+}
+
+//migrateUserPassword replaces password encrypted by aes with bcrypt hash,
+//admin still using the default password has to change it, users from
+//external source or without password have nothing to migrate
+func migrateUserPassword(user *resource.Ddiuser, tx restdb.Transaction) error {
+	if (user.Source != "" && user.Source != resource.UserSourceLocal) ||
+		user.Password == "" || isPasswordHashed(user.Password) {
 		return nil
-	}); err != nil {
-		return nil, fmt.Errorf("could not insert admin and password:%s", err.Error())
 	}
 
-	return &UserHandler{}, nil
+	hash, password, err := migrateLegacyPassword(user.Password)
+	if err != nil {
+		return fmt.Errorf("migrate password of user %s failed: %s", user.Name, err.Error())
+	}
+
+	user.Password = hash
+	if user.Name == Admin && password == Admin {
+		user.MustChangePassword = true
+	}
+
+	return updateUserToDB(user.GetID(), map[string]interface{}{
+		"password":             user.Password,
+		"must_change_password": user.MustChangePassword}, tx)
 }
 
 func CheckPassword(userName, password string) error {
@@ -68,14 +140,17 @@ func CheckPassword(userName, password string) error {
 	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
 		if err := tx.Fill(map[string]interface{}{restdb.IDField: userName}, &ddiUsers); err != nil {
 			return err
-		} else if len(ddiUsers) != 1 {
-			return fmt.Errorf("user or password is incorrect")
+		} else if len(ddiUsers) != 1 || ddiUsers[0].Source != resource.UserSourceLocal {
+			comparePassword(string(dummyPasswordHash), password)
+			return ErrUserNotFound
 		}
 
-		if passwordDecrypt, err := util.Decrypt(aesKey, ddiUsers[0].Password); err != nil {
-			return err
-		} else if passwordDecrypt != password {
-			return fmt.Errorf("user or password is incorrect")
+		if comparePassword(ddiUsers[0].Password, password) == false {
+			return ErrPasswordIncorrect
+		}
+
+		if ddiUsers[0].MustChangePassword == false && gPasswordPolicy.isExpired(ddiUsers[0], time.Now()) {
+			return updateUserToDB(userName, map[string]interface{}{"must_change_password": true}, tx)
 		}
 
 		return nil
@@ -102,17 +177,21 @@ func GetUserInfo(userName string) (*resource.Ddiuser, error) {
 
 func (h *UserHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	user := ctx.Resource.(*resource.Ddiuser)
-	if user.Password == "" {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("password should not be empty"))
+	if err := gPasswordPolicy.validate(user.Password); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
 	}
 
-	encryptText, err := util.Encrypt(aesKey, user.Password)
+	hash, err := hashPassword(user.Password)
 	if err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("get password failed"))
 	}
-	user.Password = encryptText
+	user.Password = hash
 	user.SetID(user.Name)
 	user.RoleType = resource.RoleTypeNORMAL
+	user.MustChangePassword = false
+	user.Source = resource.UserSourceLocal
+	user.PasswordHistory = []string{}
+	user.PasswordChangedTime = time.Now()
 	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
 		if _, err := tx.Insert(user); err != nil {
 			return err
@@ -157,6 +236,7 @@ func reloadUserAuthority(user *resource.Ddiuser, tx restdb.Transaction) error {
 	}
 
 	user.RoleAuthority = authorization.CreateBaseAuthority()
+	user.TwoFactorRequired = false
 	var groupList []*resource.UserGroup
 	if err := tx.FillEx(&groupList,
 		fmt.Sprintf(`select * from gr_user_group where id in ('%s')`,
@@ -182,15 +262,24 @@ func reloadUserAuthority(user *resource.Ddiuser, tx restdb.Transaction) error {
 
 	var views []string
 	var planIds []string
+	var businessOperations []string
+	var webGroups []string
 	for _, role := range roleList {
 		views = append(views, role.Views...)
 		planIds = append(planIds, role.Plans...)
+		businessOperations = append(businessOperations, role.BusinessOperations...)
+		webGroups = append(webGroups, role.WebGroups...)
+		if role.RequireTwoFactor {
+			user.TwoFactorRequired = true
+		}
 	}
 	views = recombineSlices(views, []string{}, false)
 	authorization.CreateViewAuthority(views, user.RoleAuthority)
 
 	var plans []string
 	authorization.CreateDhcpAuthority(plans, user.RoleAuthority)
+	webGroups = recombineSlices(webGroups, []string{}, false)
+	authorization.CreateBusinessAuthority(businessOperations, webGroups, user.RoleAuthority)
 
 	return nil
 }
@@ -207,22 +296,59 @@ func (h *UserHandler) Update(ctx *restresource.Context) (restresource.Resource,
 	}
 
 	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
-		if err := updateUserToDB(ddiUser.ID, map[string]interface{}{
-			"comment":        ddiUser.Comment,
-			"user_group_ids": ddiUser.UserGroupIds,
-			"role_ids":       ddiUser.RoleIds}, tx); err != nil {
+		updated, err := updateUser(user.(string), ddiUser, tx)
+		if err != nil {
 			return err
 		}
 
-		return reloadUserAuthority(ddiUser, tx)
+		ddiUser = updated
+		return nil
 	}); err != nil {
+		if err == errUpdateAuthorityDenied {
+			return nil, resterror.NewAPIError(resterror.PermissionDenied, err.Error())
+		}
 		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("update user %s to db failed: %s", ddiUser.Name, err.Error()))
 	}
 
-	ddiUser.Password = ""
 	ActivityUsers.Store(ddiUser.Name, ddiUser)
-	return ddiUser, nil
+	copied := *ddiUser
+	copied.Password = ""
+	return &copied, nil
+}
+
+//updateUser saves comment, user groups and roles of user, user groups and
+//roles grant authority so only admin can change them
+func updateUser(caller string, ddiUser *resource.Ddiuser, tx restdb.Transaction) (*resource.Ddiuser, error) {
+	if caller != Admin {
+		saved, err := getUserFromDB(ddiUser.GetID(), tx)
+		if err != nil {
+			return nil, err
+		}
+
+		if isSameIds(saved.UserGroupIds, ddiUser.UserGroupIds) == false ||
+			isSameIds(saved.RoleIds, ddiUser.RoleIds) == false {
+			return nil, errUpdateAuthorityDenied
+		}
+	}
+
+	if err := updateUserToDB(ddiUser.GetID(), map[string]interface{}{
+		"comment":        ddiUser.Comment,
+		"user_group_ids": ddiUser.UserGroupIds,
+		"role_ids":       ddiUser.RoleIds}, tx); err != nil {
+		return nil, err
+	}
+
+	updated, err := getUserFromDB(ddiUser.GetID(), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, reloadUserAuthority(updated, tx)
+}
+
+func isSameIds(ids1, ids2 []string) bool {
+	return len(recombineSlices(ids1, ids2, true)) == 0 && len(recombineSlices(ids2, ids1, true)) == 0
 }
 
 func (h *UserHandler) Delete(ctx *restresource.Context) *resterror.APIError {
@@ -231,8 +357,15 @@ func (h *UserHandler) Delete(ctx *restresource.Context) *resterror.APIError {
 	}
 
 	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
-		_, err := tx.Delete(TableUser, map[string]interface{}{restdb.IDField: ctx.Resource.GetID()})
-		return err
+		if _, err := tx.Delete(TableUser, map[string]interface{}{restdb.IDField: ctx.Resource.GetID()}); err != nil {
+			return err
+		}
+
+		if err := deleteUserApiTokens(ctx.Resource.GetID(), tx); err != nil {
+			return err
+		}
+
+		return deleteUserSessions(ctx.Resource.GetID(), "", tx)
 	}); err != nil {
 		return resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("delete user %s from db failed: %s", ctx.Resource.GetID(), err.Error()))
@@ -274,7 +407,12 @@ func (h *UserHandler) List(ctx *restresource.Context) (interface{}, *resterror.A
 }
 
 func (h *UserHandler) Action(ctx *restresource.Context) (interface{}, *resterror.APIError) {
-	ctx.Set(AuditlogIgnore, nil)
+	switch ctx.Resource.GetAction().Name {
+	case resource.ActionUnlock, resource.ActionDisableTotp:
+	default:
+		ctx.Set(AuditlogIgnore, nil)
+	}
+
 	switch ctx.Resource.GetAction().Name {
 	case resource.ActionLogout:
 		return h.Logout(ctx)
@@ -284,18 +422,32 @@ func (h *UserHandler) Action(ctx *restresource.Context) (interface{}, *resterror
 		return h.changePassword(ctx)
 	case resource.ActionResetPassword:
 		return h.resetPassword(ctx)
+	case resource.ActionUnlock:
+		return h.unlock(ctx)
+	case resource.ActionEnrollTotp:
+		return h.enrollTotp(ctx)
+	case resource.ActionActivateTotp:
+		return h.activateTotp(ctx)
+	case resource.ActionDisableTotp:
+		return h.disableTotp(ctx)
 	default:
 		return nil, resterror.NewAPIError(resterror.InvalidAction,
 			fmt.Sprintf("action %s is unknown", ctx.Resource.GetAction().Name))
 	}
 }
 
+//Logout only revokes the session of request, other sessions of the user
+//are still valid
 func (h *UserHandler) Logout(ctx *restresource.Context) (interface{}, *resterror.APIError) {
-	user, ok := ctx.Get(resource.AuthUser)
+	sessionID, ok := ctx.Get(resource.AuthSession)
 	if !ok {
-		return nil, resterror.NewAPIError(resterror.InvalidAction, fmt.Sprintf("unknown user"))
+		return nil, resterror.NewAPIError(resterror.InvalidAction, fmt.Sprintf("unknown session"))
+	}
+
+	if err := DeleteSession(sessionID.(string)); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
 	}
-	ActivityUsers.Delete(user)
+
 	return resource.LogoutResponse{Result: true, RetMsg: ""}, nil
 }
 
@@ -314,38 +466,96 @@ func (h *UserHandler) CurrentUser(ctx *restresource.Context) (interface{}, *rest
 	return &userInfo, nil
 }
 
+//changePassword only changes password of current user, admin should reset
+//password of other users
 func (h *UserHandler) changePassword(ctx *restresource.Context) (interface{}, *resterror.APIError) {
 	user, ok := ctx.Get(resource.AuthUser)
 	if !ok {
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("unknown user"))
 	}
 
-	input := ctx.Resource.GetAction().Input.(*resource.LoginRequest)
-	if user != Admin && user != input.Username {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("bad option"))
+	input := ctx.Resource.GetAction().Input.(*resource.ChangePasswordRequest)
+	if input.Username == "" {
+		input.Username = user.(string)
 	}
 
-	if input.Password == "" {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("password should not be empty"))
+	if user != input.Username {
+		return nil, resterror.NewAPIError(resterror.PermissionDenied,
+			fmt.Sprintf("can't change password of other user, reset it instead"))
 	}
 
-	encryptText, err := util.Encrypt(aesKey, input.Password)
-	if err != nil {
-		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	if input.CurrentPassword == "" {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, fmt.Sprintf("current password should not be empty"))
+	}
+
+	sourceIp := getRequestSourceIp(ctx)
+	if _, err := CheckLoginAllowed(input.Username, sourceIp); err != nil {
+		return nil, resterror.NewAPIError(resterror.PermissionDenied, err.Error())
+	}
+
+	var keptSessionID string
+	if sessionID, ok := ctx.Get(resource.AuthSession); ok {
+		keptSessionID = sessionID.(string)
 	}
 
 	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
-		return updateUserToDB(input.Username, map[string]interface{}{
-			"password": encryptText}, tx)
-	}); err != nil {
+		ddiUser, err := getUserFromDB(input.Username, tx)
+		if err != nil {
+			return err
+		}
+
+		if comparePassword(ddiUser.Password, input.CurrentPassword) == false {
+			return errCurrentPasswordIncorrect
+		}
+
+		if err := updateUserPassword(ddiUser, input.Password, false, tx); err != nil {
+			return err
+		}
+
+		return deleteUserSessions(input.Username, keptSessionID, tx)
+	}); err == errCurrentPasswordIncorrect {
+		LoginFailed(input.Username, sourceIp)
+		return nil, resterror.NewAPIError(resterror.PermissionDenied, err.Error())
+	} else if err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("update password failed:%s", err.Error()))
 	}
 
+	LoginSucceeded(input.Username)
+	setMustChangePassword(input.Username, false)
+	input.CurrentPassword = ""
 	input.Password = ""
 	return &input, nil
 }
 
+//setMustChangePassword updates the cached user so the changed password
+//takes effect without login again
+func setMustChangePassword(userName string, mustChangePassword bool) {
+	updateCachedUser(userName, func(user *resource.Ddiuser) {
+		user.MustChangePassword = mustChangePassword
+	})
+}
+
+//getRequestSourceIp returns ip of the client, it is counted with failed
+//logins since a wrong current password is also a password guess
+func getRequestSourceIp(ctx *restresource.Context) string {
+	if host, _, err := net.SplitHostPort(ctx.Request.RemoteAddr); err == nil {
+		return host
+	}
+
+	return ctx.Request.RemoteAddr
+}
+
+//updateCachedUser updates a copy of the cached user, so requests holding the
+//old one aren't affected
+func updateCachedUser(userName string, update func(*resource.Ddiuser)) {
+	if cached, ok := ActivityUsers.Load(userName); ok {
+		user := *cached.(*resource.Ddiuser)
+		update(&user)
+		ActivityUsers.Store(userName, &user)
+	}
+}
+
 func (h *UserHandler) resetPassword(ctx *restresource.Context) (interface{}, *resterror.APIError) {
 	user, ok := ctx.Get(resource.AuthUser)
 	if !ok {
@@ -357,27 +567,95 @@ func (h *UserHandler) resetPassword(ctx *restresource.Context) (interface{}, *re
 	}
 
 	input := ctx.Resource.GetAction().Input.(*resource.LoginRequest)
-	if input.Password == "" {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("password should not be empty"))
-	}
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		ddiUser, err := getUserFromDB(input.Username, tx)
+		if err != nil {
+			return err
+		}
 
-	encryptText, err := util.Encrypt(aesKey, input.Password)
-	if err != nil {
-		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
-	}
+		if err := updateUserPassword(ddiUser, input.Password, true, tx); err != nil {
+			return err
+		}
 
-	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
-		return updateUserToDB(input.Username, map[string]interface{}{
-			"password": encryptText}, tx)
+		return deleteUserSessions(input.Username, "", tx)
 	}); err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError,
 			fmt.Sprintf("update password failed:%s", err.Error()))
 	}
 
+	setMustChangePassword(input.Username, true)
 	input.Password = ""
 	return &input, nil
 }
 
+func getUserFromDB(userName string, tx restdb.Transaction) (*resource.Ddiuser, error) {
+	var users []*resource.Ddiuser
+	if err := tx.Fill(map[string]interface{}{restdb.IDField: userName}, &users); err != nil {
+		return nil, err
+	} else if len(users) != 1 {
+		return nil, fmt.Errorf("user %s is non-exists", userName)
+	}
+
+	return users[0], nil
+}
+
+//updateUserPassword checks password by policy and saves it, the replaced
+//password is pushed to history, password reset by admin must be changed by
+//the user at next login
+func updateUserPassword(user *resource.Ddiuser, password string, mustChangePassword bool, tx restdb.Transaction) error {
+	if user.Source != resource.UserSourceLocal {
+		return fmt.Errorf("password of user %s is managed by %s", user.Name, user.Source)
+	}
+
+	if err := gPasswordPolicy.validate(password); err != nil {
+		return err
+	}
+
+	if err := gPasswordPolicy.checkHistory(user, password); err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return updateUserToDB(user.GetID(), map[string]interface{}{
+		"password":              hash,
+		"password_history":      gPasswordPolicy.pushHistory(user),
+		"password_changed_time": time.Now(),
+		"must_change_password":  mustChangePassword}, tx)
+}
+
+//unlock clears failed logins and lockout of user, lockout of source ip isn't
+//cleared and expires by itself
+func (h *UserHandler) unlock(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	user, ok := ctx.Get(resource.AuthUser)
+	if !ok {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("bad user"))
+	}
+
+	if user != Admin {
+		return nil, resterror.NewAPIError(resterror.PermissionDenied, fmt.Sprintf("permission denied"))
+	}
+
+	input := ctx.Resource.GetAction().Input.(*resource.UnlockRequest)
+	if input.Username == "" {
+		input.Username = ctx.Resource.GetID()
+	}
+
+	if input.Username == "" {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, fmt.Sprintf("username should not be empty"))
+	}
+
+	if UnlockUser(input.Username) == false {
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("user %s is not locked", input.Username))
+	}
+
+	return input, nil
+}
+
 func updateUserToDB(userId string, con map[string]interface{}, tx restdb.Transaction) error {
 	_, err := tx.Update(TableUser, con,
 		map[string]interface{}{restdb.IDField: userId})
@@ -1,6 +1,10 @@
 package handler
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
+)
 
 func TestRecombineSlices(tt *testing.T) {
 	tests := []struct {
@@ -20,3 +24,39 @@ func TestRecombineSlices(tt *testing.T) {
 		tt.Logf("expected:%+v result %+v \n", t.expect, result)
 	}
 }
+
+func TestUpdateUserAuthority(t *testing.T) {
+	loadRoleTemplate(t)
+	tests := []struct {
+		caller       string
+		userGroupIds []string
+		roleIds      []string
+		denied       bool
+	}{
+		{caller: "alice", userGroupIds: []string{"g2", "g1"}, roleIds: []string{"r1"}},
+		{caller: "alice", userGroupIds: []string{"g1", "g2", "g3"}, roleIds: []string{"r1"}, denied: true},
+		{caller: "alice", userGroupIds: []string{"g1", "g2"}, roleIds: []string{"r1", "r2"}, denied: true},
+		{caller: "alice", userGroupIds: []string{"g1", "g2"}, denied: true},
+		{caller: Admin, userGroupIds: []string{"g3"}, roleIds: []string{"r2"}},
+	}
+
+	for _, tt := range tests {
+		saved := &resource.Ddiuser{Name: "alice", RoleType: resource.RoleTypeNORMAL,
+			UserGroupIds: []string{"g1", "g2"}, RoleIds: []string{"r1"}}
+		saved.SetID("alice")
+		tx := &userTx{users: map[string]*resource.Ddiuser{"alice": saved}}
+		user := &resource.Ddiuser{Name: "alice", Comment: "updated", UserGroupIds: tt.userGroupIds, RoleIds: tt.roleIds}
+		user.SetID("alice")
+
+		_, err := updateUser(tt.caller, user, tx)
+		if tt.denied {
+			if err != errUpdateAuthorityDenied || saved.Comment != "" {
+				t.Errorf("%s update user groups %v and roles %v should be denied but get %v",
+					tt.caller, tt.userGroupIds, tt.roleIds, err)
+			}
+		} else if err != nil || saved.Comment != "updated" || isSameIds(saved.UserGroupIds, tt.userGroupIds) == false {
+			t.Errorf("%s update user groups %v and roles %v should succeed but get %v",
+				tt.caller, tt.userGroupIds, tt.roleIds, err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package resource
+
+import (
+	"time"
+
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+//ApiToken is a long lived credential of user for automation, scope is a
+//resource kind or kind:method like website:POST, * matches every kind except
+//apitoken. token is only returned when it is created
+type ApiToken struct {
+	restresource.ResourceBase `json:",inline"`
+	Name                      string    `json:"name" rest:"required=true,minLen=1,maxLen=40"`
+	Username                  string    `json:"username" rest:"description=readonly"`
+	Scopes                    []string  `json:"scopes" rest:"required=true"`
+	ExpireTime                time.Time `json:"expireTime" rest:"required=true"`
+	LastUsedTime              time.Time `json:"lastUsedTime" rest:"description=readonly"`
+	Token                     string    `json:"token,omitempty" rest:"description=readonly" db:"-"`
+	SecretHash                string    `json:"-"`
+}
+
+var AuthApiToken = "apitoken"
+
+const ApiTokenScopeAll = "*"
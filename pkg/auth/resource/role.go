@@ -12,6 +12,9 @@ type Role struct {
 	Comment                   string                   `json:"comment"`
 	Views                     []string                 `json:"views"`
 	Plans                     []string                 `json:"plans"`
+	BusinessOperations        []string                 `json:"businessOperations"`
+	WebGroups                 []string                 `json:"webGroups"`
+	RequireTwoFactor          bool                     `json:"requireTwoFactor"`
 	RoleAuthority             map[string]RoleAuthority `json:"-" db:"-"`
 }
 
@@ -26,6 +29,15 @@ const (
 	OperationsTypeACTION OperationsType = "ACTION"
 )
 
+//RequestOperation returns operation of request, action is posted but it is
+//authorized as ACTION rather than POST
+func RequestOperation(method string, isAction bool) OperationsType {
+	if isAction {
+		return OperationsTypeACTION
+	}
+	return OperationsType(method)
+}
+
 const (
 	RoleTypeSUPER  RoleType = "SUPER"
 	RoleTypeNORMAL RoleType = "NORMAL"
@@ -35,14 +47,16 @@ type RoleAuthority struct {
 	Resource   string           `json:"resource"`
 	Views      []string         `json:"views"`
 	Plans      []string         `json:"plans"`
+	WebGroups  []string         `json:"webGroups"`
 	Filter     bool             `json:"filter"`
 	Operations []OperationsType `json:"operations"`
 }
 
 type RoleAuthorityClass struct {
-	BaseAuthority []RoleAuthority `json:"baseAuthority"`
-	DnsAuthority  []RoleAuthority `json:"dnsAuthority"`
-	DhcpAuthority []RoleAuthority `json:"dhcpAuthority"`
+	BaseAuthority     []RoleAuthority `json:"baseAuthority"`
+	DnsAuthority      []RoleAuthority `json:"dnsAuthority"`
+	DhcpAuthority     []RoleAuthority `json:"dhcpAuthority"`
+	BusinessAuthority []RoleAuthority `json:"businessAuthority"`
 }
 
 type RoleTemplate struct {
@@ -0,0 +1,25 @@
+package resource
+
+import (
+	"time"
+
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+//Session is created when user logins, its id is carried by access token and
+//it is refreshed by refresh token until expire time
+type Session struct {
+	restresource.ResourceBase `json:",inline"`
+	Username                  string    `json:"username" rest:"description=readonly"`
+	SourceIp                  string    `json:"sourceIp" rest:"description=readonly"`
+	UserAgent                 string    `json:"userAgent" rest:"description=readonly"`
+	LastRefreshTime           time.Time `json:"lastRefreshTime" rest:"description=readonly"`
+	ExpireTime                time.Time `json:"expireTime" rest:"description=readonly"`
+	RefreshTokenHash          string    `json:"-"`
+}
+
+var AuthSession = "session"
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
@@ -1,6 +1,8 @@
 package resource
 
 import (
+	"time"
+
 	"github.com/zdnscloud/gorest/resource"
 	restresource "github.com/zdnscloud/gorest/resource"
 )
@@ -8,24 +10,61 @@ import (
 type Ddiuser struct {
 	restresource.ResourceBase `json:",inline"`
 	Name                      string                   `json:"username" rest:"required=true,minLen=1,maxLen=20" db:"uk"`
-	Password                  string                   `json:"password" rest:"required=true,minLen=0,maxLen=20"`
+	Password                  string                   `json:"password" rest:"required=true"`
 	Comment                   string                   `json:"comment"`
 	RoleType                  RoleType                 `json:"roleType"`
 	UserGroupIds              []string                 `json:"userGroupIDs"`
 	RoleIds                   []string                 `json:"roleIDs"`
+	MustChangePassword        bool                     `json:"mustChangePassword" rest:"description=readonly"`
+	Source                    string                   `json:"source" rest:"description=readonly"`
+	PasswordHistory           []string                 `json:"-"`
+	PasswordChangedTime       time.Time                `json:"-"`
+	TotpEnabled               bool                     `json:"totpEnabled" rest:"description=readonly"`
+	TotpSecret                string                   `json:"-"`
+	TotpLastStep              int64                    `json:"-"`
+	RecoveryCodes             []string                 `json:"-"`
+	TwoFactorRequired         bool                     `json:"-" db:"-"`
 	RoleAuthority             map[string]RoleAuthority `json:"-" db:"-"`
 }
 
 var AuthUser = "user"
 
+const (
+	UserSourceLocal = "local"
+	UserSourceLDAP  = "ldap"
+	UserSourceOIDC  = "oidc"
+)
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
+type ChangePasswordRequest struct {
+	Username        string `json:"username"`
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password"`
+}
+
 type LoginResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code              int    `json:"code"`
+	Message           string `json:"message"`
+	RefreshToken      string `json:"refreshToken,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	PreAuthToken      string `json:"preAuthToken,omitempty"`
+}
+
+//OIDCLoginRequest code and state are the query parameters which identity
+//provider redirects user with
+type OIDCLoginRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+//TotpLoginRequest code is a totp code or an unused recovery code
+type TotpLoginRequest struct {
+	PreAuthToken string `json:"preAuthToken" binding:"required"`
+	Code         string `json:"code" binding:"required"`
 }
 
 type UserRole struct {
@@ -45,9 +84,18 @@ func (u Ddiuser) RoleAuthorityToUserInfo() UserInfo {
 	}
 
 	return UserInfo{
-		UserName: u.Name,
-		UserType: string(u.RoleType),
-		MenuList: roleAuthority}
+		UserName:           u.Name,
+		UserType:           string(u.RoleType),
+		MenuList:           roleAuthority,
+		MustChangePassword: u.MustChangePassword,
+		TotpEnabled:        u.TotpEnabled,
+		MustEnrollTotp:     u.MustEnrollTotp()}
+}
+
+//MustEnrollTotp returns true if any role of user requires two factor
+//authentication but user hasn't enabled totp
+func (u Ddiuser) MustEnrollTotp() bool {
+	return u.TwoFactorRequired && u.TotpEnabled == false
 }
 
 const (
@@ -55,6 +103,10 @@ const (
 	ActionCurrentUser    = "currentUser"
 	ActionChangePassword = "changePassword"
 	ActionResetPassword  = "resetPassword"
+	ActionUnlock         = "unlock"
+	ActionEnrollTotp     = "enrollTotp"
+	ActionActivateTotp   = "activateTotp"
+	ActionDisableTotp    = "disableTotp"
 )
 
 type LogoutResponse struct {
@@ -63,9 +115,26 @@ type LogoutResponse struct {
 }
 
 type UserInfo struct {
-	UserName string          `json:"username"`
-	UserType string          `json:"userType"`
-	MenuList []RoleAuthority `json:"menuList"`
+	UserName           string          `json:"username"`
+	UserType           string          `json:"userType"`
+	MenuList           []RoleAuthority `json:"menuList"`
+	MustChangePassword bool            `json:"mustChangePassword"`
+	TotpEnabled        bool            `json:"totpEnabled"`
+	MustEnrollTotp     bool            `json:"mustEnrollTotp"`
+}
+
+type TotpEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+}
+
+type TotpRequest struct {
+	Username string `json:"username"`
+	Code     string `json:"code"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recoveryCodes"`
 }
 
 var UserAction = []resource.Action{
@@ -79,7 +148,7 @@ var UserAction = []resource.Action{
 	},
 	resource.Action{
 		Name:   ActionChangePassword,
-		Input:  &LoginRequest{},
+		Input:  &ChangePasswordRequest{},
 		Output: &LoginResponse{},
 	},
 	resource.Action{
@@ -87,4 +156,27 @@ var UserAction = []resource.Action{
 		Input:  &LoginRequest{},
 		Output: &LoginResponse{},
 	},
+	resource.Action{
+		Name:   ActionUnlock,
+		Input:  &UnlockRequest{},
+		Output: &UnlockRequest{},
+	},
+	resource.Action{
+		Name:   ActionEnrollTotp,
+		Output: &TotpEnrollment{},
+	},
+	resource.Action{
+		Name:   ActionActivateTotp,
+		Input:  &TotpRequest{},
+		Output: &RecoveryCodes{},
+	},
+	resource.Action{
+		Name:   ActionDisableTotp,
+		Input:  &TotpRequest{},
+		Output: &TotpRequest{},
+	},
+}
+
+type UnlockRequest struct {
+	Username string `json:"username"`
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/zdnscloud/gorest"
 	restresource "github.com/zdnscloud/gorest/resource"
 
+	"github.com/trymanytimes/UpdateWeb/config"
 	"github.com/trymanytimes/UpdateWeb/pkg/business/handler"
 	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
 )
@@ -19,18 +20,29 @@ var (
 )
 
 func RegisterHandler(apiServer *gorest.Server, router gin.IRoutes) error {
-	clusterHandler, err := handler.NewClusterHandler()
+	rollingHandler := handler.NewRollingHandler()
+	rollingHandler.RegisterWSHandler(router)
+	clusterHandler, err := handler.NewClusterHandler(rollingHandler)
 	if err != nil {
 		return fmt.Errorf("new cluster handler err:%s", err.Error())
 	}
 
+	//home page and domain visits share websites of groups cached
+	homePageConf := config.GetConfig().HomePage
+	homePageAggregator := handler.NewHomePageAggregator(homePageConf.CacheTTL, homePageConf.Timeout)
 	apiServer.Schemas.MustImport(&Version, resource.Cluster{}, clusterHandler)
-	apiServer.Schemas.MustImport(&Version, resource.HomePage{}, handler.NewHomePageHandler())
+	apiServer.Schemas.MustImport(&Version, resource.HomePage{}, handler.NewHomePageHandler(homePageAggregator))
 	apiServer.Schemas.MustImport(&Version, resource.Host{}, handler.NewHostHandler())
 	apiServer.Schemas.MustImport(&Version, resource.WebGroup{}, handler.NewWebGroupHandler())
 	apiServer.Schemas.MustImport(&Version, resource.Website{}, handler.NewWebsiteHandler())
 	apiServer.Schemas.MustImport(&Version, resource.Balance{}, handler.NewBalanceHandler())
 	apiServer.Schemas.MustImport(&Version, resource.VipInterval{}, handler.NewVipHandler())
+	apiServer.Schemas.MustImport(&Version, resource.Rule{}, handler.NewRuleHandler())
+	apiServer.Schemas.MustImport(&Version, resource.MiscSetting{}, handler.NewMiscSettingHandler())
+	apiServer.Schemas.MustImport(&Version, resource.VisitorStats{}, handler.NewVisitorStatsHandler())
+	apiServer.Schemas.MustImport(&Version, resource.DomainVisit{}, handler.NewDomainVisitHandler(homePageAggregator))
+	apiServer.Schemas.MustImport(&Version, resource.GroupDashboard{}, handler.NewGroupDashboardHandler())
+	apiServer.Schemas.MustImport(&Version, resource.WebsiteDashboard{}, handler.NewWebsiteDashboardHandler())
 	return nil
 }
 
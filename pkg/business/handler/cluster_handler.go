@@ -30,9 +30,11 @@ var (
 	LogPort            = int32(50000)
 )
 
-type ClusterHandler struct{}
+type ClusterHandler struct {
+	rollingHandler *RollingHandler
+}
 
-func NewClusterHandler() (*ClusterHandler, error) {
+func NewClusterHandler(rollingHandler *RollingHandler) (*ClusterHandler, error) {
 	cli := grpcclient.GetGrpcClient()
 	//query wether exists a cluster
 	clusterIDReq := pbCluster.ClusterIDReq{ClusterId: DefaultClusterID}
@@ -77,7 +79,7 @@ func NewClusterHandler() (*ClusterHandler, error) {
 			return nil, log.Errorf("grpc service exec SetCluster failed: %s", err.Error())
 		}
 	}
-	return &ClusterHandler{}, nil
+	return &ClusterHandler{rollingHandler: rollingHandler}, nil
 }
 
 func (h *ClusterHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
@@ -159,3 +161,24 @@ func (h *ClusterHandler) List(ctx *restresource.Context) (interface{}, *resterro
 	var Clusters []*resource.Cluster
 	return Clusters, nil
 }
+
+func (h *ClusterHandler) Action(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	switch ctx.Resource.GetAction().Name {
+	case resource.ActionRollingRestart, resource.ActionRollingReload:
+		return h.rolling(ctx)
+	default:
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("action %s is unknown", ctx.Resource.GetAction().Name))
+	}
+}
+
+func (h *ClusterHandler) rolling(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	action := ctx.Resource.GetAction()
+	req := action.Input.(*resource.RollingRequest)
+	task, err := h.rollingHandler.Start(ctx.Resource.GetID(), action.Name, req)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("start %s failed: %s", action.Name, err.Error()))
+	}
+
+	return task, nil
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authentification"
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+	pbWeb "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	//istatus of website is 0 when upgrade service of the website works
+	websiteStatusNormal = int32(0)
+	dashboardTimeout    = 10 * time.Second
+)
+
+type GroupDashboardHandler struct{}
+
+func NewGroupDashboardHandler() *GroupDashboardHandler {
+	return &GroupDashboardHandler{}
+}
+
+func (h *GroupDashboardHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	if err := checkWebGroupAuthority(ctx, ctx.Resource.GetID()); err != nil {
+		return nil, err
+	}
+
+	groups, err := getWebGroups(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	if len(groups) == 0 {
+		return nil, resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("webgroup %s is non-exists", ctx.Resource.GetID()))
+	}
+
+	filters := ctx.GetFilters()
+	stats := getClusterStats(groupClusterID(groups[0]), util.GetPeriodFromFilters(filters))
+	return newGroupDashboard(groups[0], filters, stats), nil
+}
+
+//List returns dashboards of groups authorized to current user, traffic and
+//cache are fetched once for each cluster
+func (h *GroupDashboardHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	groups, err := getWebGroups("")
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	filters := ctx.GetFilters()
+	period := util.GetPeriodFromFilters(filters)
+	statsOfCluster := make(map[string]*clusterStats)
+	var dashboards []*resource.GroupDashboard
+	for _, group := range groups {
+		if authentification.WebGroupFilter(ctx, group.GetStrgroupId()) == false {
+			continue
+		}
+
+		clusterID := groupClusterID(group)
+		stats, ok := statsOfCluster[clusterID]
+		if ok == false {
+			stats = getClusterStats(clusterID, period)
+			statsOfCluster[clusterID] = stats
+		}
+
+		dashboards = append(dashboards, newGroupDashboard(group, filters, stats))
+	}
+
+	return dashboards, nil
+}
+
+func newGroupDashboard(group *pbWeb.GroupInfo, filters []restresource.Filter, stats *clusterStats) *resource.GroupDashboard {
+	dashboard := &resource.GroupDashboard{
+		GroupName:      group.GetStrgroupName(),
+		ClusterID:      groupClusterID(group),
+		ClusterTraffic: stats.traffic,
+		ClusterCache:   stats.cache,
+	}
+	dashboard.SetID(group.GetStrgroupId())
+
+	rsp, err := grpcclient.GetGrpcClient().WebsiteClient.GetRaltGroupWebsite(context.Background(),
+		&pbWeb.GetRaltGroupWebsiteReq{StrgroupId: group.GetStrgroupId()})
+	if err != nil {
+		//visits are unknown too without websites of the group
+		message := fmt.Sprintf("grpc service exec GetRaltGroupWebsite failed: %s", err.Error())
+		dashboard.Errors = append(dashboard.Errors, &resource.SectionError{
+			Section: resource.DashboardSectionWebsite,
+			Message: message,
+		}, &resource.SectionError{
+			Section: resource.DashboardSectionVisit,
+			Message: message,
+		})
+		dashboard.Errors = append(dashboard.Errors, stats.errors...)
+		return dashboard
+	}
+
+	for _, website := range rsp.GetWebsite() {
+		dashboard.Health.Total += 1
+		if websiteStatus(website) == resource.WebsiteStatusNormal {
+			dashboard.Health.Normal += 1
+		} else {
+			dashboard.Health.Abnormal += 1
+		}
+	}
+
+	if visits, err := getDomainVisits(filters, websiteDomainGroups(group.GetStrgroupId(), rsp.GetWebsite()),
+		group.GetStrgroupId()); err != nil {
+		dashboard.Errors = append(dashboard.Errors, &resource.SectionError{
+			Section: resource.DashboardSectionVisit,
+			Message: err.Error(),
+		})
+	} else {
+		for _, visit := range visits {
+			dashboard.Visits += visit.Visits
+			dashboard.PreviousVisits += visit.PreviousVisits
+		}
+		dashboard.WebsiteVisits = visits
+		dashboard.ChangePercent = changePercent(dashboard.PreviousVisits, dashboard.Visits)
+	}
+
+	dashboard.Errors = append(dashboard.Errors, stats.errors...)
+	return dashboard
+}
+
+type WebsiteDashboardHandler struct{}
+
+func NewWebsiteDashboardHandler() *WebsiteDashboardHandler {
+	return &WebsiteDashboardHandler{}
+}
+
+func (h *WebsiteDashboardHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	cli := grpcclient.GetGrpcClient()
+	rsp, err := cli.WebsiteClient.GetRaltSpecWebsite(context.Background(),
+		&pbWeb.GetRaltSpecWebsiteReq{StrdomainId: ctx.Resource.GetID()})
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec GetRaltSpecWebsite failed: %s", err.Error()))
+	}
+
+	if len(rsp.GetWebsite()) == 0 {
+		return nil, resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("website %s is non-exists", ctx.Resource.GetID()))
+	}
+
+	website := rsp.GetWebsite()[0]
+	if err := checkWebGroupAuthority(ctx, website.GetStrgroupId()); err != nil {
+		return nil, err
+	}
+
+	clusterID := DefaultClusterID
+	groups, err := getWebGroups(website.GetStrgroupId())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	if len(groups) != 0 {
+		clusterID = groupClusterID(groups[0])
+	}
+
+	filters := ctx.GetFilters()
+	stats := getClusterStats(clusterID, util.GetPeriodFromFilters(filters))
+	dashboard := &resource.WebsiteDashboard{
+		GroupID:        website.GetStrgroupId(),
+		SourceDomain:   website.GetStrsrcDomain(),
+		Status:         websiteStatus(website),
+		ClusterTraffic: stats.traffic,
+		ClusterCache:   stats.cache,
+	}
+	dashboard.SetID(ctx.Resource.GetID())
+
+	if visits, err := getDomainVisits(filters, websiteDomainGroups(website.GetStrgroupId(),
+		[]*pbWeb.WebsiteReqInfo{website}), website.GetStrgroupId()); err != nil {
+		dashboard.Errors = append(dashboard.Errors, &resource.SectionError{
+			Section: resource.DashboardSectionVisit,
+			Message: err.Error(),
+		})
+	} else {
+		for _, visit := range visits {
+			if visit.Domain == website.GetStrsrcDomain() {
+				dashboard.Visits = visit.Visits
+				dashboard.PreviousVisits = visit.PreviousVisits
+				dashboard.ChangePercent = visit.ChangePercent
+				dashboard.History = visit.History
+				break
+			}
+		}
+	}
+
+	dashboard.Errors = append(dashboard.Errors, stats.errors...)
+	return dashboard, nil
+}
+
+func getWebGroups(groupID string) ([]*pbWeb.GroupInfo, error) {
+	rsp, err := grpcclient.GetGrpcClient().WebsiteClient.GetRaltGroup(context.Background(),
+		&pbWeb.GetRaltGroupReq{StrgroupId: groupID})
+	if err != nil {
+		return nil, fmt.Errorf("grpc service exec GetRaltGroup failed: %s", err.Error())
+	}
+
+	return rsp.GetGroupList(), nil
+}
+
+func groupClusterID(group *pbWeb.GroupInfo) string {
+	if group.GetStrclusterId() != "" {
+		return group.GetStrclusterId()
+	}
+
+	return DefaultClusterID
+}
+
+func websiteStatus(website *pbWeb.WebsiteReqInfo) string {
+	if website.GetIstatus() == websiteStatusNormal {
+		return resource.WebsiteStatusNormal
+	}
+
+	return resource.WebsiteStatusAbnormal
+}
+
+type clusterStats struct {
+	traffic resource.Traffic
+	cache   resource.CacheStats
+	errors  []*resource.SectionError
+}
+
+// getClusterStats sums traffic of nodes in period and averages their cache
+// hit ratio, a node failed to answer is marked in errors and skipped
+func getClusterStats(clusterID string, period int) *clusterStats {
+	ctx, cancel := context.WithTimeout(context.Background(), dashboardTimeout)
+	defer cancel()
+
+	stats := &clusterStats{}
+	cli := grpcclient.GetGrpcClient()
+	devices, err := cli.GetClusterDevices(ctx, clusterID)
+	if err != nil {
+		stats.errors = append(stats.errors,
+			&resource.SectionError{Section: resource.DashboardSectionTraffic, Message: err.Error()},
+			&resource.SectionError{Section: resource.DashboardSectionCache, Message: err.Error()})
+		return stats
+	}
+
+	begin := uint64(util.PeriodBeginTime(period).Unix())
+	var hitRatio float64
+	var hitRatioCount int
+	for _, device := range devices {
+		if rsp, err := cli.MonitorClient.ShowHomePageFlowData(ctx,
+			&pbHomePage.ShowHomePageFlowDataReq{DeviceId: device.GetHostId()}); err != nil {
+			stats.errors = append(stats.errors, &resource.SectionError{
+				Section: resource.DashboardSectionTraffic,
+				ID:      device.GetHostId(),
+				Message: fmt.Sprintf("grpc service exec ShowHomePageFlowData failed: %s", err.Error()),
+			})
+		} else {
+			addTraffic(&stats.traffic, deviceFlowsToHostFlows(rsp.GetUpDowmFlow(), begin))
+		}
+
+		if rsp, err := cli.RaltClient.GetRaltStats(ctx,
+			&pbRalt.GetRaltStatsReq{IpAddr: grpcclient.DeviceIP(device)}); err != nil {
+			stats.errors = append(stats.errors, &resource.SectionError{
+				Section: resource.DashboardSectionCache,
+				ID:      device.GetHostId(),
+				Message: fmt.Sprintf("grpc service exec GetRaltStats failed: %s", err.Error()),
+			})
+		} else {
+			stats.cache.Hits += uint64(rsp.GetCacheTotalHits())
+			hitRatio += float64(rsp.GetCacheHitRatio())
+			hitRatioCount += 1
+		}
+	}
+
+	if hitRatioCount != 0 {
+		stats.cache.HitRatio = fmt.Sprintf("%.4f", hitRatio/float64(hitRatioCount))
+	}
+
+	return stats
+}
+
+// addTraffic adds bytes between samples, a counter reset is skipped
+func addTraffic(traffic *resource.Traffic, flows []*resource.HostFlow) {
+	for i := 1; i < len(flows); i++ {
+		traffic.V4UpBytes += bytesDelta(flows[i-1].V4UpBytes, flows[i].V4UpBytes)
+		traffic.V4DownBytes += bytesDelta(flows[i-1].V4DownBytes, flows[i].V4DownBytes)
+		traffic.V6UpBytes += bytesDelta(flows[i-1].V6UpBytes, flows[i].V6UpBytes)
+		traffic.V6DownBytes += bytesDelta(flows[i-1].V6DownBytes, flows[i].V6DownBytes)
+	}
+}
+
+func bytesDelta(prevBytes, bytes uint64) uint64 {
+	if bytes < prevBytes {
+		return 0
+	}
+
+	return bytes - prevBytes
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authentification"
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	pbWeb "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	FilterGroup = "group"
+	FilterTop   = "top"
+)
+
+//DomainVisitHandler gets group of domains from websites cached by aggregator
+type DomainVisitHandler struct {
+	aggregator *HomePageAggregator
+}
+
+func NewDomainVisitHandler(aggregator *HomePageAggregator) *DomainVisitHandler {
+	return &DomainVisitHandler{
+		aggregator: aggregator,
+	}
+}
+
+func (h *DomainVisitHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	filters := ctx.GetFilters()
+	top := 0
+	if value, ok := util.GetFilterValueWithEqModifierFromFilters(FilterTop, filters); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, resterror.NewAPIError(resterror.InvalidFormat, fmt.Sprintf("invalid top %s", value))
+		}
+		top = n
+	}
+
+	groupID, _ := util.GetFilterValueWithEqModifierFromFilters(FilterGroup, filters)
+	if groupID != "" {
+		if err := checkWebGroupAuthority(ctx, groupID); err != nil {
+			return nil, err
+		}
+	}
+
+	domainGroups, err := h.aggregator.DomainGroups()
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	visits, err := getDomainVisits(filters, domainGroups, groupID)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	visits = filterAuthorizedDomainVisits(ctx, visits)
+	if top > 0 && len(visits) > top {
+		visits = visits[:top]
+	}
+
+	return visits, nil
+}
+
+func (h *DomainVisitHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	domainGroups, err := h.aggregator.DomainGroups()
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	visits, err := getDomainVisits(ctx.GetFilters(), domainGroups, "")
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	for _, visit := range visits {
+		if visit.GetID() == ctx.Resource.GetID() {
+			if err := checkWebGroupAuthority(ctx, visit.GroupID); err != nil {
+				return nil, err
+			}
+			return visit, nil
+		}
+	}
+
+	return nil, resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("domain %s has no visit data", ctx.Resource.GetID()))
+}
+
+//filterAuthorizedDomainVisits keeps visits of domains in web groups visible
+//to the user, the order is not changed
+func filterAuthorizedDomainVisits(ctx *restresource.Context, visits []*resource.DomainVisit) []*resource.DomainVisit {
+	var authorized []*resource.DomainVisit
+	for _, visit := range visits {
+		if authentification.WebGroupFilter(ctx, visit.GroupID) {
+			authorized = append(authorized, visit)
+		}
+	}
+
+	return authorized
+}
+
+//getDomainVisits returns visits of domains in period hours sorted by visits,
+//domainGroups maps source domain of websites to group id and domains are
+//limited to group when groupID is not empty
+func getDomainVisits(filters []restresource.Filter, domainGroups map[string]string, groupID string) ([]*resource.DomainVisit, error) {
+	period := util.GetPeriodFromFilters(filters)
+	now := time.Now()
+	begin := now.Add(-time.Duration(period) * time.Hour)
+	previousBegin := begin.Add(-time.Duration(period) * time.Hour)
+	var domainVisitors []*pbHomePage.DomainVisitor
+	if resolution, ok := getResolutionFromFilters(filters); ok {
+		var err error
+		if domainVisitors, err = getDomainVisitorsFromRollups(resolution, previousBegin, now); err != nil {
+			return nil, err
+		}
+	} else {
+		cli := grpcclient.GetGrpcClient()
+		resp, err := cli.MonitorClient.ShowDomainVisitorData(context.Background(),
+			&pbHomePage.ShowDomainVisitorDataReq{ClusterId: DefaultClusterID})
+		if err != nil {
+			return nil, fmt.Errorf("grpc service exec ShowDomainVisitorData failed: %s", err.Error())
+		}
+		domainVisitors = resp.GetDomainVisitNum()
+	}
+
+	return domainVisitorsToVisits(domainVisitors, domainGroups, groupID, previousBegin, begin, now), nil
+}
+
+//domainVisitorsToVisits counts samples in [begin, now] as visits and samples
+//in [previousBegin, begin) as previous visits, the others are ignored
+func domainVisitorsToVisits(domainVisitors []*pbHomePage.DomainVisitor, domainGroups map[string]string, groupID string,
+	previousBegin, begin, now time.Time) []*resource.DomainVisit {
+	var visits []*resource.DomainVisit
+	for _, domainVisitor := range domainVisitors {
+		domain := domainVisitor.GetMemberDomain()
+		group := domainGroups[domain]
+		if groupID != "" && group != groupID {
+			continue
+		}
+
+		visit := &resource.DomainVisit{Domain: domain, GroupID: group}
+		visit.SetID(domain)
+		for _, num := range domainVisitor.GetVisitHostNumber() {
+			timestamp := time.Unix(int64(num.GetTimestamp()), 0)
+			switch {
+			case timestamp.Before(previousBegin) || timestamp.After(now):
+			case timestamp.Before(begin):
+				visit.PreviousVisits += num.GetVisitDomainNum()
+			default:
+				visit.Visits += num.GetVisitDomainNum()
+				visit.History = append(visit.History, &resource.DomainVisitSample{
+					Timestamp: restresource.ISOTime(timestamp),
+					Visits:    num.GetVisitDomainNum(),
+				})
+			}
+		}
+
+		sort.Slice(visit.History, func(i, j int) bool {
+			return time.Time(visit.History[i].Timestamp).Before(time.Time(visit.History[j].Timestamp))
+		})
+		visit.ChangePercent = changePercent(visit.PreviousVisits, visit.Visits)
+		visits = append(visits, visit)
+	}
+
+	sort.SliceStable(visits, func(i, j int) bool {
+		return visits[i].Visits > visits[j].Visits
+	})
+	return visits
+}
+
+//websiteDomainGroups maps source domain of websites to group id
+func websiteDomainGroups(groupID string, websites []*pbWeb.WebsiteReqInfo) map[string]string {
+	domainGroups := make(map[string]string, len(websites))
+	for _, website := range websites {
+		domainGroups[website.GetStrsrcDomain()] = groupID
+	}
+
+	return domainGroups
+}
+
+//changePercent is empty when there is no visit in previous period
+func changePercent(previous, current uint64) string {
+	if previous == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%.2f", (float64(current)-float64(previous))*100/float64(previous))
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+)
+
+func TestChangePercent(t *testing.T) {
+	tests := []struct {
+		previous uint64
+		current  uint64
+		expect   string
+	}{
+		{0, 0, ""},
+		{0, 100, ""},
+		{100, 0, "-100.00"},
+		{100, 150, "50.00"},
+		{3, 4, "33.33"},
+	}
+
+	for _, tt := range tests {
+		if percent := changePercent(tt.previous, tt.current); percent != tt.expect {
+			t.Errorf("change from %d to %d expect %q but get %q", tt.previous, tt.current, tt.expect, percent)
+		}
+	}
+}
+
+func TestDomainVisitorsToVisits(t *testing.T) {
+	now := time.Date(2020, 11, 4, 12, 0, 0, 0, time.Local)
+	begin := now.Add(-time.Hour)
+	previousBegin := begin.Add(-time.Hour)
+	sample := func(timestamp time.Time, visits uint64) *pbHomePage.VisitDomainNum {
+		return &pbHomePage.VisitDomainNum{Timestamp: uint64(timestamp.Unix()), VisitDomainNum: visits}
+	}
+	domainVisitors := []*pbHomePage.DomainVisitor{
+		&pbHomePage.DomainVisitor{
+			MemberDomain: "a.com",
+			VisitHostNumber: []*pbHomePage.VisitDomainNum{
+				sample(now.Add(-10*time.Minute), 20),
+				sample(now.Add(-50*time.Minute), 10),
+				sample(begin.Add(-time.Minute), 15),
+				sample(previousBegin.Add(-time.Minute), 1000),
+				sample(now.Add(time.Minute), 1000),
+			},
+		},
+		&pbHomePage.DomainVisitor{
+			MemberDomain: "b.com",
+			VisitHostNumber: []*pbHomePage.VisitDomainNum{
+				sample(now, 40),
+			},
+		},
+		&pbHomePage.DomainVisitor{MemberDomain: "c.com"},
+	}
+	domainGroups := map[string]string{"a.com": "g1", "b.com": "g2"}
+
+	tests := []struct {
+		name    string
+		groupID string
+		domains []string
+	}{
+		{"all groups sorted by visits", "", []string{"b.com", "a.com", "c.com"}},
+		{"group filter", "g1", []string{"a.com"}},
+		{"unknown group", "g3", nil},
+	}
+
+	for _, tt := range tests {
+		visits := domainVisitorsToVisits(domainVisitors, domainGroups, tt.groupID, previousBegin, begin, now)
+		if len(visits) != len(tt.domains) {
+			t.Errorf("%s: expect %d visits but get %d", tt.name, len(tt.domains), len(visits))
+			continue
+		}
+
+		for i, domain := range tt.domains {
+			if visits[i].Domain != domain || visits[i].GroupID != domainGroups[domain] {
+				t.Errorf("%s: expect %s of group %s at %d but get %s of group %s", tt.name,
+					domain, domainGroups[domain], i, visits[i].Domain, visits[i].GroupID)
+			}
+		}
+	}
+
+	visits := domainVisitorsToVisits(domainVisitors, domainGroups, "g1", previousBegin, begin, now)
+	visit := visits[0]
+	if visit.Visits != 30 || visit.PreviousVisits != 15 || visit.ChangePercent != "100.00" {
+		t.Errorf("a.com expect 30 visits and 15 previous visits but get %d %d %s",
+			visit.Visits, visit.PreviousVisits, visit.ChangePercent)
+	}
+	if len(visit.History) != 2 || time.Time(visit.History[0].Timestamp).After(time.Time(visit.History[1].Timestamp)) {
+		t.Errorf("history of a.com should have 2 samples in time order")
+	}
+
+	visits = domainVisitorsToVisits(domainVisitors, domainGroups, "", previousBegin, begin, now)
+	if empty := visits[2]; empty.Visits != 0 || empty.ChangePercent != "" || len(empty.History) != 0 {
+		t.Errorf("domain without samples should have no visits but get %d %q", empty.Visits, empty.ChangePercent)
+	}
+
+	if visits := domainVisitorsToVisits(nil, domainGroups, "", previousBegin, begin, now); len(visits) != 0 {
+		t.Errorf("no domain visitors should return no visits but get %d", len(visits))
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	pbWeb "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
+)
+
+const (
+	defaultHomePageCacheTTL = 10
+	defaultHomePageTimeout  = 5
+	//at most groupWebsitesConcurrency GetRaltGroupWebsite calls are in flight
+	groupWebsitesConcurrency = 8
+)
+
+//HomePageAggregator composes HomePage from backend concurrently and caches
+//it for ttl, requests arrived while composing wait for the same result. The
+//websites of groups loaded are cached too for domain visits
+type HomePageAggregator struct {
+	ttl        time.Duration
+	timeout    time.Duration
+	lock       sync.Mutex
+	snapshot   *homePageSnapshot
+	expireTime time.Time
+	loading    chan struct{}
+	getClient  func() *grpcclient.GrpcClient
+}
+
+func NewHomePageAggregator(ttl, timeout uint32) *HomePageAggregator {
+	if ttl == 0 {
+		ttl = defaultHomePageCacheTTL
+	}
+
+	if timeout == 0 {
+		timeout = defaultHomePageTimeout
+	}
+
+	return &HomePageAggregator{
+		ttl:       time.Duration(ttl) * time.Second,
+		timeout:   time.Duration(timeout) * time.Second,
+		getClient: grpcclient.GetGrpcClient,
+	}
+}
+
+//Get returns a copy of the cached HomePage since gorest sets links on the
+//resource returned
+func (a *HomePageAggregator) Get() *resource.HomePage {
+	homePage := *a.get().homePage
+	return &homePage
+}
+
+//DomainGroups maps source domain of websites to its group id, the map is
+//shared by callers and mustn't be modified
+func (a *HomePageAggregator) DomainGroups() (map[string]string, error) {
+	snapshot := a.get()
+	return snapshot.domainGroups, snapshot.domainGroupsErr
+}
+
+//homePageSnapshot has domainGroupsErr set if any group failed to load since
+//domains of the group are unknown then
+type homePageSnapshot struct {
+	homePage        *resource.HomePage
+	domainGroups    map[string]string
+	domainGroupsErr error
+}
+
+func (a *HomePageAggregator) get() *homePageSnapshot {
+	a.lock.Lock()
+	if a.snapshot != nil && time.Now().Before(a.expireTime) {
+		snapshot := a.snapshot
+		a.lock.Unlock()
+		return snapshot
+	}
+
+	if loading := a.loading; loading != nil {
+		a.lock.Unlock()
+		<-loading
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		return a.snapshot
+	}
+
+	loading := make(chan struct{})
+	a.loading = loading
+	a.lock.Unlock()
+
+	snapshot := a.aggregate()
+	a.lock.Lock()
+	a.snapshot = snapshot
+	a.expireTime = time.Now().Add(a.ttl)
+	a.loading = nil
+	a.lock.Unlock()
+	close(loading)
+	return snapshot
+}
+
+type groupWebsites struct {
+	groupID  string
+	websites []*pbWeb.WebsiteReqInfo
+	err      error
+}
+
+func (a *HomePageAggregator) aggregate() *homePageSnapshot {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	cli := a.getClient()
+	var wg sync.WaitGroup
+	var summary *pbHomePage.ShowHomePageDataRsp
+	var summaryErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		summary, summaryErr = cli.MonitorClient.ShowHomePageData(ctx,
+			&pbHomePage.ShowHomePageDataReq{ClusterId: DefaultClusterID})
+	}()
+
+	var domainVisitors *pbHomePage.ShowDomainVisitorDataRsp
+	var domainVisitorsErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		domainVisitors, domainVisitorsErr = cli.MonitorClient.ShowDomainVisitorData(ctx,
+			&pbHomePage.ShowDomainVisitorDataReq{ClusterId: DefaultClusterID})
+	}()
+
+	var groups []*groupWebsites
+	var groupsErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		groups, groupsErr = getGroupWebsites(ctx, cli)
+	}()
+	wg.Wait()
+
+	homePage := &resource.HomePage{}
+	homePage.SetID(MonitorID)
+	if summaryErr != nil {
+		homePage.Errors = append(homePage.Errors, &resource.SectionError{
+			Section: resource.HomePageSectionSummary,
+			Message: fmt.Sprintf("grpc service exec ShowHomePageData failed: %s", summaryErr.Error()),
+		})
+	} else {
+		setHomePageSummary(homePage, summary)
+	}
+
+	domainVisits := make(map[string]uint64)
+	if domainVisitorsErr != nil {
+		homePage.Errors = append(homePage.Errors, &resource.SectionError{
+			Section: resource.HomePageSectionDomainVisit,
+			Message: fmt.Sprintf("grpc service exec ShowDomainVisitorData failed: %s", domainVisitorsErr.Error()),
+		})
+	} else {
+		for _, v := range domainVisitors.GetDomainVisitNum() {
+			if nums := v.GetVisitHostNumber(); len(nums) != 0 {
+				domainVisits[v.GetMemberDomain()] = nums[len(nums)-1].GetVisitDomainNum()
+			}
+		}
+	}
+
+	snapshot := &homePageSnapshot{homePage: homePage, domainGroups: make(map[string]string)}
+	if groupsErr != nil {
+		homePage.Errors = append(homePage.Errors, &resource.SectionError{
+			Section: resource.HomePageSectionWebGroup,
+			Message: groupsErr.Error(),
+		})
+		snapshot.domainGroupsErr = groupsErr
+	}
+
+	for _, group := range groups {
+		if group.err != nil {
+			homePage.Errors = append(homePage.Errors, &resource.SectionError{
+				Section: resource.HomePageSectionWebGroup,
+				ID:      group.groupID,
+				Message: group.err.Error(),
+			})
+			if snapshot.domainGroupsErr == nil {
+				snapshot.domainGroupsErr = fmt.Errorf("load websites of group %s failed: %s",
+					group.groupID, group.err.Error())
+			}
+			continue
+		}
+
+		var groupVisit resource.GroupVisit
+		for _, website := range group.websites {
+			snapshot.domainGroups[website.GetStrsrcDomain()] = group.groupID
+			if count, ok := domainVisits[website.GetStrsrcDomain()]; ok {
+				groupVisit.Count += count
+				groupVisit.WebsiteVisits = append(groupVisit.WebsiteVisits, &resource.WebsiteVisit{
+					Domain: website.GetStrsrcDomain(),
+					Count:  count,
+				})
+			}
+		}
+		homePage.GroupVisit = append(homePage.GroupVisit, &groupVisit)
+	}
+
+	if snapshot.domainGroupsErr != nil {
+		snapshot.domainGroups = nil
+	}
+	return snapshot
+}
+
+func setHomePageSummary(homePage *resource.HomePage, resp *pbHomePage.ShowHomePageDataRsp) {
+	homePage.NormalDomains = resp.GetDomainIsNormal()
+	homePage.AbnormalDomains = resp.GetDomainIsAbnormal()
+	homePage.DomainsCount = resp.GetDomainIsNormal() + resp.GetDomainIsAbnormal()
+	homePage.NormalIPv6Address = resp.GetNormalIpv6IpaddrNumber()
+	homePage.AbnormalIPv6Address = resp.GetAbnormalIpv6IpaddrNumber()
+	homePage.IPv6AddressCount = resp.GetNormalIpv6IpaddrNumber() + resp.GetAbnormalIpv6IpaddrNumber()
+	homePage.NormalNode = resp.GetNormalNodeNumber()
+	homePage.AbnormalNode = resp.GetAbnormalNodeNumber()
+	homePage.NodesCount = resp.GetNormalNodeNumber() + resp.GetAbnormalNodeNumber()
+}
+
+//getGroupWebsites keeps the order of groups, a group failed to load has err
+//set and doesn't fail the others
+func getGroupWebsites(ctx context.Context, cli *grpcclient.GrpcClient) ([]*groupWebsites, error) {
+	webGroups, err := cli.WebsiteClient.GetRaltGroup(ctx, &pbWeb.GetRaltGroupReq{})
+	if err != nil {
+		return nil, fmt.Errorf("grpc service exec GetRaltGroup failed: %s", err.Error())
+	}
+
+	groups := make([]*groupWebsites, len(webGroups.GetGroupList()))
+	sem := make(chan struct{}, groupWebsitesConcurrency)
+	var wg sync.WaitGroup
+	for i, group := range webGroups.GetGroupList() {
+		groups[i] = &groupWebsites{groupID: group.GetStrgroupId()}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(group *groupWebsites) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			rsp, err := cli.WebsiteClient.GetRaltGroupWebsite(ctx, &pbWeb.GetRaltGroupWebsiteReq{StrgroupId: group.groupID})
+			if err != nil {
+				group.err = fmt.Errorf("grpc service exec GetRaltGroupWebsite failed: %s", err.Error())
+				return
+			}
+			group.websites = rsp.GetWebsite()
+		}(groups[i])
+	}
+	wg.Wait()
+
+	return groups, nil
+}
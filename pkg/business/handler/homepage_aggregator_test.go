@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	pbWeb "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
+)
+
+type fakeMonitorClient struct {
+	pbHomePage.AteStatsHomePageClient
+	summaryCalls int32
+	summaryErr   error
+	//summary call blocks until release is closed if it isn't nil
+	release        chan struct{}
+	domainVisits   map[string]uint64
+	domainVisitErr error
+}
+
+func (c *fakeMonitorClient) ShowHomePageData(ctx context.Context, in *pbHomePage.ShowHomePageDataReq, opts ...grpc.CallOption) (*pbHomePage.ShowHomePageDataRsp, error) {
+	atomic.AddInt32(&c.summaryCalls, 1)
+	if c.release != nil {
+		<-c.release
+	}
+	if c.summaryErr != nil {
+		return nil, c.summaryErr
+	}
+	return &pbHomePage.ShowHomePageDataRsp{DomainIsNormal: 2, DomainIsAbnormal: 1, NormalNodeNumber: 3}, nil
+}
+
+func (c *fakeMonitorClient) ShowDomainVisitorData(ctx context.Context, in *pbHomePage.ShowDomainVisitorDataReq, opts ...grpc.CallOption) (*pbHomePage.ShowDomainVisitorDataRsp, error) {
+	if c.domainVisitErr != nil {
+		return nil, c.domainVisitErr
+	}
+	var rsp pbHomePage.ShowDomainVisitorDataRsp
+	for domain, count := range c.domainVisits {
+		rsp.DomainVisitNum = append(rsp.DomainVisitNum, &pbHomePage.DomainVisitor{
+			MemberDomain: domain,
+			VisitHostNumber: []*pbHomePage.VisitDomainNum{
+				&pbHomePage.VisitDomainNum{VisitDomainNum: 1},
+				&pbHomePage.VisitDomainNum{VisitDomainNum: count},
+			},
+		})
+	}
+	return &rsp, nil
+}
+
+type fakeWebsiteClient struct {
+	pbWeb.RaltConfServClient
+	groups       map[string][]string
+	groupIDs     []string
+	groupErrs    map[string]error
+	delay        time.Duration
+	websiteCalls int32
+	inFlight     int32
+	maxFlight    int32
+}
+
+func (c *fakeWebsiteClient) GetRaltGroup(ctx context.Context, in *pbWeb.GetRaltGroupReq, opts ...grpc.CallOption) (*pbWeb.GetRaltGroupRsp, error) {
+	var rsp pbWeb.GetRaltGroupRsp
+	for _, id := range c.groupIDs {
+		rsp.GroupList = append(rsp.GroupList, &pbWeb.GroupInfo{StrgroupId: id})
+	}
+	return &rsp, nil
+}
+
+func (c *fakeWebsiteClient) GetRaltGroupWebsite(ctx context.Context, in *pbWeb.GetRaltGroupWebsiteReq, opts ...grpc.CallOption) (*pbWeb.GetRaltGroupWebsiteRsp, error) {
+	atomic.AddInt32(&c.websiteCalls, 1)
+	inFlight := atomic.AddInt32(&c.inFlight, 1)
+	defer atomic.AddInt32(&c.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&c.maxFlight)
+		if inFlight <= max || atomic.CompareAndSwapInt32(&c.maxFlight, max, inFlight) {
+			break
+		}
+	}
+	time.Sleep(c.delay)
+
+	if err := c.groupErrs[in.GetStrgroupId()]; err != nil {
+		return nil, err
+	}
+	var rsp pbWeb.GetRaltGroupWebsiteRsp
+	for _, domain := range c.groups[in.GetStrgroupId()] {
+		rsp.Website = append(rsp.Website, &pbWeb.WebsiteReqInfo{StrgroupId: in.GetStrgroupId(), StrsrcDomain: domain})
+	}
+	return &rsp, nil
+}
+
+func newFakeAggregator(ttl time.Duration, monitor *fakeMonitorClient, website *fakeWebsiteClient) *HomePageAggregator {
+	a := NewHomePageAggregator(0, 0)
+	a.ttl = ttl
+	a.getClient = func() *grpcclient.GrpcClient {
+		return &grpcclient.GrpcClient{MonitorClient: monitor, WebsiteClient: website}
+	}
+	return a
+}
+
+func TestHomePageCacheTTL(t *testing.T) {
+	monitor := &fakeMonitorClient{}
+	a := newFakeAggregator(50*time.Millisecond, monitor, &fakeWebsiteClient{})
+
+	first := a.Get()
+	second := a.Get()
+	if calls := atomic.LoadInt32(&monitor.summaryCalls); calls != 1 {
+		t.Fatalf("home page should be cached within ttl but backend called %d times", calls)
+	}
+	if first == second {
+		t.Errorf("Get should return a copy of the cached home page")
+	}
+	if first.DomainsCount != 3 || first.NodesCount != 3 {
+		t.Errorf("unexpected summary %d domains %d nodes", first.DomainsCount, first.NodesCount)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	a.Get()
+	if calls := atomic.LoadInt32(&monitor.summaryCalls); calls != 2 {
+		t.Errorf("home page should be reloaded after ttl but backend called %d times", calls)
+	}
+}
+
+func TestHomePageSingleFlight(t *testing.T) {
+	monitor := &fakeMonitorClient{release: make(chan struct{})}
+	a := newFakeAggregator(time.Minute, monitor, &fakeWebsiteClient{})
+
+	const callers = 10
+	results := make([]*homePageSnapshot, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = a.get()
+		}(i)
+	}
+
+	for atomic.LoadInt32(&monitor.summaryCalls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	//give the other callers time to find the loading in progress
+	time.Sleep(20 * time.Millisecond)
+	close(monitor.release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&monitor.summaryCalls); calls != 1 {
+		t.Fatalf("concurrent get should aggregate once but backend called %d times", calls)
+	}
+	for i, result := range results {
+		if result != results[0] {
+			t.Errorf("caller %d get a different home page", i)
+		}
+	}
+}
+
+func TestHomePageSectionErrors(t *testing.T) {
+	monitor := &fakeMonitorClient{
+		summaryErr:   errors.New("summary unavailable"),
+		domainVisits: map[string]uint64{"a.com": 10, "b.com": 5, "c.com": 7},
+	}
+	website := &fakeWebsiteClient{
+		groupIDs:  []string{"g1", "g2", "g3"},
+		groups:    map[string][]string{"g1": []string{"a.com", "b.com", "x.com"}, "g3": []string{"c.com"}},
+		groupErrs: map[string]error{"g2": errors.New("group unavailable")},
+	}
+	homePage := newFakeAggregator(time.Minute, monitor, website).Get()
+
+	if len(homePage.Errors) != 2 {
+		t.Fatalf("expect summary and one group error but get %d errors", len(homePage.Errors))
+	}
+	if homePage.Errors[0].Section != resource.HomePageSectionSummary || homePage.DomainsCount != 0 {
+		t.Errorf("summary failure should be reported and leave summary empty")
+	}
+	if err := homePage.Errors[1]; err.Section != resource.HomePageSectionWebGroup || err.ID != "g2" {
+		t.Errorf("group failure should be reported with group id but get %s %s", err.Section, err.ID)
+	}
+
+	if len(homePage.GroupVisit) != 2 {
+		t.Fatalf("groups loaded should still be returned but get %d", len(homePage.GroupVisit))
+	}
+	if visit := homePage.GroupVisit[0]; visit.Count != 15 || len(visit.WebsiteVisits) != 2 {
+		t.Errorf("group g1 should count the latest visits of a.com and b.com but get %d of %d websites",
+			visit.Count, len(visit.WebsiteVisits))
+	}
+	if visit := homePage.GroupVisit[1]; visit.Count != 7 {
+		t.Errorf("group g3 should count 7 visits but get %d", visit.Count)
+	}
+
+	if _, err := newFakeAggregator(time.Minute, monitor, website).DomainGroups(); err == nil {
+		t.Errorf("domain groups should fail when a group failed to load")
+	}
+
+	monitor = &fakeMonitorClient{domainVisitErr: errors.New("visits unavailable")}
+	homePage = newFakeAggregator(time.Minute, monitor, website).Get()
+	if len(homePage.Errors) != 2 || homePage.Errors[0].Section != resource.HomePageSectionDomainVisit {
+		t.Errorf("domain visit failure should be reported")
+	}
+	if homePage.DomainsCount != 3 {
+		t.Errorf("summary should be filled when only domain visits failed")
+	}
+}
+
+func TestHomePageDomainGroups(t *testing.T) {
+	website := &fakeWebsiteClient{
+		groupIDs: []string{"g1", "g2"},
+		groups:   map[string][]string{"g1": []string{"a.com", "b.com"}, "g2": []string{"c.com"}},
+	}
+	a := newFakeAggregator(time.Minute, &fakeMonitorClient{}, website)
+	domainGroups, err := a.DomainGroups()
+	if err != nil {
+		t.Fatalf("get domain groups failed: %s", err.Error())
+	}
+
+	expect := map[string]string{"a.com": "g1", "b.com": "g1", "c.com": "g2"}
+	if len(domainGroups) != len(expect) {
+		t.Fatalf("expect %d domains but get %d", len(expect), len(domainGroups))
+	}
+	for domain, group := range expect {
+		if domainGroups[domain] != group {
+			t.Errorf("domain %s should be in group %s but get %s", domain, group, domainGroups[domain])
+		}
+	}
+
+	a.Get()
+	a.DomainGroups()
+	if calls := atomic.LoadInt32(&website.websiteCalls); calls != 2 {
+		t.Errorf("domain groups and home page should share websites cached but get %d calls", calls)
+	}
+}
+
+func TestGroupWebsitesBounded(t *testing.T) {
+	website := &fakeWebsiteClient{delay: 5 * time.Millisecond}
+	for i := 0; i < groupWebsitesConcurrency*3; i++ {
+		website.groupIDs = append(website.groupIDs, fmt.Sprintf("g%d", i))
+	}
+
+	groups, err := getGroupWebsites(context.Background(), &grpcclient.GrpcClient{WebsiteClient: website})
+	if err != nil {
+		t.Fatalf("get group websites failed: %s", err.Error())
+	}
+	if len(groups) != len(website.groupIDs) {
+		t.Fatalf("expect %d groups but get %d", len(website.groupIDs), len(groups))
+	}
+	for i, group := range groups {
+		if group.groupID != website.groupIDs[i] {
+			t.Errorf("group order should be kept, expect %s but get %s", website.groupIDs[i], group.groupID)
+		}
+	}
+	if max := atomic.LoadInt32(&website.maxFlight); max > groupWebsitesConcurrency {
+		t.Errorf("at most %d concurrent calls expected but get %d", groupWebsitesConcurrency, max)
+	}
+}
@@ -3,6 +3,8 @@ package handler
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	resterror "github.com/zdnscloud/gorest/error"
 	restresource "github.com/zdnscloud/gorest/resource"
@@ -11,6 +13,7 @@ import (
 	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
 	pbHost "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
 	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
 )
 
 type HostHandler struct{}
@@ -21,43 +24,116 @@ func NewHostHandler() *HostHandler {
 
 func (h *HostHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	device := ctx.Resource.(*resource.Host)
-	cli := grpcclient.GetGrpcClient()
-	//query wether exists a cluster
-	req := pbHost.ShowHomePageFlowDataReq{DeviceId: device.GetID()}
-	resp, err := cli.MonitorClient.ShowHomePageFlowData(context.Background(), &req)
-	if err != nil {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec ShowHomePageFlowData failed: %s", err.Error()))
+	if err := setHostFlows(device, ctx.GetFilters()); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
 	}
-	device.V4UpFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV4UpBytes
-	device.V4DownFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV4DownBytes
-	device.V6UpFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV6UpBytes
-	device.V6DownFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV6DownBytes
-	device.TimeStamp = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].Timestamp
 
 	return device, nil
 }
+
 func (h *HostHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
 	cli := grpcclient.GetGrpcClient()
 	clusterIDReq := pbCluster.ClusterIDReq{ClusterId: DefaultClusterID}
 	defaultCluster, err := cli.ClusterClient.QryOneCluster(context.Background(), &clusterIDReq)
 	if err != nil {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec SetCluster failed: %s", err.Error()))
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec QryOneCluster failed: %s", err.Error()))
 	}
+
+	filters := ctx.GetFilters()
 	var devices []*resource.Host
-	for _, v := range defaultCluster.SocsInfo.GetNodeHost() {
-		req := pbHost.ShowHomePageFlowDataReq{DeviceId: v.GetHostId()}
-		resp, err := cli.MonitorClient.ShowHomePageFlowData(context.Background(), &req)
-		if err != nil {
-			return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec ShowHomePageFlowData failed: %s", err.Error()))
-		}
-		var device resource.Host
-		device.V4UpFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV4UpBytes
-		device.V4DownFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV4DownBytes
-		device.V6UpFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV6UpBytes
-		device.V6DownFlow = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].CurrentDeviceV6DownBytes
-		device.TimeStamp = resp.GetUpDowmFlow()[len(resp.UpDowmFlow)-1].Timestamp
-		devices = append(devices, &device)
+	for _, v := range defaultCluster.GetSocsInfo().GetNodeHost() {
+		device := &resource.Host{}
 		device.SetID(v.GetHostId())
+		if err := setHostFlows(device, filters); err != nil {
+			return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+		}
+		devices = append(devices, device)
 	}
+
 	return devices, nil
 }
+
+//setHostFlows reads flows from rollups when resolution is set, bytes of
+//every flow is the bytes transferred in the bucket then
+func setHostFlows(device *resource.Host, filters []restresource.Filter) error {
+	period := util.GetPeriodFromFilters(filters)
+	if resolution, ok := getResolutionFromFilters(filters); ok {
+		flows, err := getHostFlowsFromRollups(resolution, device.GetID(), util.PeriodBeginTime(period), time.Now())
+		if err != nil {
+			return err
+		}
+		device.Flows = flows
+		return nil
+	}
+
+	cli := grpcclient.GetGrpcClient()
+	req := pbHost.ShowHomePageFlowDataReq{DeviceId: device.GetID()}
+	resp, err := cli.MonitorClient.ShowHomePageFlowData(context.Background(), &req)
+	if err != nil {
+		return fmt.Errorf("grpc service exec ShowHomePageFlowData failed: %s", err.Error())
+	}
+
+	device.Flows = deviceFlowsToHostFlows(resp.GetUpDowmFlow(), uint64(util.PeriodBeginTime(period).Unix()))
+	if len(device.Flows) == 0 {
+		return nil
+	}
+
+	last := resp.GetUpDowmFlow()[len(resp.GetUpDowmFlow())-1]
+	device.V4UpFlow = last.GetCurrentDeviceV4UpBytes()
+	device.V4DownFlow = last.GetCurrentDeviceV4DownBytes()
+	device.V6UpFlow = last.GetCurrentDeviceV6UpBytes()
+	device.V6DownFlow = last.GetCurrentDeviceV6DownBytes()
+	device.Connections = last.GetCurrentDeviceConnections()
+	device.TimeStamp = last.GetTimestamp()
+	return nil
+}
+
+//deviceFlowsToHostFlows keeps samples since begin, byte counters are
+//cumulative so bps is computed against the previous sample
+func deviceFlowsToHostFlows(flows []*pbHost.DeviceFlowData, begin uint64) []*resource.HostFlow {
+	sort.SliceStable(flows, func(i, j int) bool {
+		return flows[i].GetTimestamp() < flows[j].GetTimestamp()
+	})
+
+	hostFlows := make([]*resource.HostFlow, 0, len(flows))
+	var prev *pbHost.DeviceFlowData
+	for _, flow := range flows {
+		if flow == nil {
+			continue
+		}
+
+		if flow.GetTimestamp() < begin {
+			prev = flow
+			continue
+		}
+
+		hostFlow := &resource.HostFlow{
+			Timestamp:   restresource.ISOTime(time.Unix(int64(flow.GetTimestamp()), 0)),
+			V4UpBytes:   flow.GetCurrentDeviceV4UpBytes(),
+			V4DownBytes: flow.GetCurrentDeviceV4DownBytes(),
+			V6UpBytes:   flow.GetCurrentDeviceV6UpBytes(),
+			V6DownBytes: flow.GetCurrentDeviceV6DownBytes(),
+			Connections: flow.GetCurrentDeviceConnections(),
+		}
+		if prev != nil && flow.GetTimestamp() > prev.GetTimestamp() {
+			seconds := flow.GetTimestamp() - prev.GetTimestamp()
+			hostFlow.V4UpBps = bps(prev.GetCurrentDeviceV4UpBytes(), flow.GetCurrentDeviceV4UpBytes(), seconds)
+			hostFlow.V4DownBps = bps(prev.GetCurrentDeviceV4DownBytes(), flow.GetCurrentDeviceV4DownBytes(), seconds)
+			hostFlow.V6UpBps = bps(prev.GetCurrentDeviceV6UpBytes(), flow.GetCurrentDeviceV6UpBytes(), seconds)
+			hostFlow.V6DownBps = bps(prev.GetCurrentDeviceV6DownBytes(), flow.GetCurrentDeviceV6DownBytes(), seconds)
+		}
+		hostFlows = append(hostFlows, hostFlow)
+		prev = flow
+	}
+
+	return hostFlows
+}
+
+//bps returns 0 when the counter is reset
+func bps(prevBytes, bytes, seconds uint64) uint64 {
+	if bytes < prevBytes || seconds == 0 {
+		return 0
+	}
+
+	return (bytes - prevBytes) * 8 / seconds
+}
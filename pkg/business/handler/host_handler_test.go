@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	pbHost "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+)
+
+func TestBps(t *testing.T) {
+	tests := []struct {
+		name      string
+		prevBytes uint64
+		bytes     uint64
+		seconds   uint64
+		expect    uint64
+	}{
+		{"normal", 1000, 2000, 10, 800},
+		{"no traffic", 1000, 1000, 10, 0},
+		{"counter reset", 5000, 100, 10, 0},
+		{"zero seconds", 1000, 2000, 0, 0},
+		{"rounded down", 0, 1, 3, 2},
+	}
+
+	for _, tt := range tests {
+		if rate := bps(tt.prevBytes, tt.bytes, tt.seconds); rate != tt.expect {
+			t.Errorf("%s: expect %d bps but get %d", tt.name, tt.expect, rate)
+		}
+	}
+}
+
+func TestDeviceFlowsToHostFlows(t *testing.T) {
+	flow := func(timestamp, v4Up, v6Down uint64) *pbHost.DeviceFlowData {
+		return &pbHost.DeviceFlowData{
+			Timestamp:                timestamp,
+			CurrentDeviceV4UpBytes:   v4Up,
+			CurrentDeviceV6DownBytes: v6Down,
+			CurrentDeviceConnections: 3,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		flows     []*pbHost.DeviceFlowData
+		begin     uint64
+		v4UpBps   []uint64
+		v6DownBps []uint64
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:      "unsorted samples",
+			flows:     []*pbHost.DeviceFlowData{flow(120, 3000, 600), flow(60, 1500, 300), flow(0, 0, 0)},
+			v4UpBps:   []uint64{0, 200, 200},
+			v6DownBps: []uint64{0, 40, 40},
+		},
+		{
+			name:      "sample before begin is the base of rate",
+			flows:     []*pbHost.DeviceFlowData{flow(0, 0, 0), flow(60, 1500, 300), flow(120, 3000, 600)},
+			begin:     60,
+			v4UpBps:   []uint64{200, 200},
+			v6DownBps: []uint64{40, 40},
+		},
+		{
+			name:      "counter reset",
+			flows:     []*pbHost.DeviceFlowData{flow(0, 6000, 600), flow(60, 1500, 300), flow(120, 3000, 900)},
+			v4UpBps:   []uint64{0, 0, 200},
+			v6DownBps: []uint64{0, 0, 80},
+		},
+		{
+			name:      "duplicate timestamp",
+			flows:     []*pbHost.DeviceFlowData{flow(0, 0, 0), flow(0, 1500, 300)},
+			v4UpBps:   []uint64{0, 0},
+			v6DownBps: []uint64{0, 0},
+		},
+		{
+			name:      "nil sample",
+			flows:     []*pbHost.DeviceFlowData{flow(0, 0, 0), nil, flow(60, 1500, 300)},
+			v4UpBps:   []uint64{0, 200},
+			v6DownBps: []uint64{0, 40},
+		},
+		{
+			name:  "all samples before begin",
+			flows: []*pbHost.DeviceFlowData{flow(0, 0, 0), flow(60, 1500, 300)},
+			begin: 120,
+		},
+	}
+
+	for _, tt := range tests {
+		hostFlows := deviceFlowsToHostFlows(tt.flows, tt.begin)
+		if len(hostFlows) != len(tt.v4UpBps) {
+			t.Errorf("%s: expect %d flows but get %d", tt.name, len(tt.v4UpBps), len(hostFlows))
+			continue
+		}
+
+		for i, hostFlow := range hostFlows {
+			if hostFlow.V4UpBps != tt.v4UpBps[i] || hostFlow.V6DownBps != tt.v6DownBps[i] {
+				t.Errorf("%s: flow %d expect bps %d %d but get %d %d", tt.name, i,
+					tt.v4UpBps[i], tt.v6DownBps[i], hostFlow.V4UpBps, hostFlow.V6DownBps)
+			}
+
+			if i > 0 && time.Time(hostFlow.Timestamp).Before(time.Time(hostFlows[i-1].Timestamp)) {
+				t.Errorf("%s: flows should be in time order", tt.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+const (
+	miscKeyNamePrefix = "enum_"
+	MiscValueOn       = "on"
+	MiscValueOff      = "off"
+)
+
+//miscKeys registers every supported MiscKey with the getter of its value in
+//GetMiscRsp, a new key only needs one more entry here
+var miscKeys = []struct {
+	key      pbRalt.MiscKey
+	getValue func(*pbRalt.GetMiscRsp) pbRalt.MiscSwitch
+}{
+	{pbRalt.MiscKey_enum_ralt_filter_type_default, (*pbRalt.GetMiscRsp).GetRaltFilterTypeDefault},
+}
+
+type MiscSettingHandler struct {
+	getClient func() *grpcclient.GrpcClient
+}
+
+func NewMiscSettingHandler() *MiscSettingHandler {
+	return &MiscSettingHandler{getClient: grpcclient.GetGrpcClient}
+}
+
+func MiscKeyName(key pbRalt.MiscKey) string {
+	return strings.TrimPrefix(key.String(), miscKeyNamePrefix)
+}
+
+func miscKeyIndex(name string) (int, error) {
+	for i, misc := range miscKeys {
+		if MiscKeyName(misc.key) == name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("misc setting %s is not exists", name)
+}
+
+func miscSwitchToValue(value pbRalt.MiscSwitch) string {
+	if value == pbRalt.MiscSwitch_switch_on {
+		return MiscValueOn
+	}
+
+	return MiscValueOff
+}
+
+func miscValueToSwitch(value string) (pbRalt.MiscSwitch, error) {
+	switch value {
+	case MiscValueOn:
+		return pbRalt.MiscSwitch_switch_on, nil
+	case MiscValueOff:
+		return pbRalt.MiscSwitch_switch_off, nil
+	default:
+		return pbRalt.MiscSwitch_switch_off, fmt.Errorf("invalid misc value %s", value)
+	}
+}
+
+func (h *MiscSettingHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	index, err := miscKeyIndex(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.NotFound, err.Error())
+	}
+
+	cli := h.getClient()
+	devices, err := getMiscDevices(cli, ctx.Resource.GetParent())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	settings := getMiscSettings(cli, devices)
+	return settings[index], nil
+}
+
+func (h *MiscSettingHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	cli := h.getClient()
+	devices, err := getMiscDevices(cli, ctx.Resource.GetParent())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return getMiscSettings(cli, devices), nil
+}
+
+func (h *MiscSettingHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	setting := ctx.Resource.(*resource.MiscSetting)
+	index, err := miscKeyIndex(setting.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.NotFound, err.Error())
+	}
+
+	value, err := miscValueToSwitch(setting.Value)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	cli := h.getClient()
+	devices, err := getMiscDevices(cli, setting.GetParent())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	var failedNodes []string
+	for _, device := range devices {
+		nodeIP := grpcclient.DeviceIP(device)
+		rsp, err := cli.RaltClient.ModMisc(context.Background(), &pbRalt.ModMiscOpReq{
+			IpAddr: nodeIP,
+			Misc:   &pbRalt.Misc{Key: miscKeys[index].key, Value: value},
+		})
+		if err != nil {
+			failedNodes = append(failedNodes, fmt.Sprintf("%s: grpc service exec ModMisc failed: %s", nodeIP, err.Error()))
+		} else if rsp.GetResult() != grpcclient.RaltResultSucceed {
+			failedNodes = append(failedNodes, fmt.Sprintf("%s: ModMisc result %d", nodeIP, rsp.GetResult()))
+		}
+	}
+
+	if len(failedNodes) != 0 {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("update misc setting %s failed on nodes %s", setting.GetID(), strings.Join(failedNodes, ", ")))
+	}
+
+	return getMiscSettings(cli, devices)[index], nil
+}
+
+//getMiscDevices returns the host or nodes of the cluster which misc setting
+//belongs to
+func getMiscDevices(cli *grpcclient.GrpcClient, parent restresource.Resource) ([]*pbCluster.Device, error) {
+	switch parent.(type) {
+	case *resource.Host:
+		return cli.GetDevices(context.Background(), parent.GetID())
+	case *resource.Cluster:
+		return cli.GetClusterDevices(context.Background(), parent.GetID())
+	default:
+		return nil, fmt.Errorf("misc setting should belong to cluster or host")
+	}
+}
+
+func getMiscSettings(cli *grpcclient.GrpcClient, devices []*pbCluster.Device) []*resource.MiscSetting {
+	settings := make([]*resource.MiscSetting, len(miscKeys))
+	for i, misc := range miscKeys {
+		settings[i] = &resource.MiscSetting{Consistent: true}
+		settings[i].SetID(MiscKeyName(misc.key))
+	}
+
+	for _, device := range devices {
+		nodeIP := grpcclient.DeviceIP(device)
+		rsp, err := cli.RaltClient.GetMisc(context.Background(), &pbRalt.GetMiscReq{IpAddr: nodeIP})
+		for i, misc := range miscKeys {
+			nodeValue := &resource.MiscNodeValue{HostID: device.GetHostId(), NodeIP: nodeIP}
+			if err != nil {
+				nodeValue.ErrMessage = fmt.Sprintf("grpc service exec GetMisc failed: %s", err.Error())
+				settings[i].Consistent = false
+			} else {
+				nodeValue.Value = miscSwitchToValue(misc.getValue(rsp))
+				if settings[i].Value == "" {
+					settings[i].Value = nodeValue.Value
+				} else if settings[i].Value != nodeValue.Value {
+					settings[i].Consistent = false
+				}
+			}
+			settings[i].Nodes = append(settings[i].Nodes, nodeValue)
+		}
+	}
+
+	return settings
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+type fakeMiscClient struct {
+	pbCluster.ClusterManagerClient
+	pbRalt.RaltServiceClient
+	clusterIDs []string
+	hostIDs    []string
+	values     map[string]pbRalt.MiscSwitch
+}
+
+func (c *fakeMiscClient) QryOneCluster(ctx context.Context, in *pbCluster.ClusterIDReq, opts ...grpc.CallOption) (*pbCluster.ClusterDetailInfoRsp, error) {
+	c.clusterIDs = append(c.clusterIDs, in.GetClusterId())
+	return &pbCluster.ClusterDetailInfoRsp{SocsInfo: &pbCluster.ClusterBalanceInfo{
+		NodeHost: []*pbCluster.NodeHost{&pbCluster.NodeHost{HostId: "h1"}},
+	}}, nil
+}
+
+func (c *fakeMiscClient) GetDevices(ctx context.Context, in *pbCluster.DeviceIDReq, opts ...grpc.CallOption) (*pbCluster.DevicesRsp, error) {
+	c.hostIDs = append(c.hostIDs, in.GetHostId()...)
+	return &pbCluster.DevicesRsp{}, nil
+}
+
+func (c *fakeMiscClient) GetMisc(ctx context.Context, in *pbRalt.GetMiscReq, opts ...grpc.CallOption) (*pbRalt.GetMiscRsp, error) {
+	value, ok := c.values[in.GetIpAddr()]
+	if ok == false {
+		return nil, errors.New("node unavailable")
+	}
+	return &pbRalt.GetMiscRsp{RaltFilterTypeDefault: value}, nil
+}
+
+func newMiscClient(fake *fakeMiscClient) *grpcclient.GrpcClient {
+	return &grpcclient.GrpcClient{ClusterClient: fake, RaltClient: fake}
+}
+
+func TestMiscKeys(t *testing.T) {
+	for i, misc := range miscKeys {
+		name := MiscKeyName(misc.key)
+		if index, err := miscKeyIndex(name); err != nil || index != i {
+			t.Errorf("misc key %s should be registered at %d but get %d %v", name, i, index, err)
+		}
+
+		rsp := &pbRalt.GetMiscRsp{RaltFilterTypeDefault: pbRalt.MiscSwitch_switch_on}
+		if misc.getValue == nil || misc.getValue(rsp) != pbRalt.MiscSwitch_switch_on {
+			t.Errorf("misc key %s should get its value from GetMiscRsp", name)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"ralt_filter_type_default", true},
+		{"enum_ralt_filter_type_default", false},
+		{"unknown", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if _, err := miscKeyIndex(tt.name); (err == nil) != tt.valid {
+			t.Errorf("misc key %q expect valid %v but get %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestMiscValueToSwitch(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{MiscValueOn, true},
+		{MiscValueOff, true},
+		{"ON", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		value, err := miscValueToSwitch(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("misc value %q expect valid %v but get %v", tt.value, tt.valid, err)
+		} else if tt.valid && miscSwitchToValue(value) != tt.value {
+			t.Errorf("misc value %q should be converted back but get %s", tt.value, miscSwitchToValue(value))
+		}
+	}
+}
+
+func TestGetMiscSettings(t *testing.T) {
+	on, off := pbRalt.MiscSwitch_switch_on, pbRalt.MiscSwitch_switch_off
+	devices := []*pbCluster.Device{
+		&pbCluster.Device{HostId: "h1", Ipv4Addr: "10.0.0.1"},
+		&pbCluster.Device{HostId: "h2", Ipv4Addr: "10.0.0.2"},
+	}
+
+	tests := []struct {
+		name       string
+		values     map[string]pbRalt.MiscSwitch
+		value      string
+		consistent bool
+		errNodes   int
+	}{
+		{"nodes agree", map[string]pbRalt.MiscSwitch{"10.0.0.1": on, "10.0.0.2": on}, MiscValueOn, true, 0},
+		{"nodes disagree", map[string]pbRalt.MiscSwitch{"10.0.0.1": off, "10.0.0.2": on}, MiscValueOff, false, 0},
+		{"node failed", map[string]pbRalt.MiscSwitch{"10.0.0.2": on}, MiscValueOn, false, 1},
+		{"all nodes failed", nil, "", false, 2},
+	}
+
+	for _, tt := range tests {
+		settings := getMiscSettings(newMiscClient(&fakeMiscClient{values: tt.values}), devices)
+		if len(settings) != len(miscKeys) {
+			t.Fatalf("%s: expect %d settings but get %d", tt.name, len(miscKeys), len(settings))
+		}
+
+		setting := settings[0]
+		if setting.Value != tt.value || setting.Consistent != tt.consistent || len(setting.Nodes) != len(devices) {
+			t.Errorf("%s: expect value %q consistent %v but get %+v", tt.name, tt.value, tt.consistent, setting)
+		}
+
+		errNodes := 0
+		for _, node := range setting.Nodes {
+			if node.ErrMessage != "" {
+				errNodes += 1
+			}
+		}
+		if errNodes != tt.errNodes {
+			t.Errorf("%s: expect %d failed nodes but get %d", tt.name, tt.errNodes, errNodes)
+		}
+	}
+
+	if setting := getMiscSettings(newMiscClient(&fakeMiscClient{}), nil)[0]; setting.Consistent == false {
+		t.Errorf("setting without node should be consistent")
+	}
+}
+
+func TestGetMiscDevices(t *testing.T) {
+	cluster := &resource.Cluster{}
+	cluster.SetID("cluster2")
+	host := &resource.Host{}
+	host.SetID("h2")
+
+	fake := &fakeMiscClient{}
+	cli := newMiscClient(fake)
+	if _, err := getMiscDevices(cli, cluster); err != nil {
+		t.Errorf("get devices of cluster failed: %s", err.Error())
+	}
+	if _, err := getMiscDevices(cli, host); err != nil {
+		t.Errorf("get devices of host failed: %s", err.Error())
+	}
+	if _, err := getMiscDevices(cli, nil); err == nil {
+		t.Errorf("get devices without parent should fail")
+	}
+
+	if equalStrings(fake.clusterIDs, []string{"cluster2"}) == false {
+		t.Errorf("nodes of cluster2 should be queried but get %v", fake.clusterIDs)
+	}
+	if equalStrings(fake.hostIDs, []string{"h1", "h2"}) == false {
+		t.Errorf("nodes of cluster2 and host h2 should be queried but get %v", fake.hostIDs)
+	}
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/zdnscloud/cement/log"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+var (
+	WSRollingEventPath = "/apis/ws.linkingthing.com/v1/rollingevent"
+)
+
+const (
+	maxRollingEventLen          = 1000
+	defaultRollingHealthTimeout = 60
+	rollingCmdTimeout           = 30 * time.Second
+)
+
+type RollingHandler struct {
+	lock        sync.RWMutex
+	cond        *sync.Cond
+	eventList   *list.List
+	eventOffset uint64
+	running     bool
+	getClient   func() *grpcclient.GrpcClient
+}
+
+type rollingEvent struct {
+	index uint64
+	*resource.RollingEvent
+}
+
+type RollingEventListener struct {
+	eventCh chan interface{}
+	offset  uint64
+	stopCh  chan struct{}
+}
+
+func NewRollingHandler() *RollingHandler {
+	h := &RollingHandler{eventList: list.New(), getClient: grpcclient.GetGrpcClient}
+	h.cond = sync.NewCond(&h.lock)
+	return h
+}
+
+func (h *RollingHandler) Start(clusterID, action string, req *resource.RollingRequest) (*resource.RollingTask, error) {
+	var cmd pbRalt.CommandType
+	switch action {
+	case resource.ActionRollingRestart:
+		cmd = pbRalt.CommandType_ralt_restart
+	case resource.ActionRollingReload:
+		cmd = pbRalt.CommandType_reload_config
+	default:
+		return nil, fmt.Errorf("unknown rolling action %s", action)
+	}
+
+	devices, err := h.getClient().GetClusterDevices(context.Background(), clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no node found in cluster %s", clusterID)
+	}
+
+	h.lock.Lock()
+	if h.running {
+		h.lock.Unlock()
+		return nil, fmt.Errorf("another rolling task is running")
+	}
+	h.running = true
+	h.lock.Unlock()
+
+	task := &resource.RollingTask{
+		TaskID: strconv.FormatInt(time.Now().UnixNano(), 10),
+		Action: action,
+	}
+	for _, device := range devices {
+		task.NodeIPs = append(task.NodeIPs, grpcclient.DeviceIP(device))
+	}
+
+	healthTimeout := req.HealthTimeout
+	if healthTimeout == 0 {
+		healthTimeout = defaultRollingHealthTimeout
+	}
+
+	go h.run(task, cmd, devices, time.Duration(healthTimeout)*time.Second)
+	return task, nil
+}
+
+func (h *RollingHandler) run(task *resource.RollingTask, cmd pbRalt.CommandType, devices []*pbCluster.Device, healthTimeout time.Duration) {
+	defer func() {
+		h.lock.Lock()
+		h.running = false
+		h.lock.Unlock()
+	}()
+
+	cli := h.getClient()
+	total := len(devices)
+	for i, device := range devices {
+		event := &resource.RollingEvent{
+			TaskID: task.TaskID,
+			Action: task.Action,
+			HostID: device.GetHostId(),
+			NodeIP: grpcclient.DeviceIP(device),
+			Step:   i + 1,
+			Total:  total,
+		}
+
+		h.publish(event, resource.RollingStateStarted, "")
+		if err := h.execOnNode(cli, event, cmd, healthTimeout); err != nil {
+			h.publish(event, resource.RollingStateFailed, fmt.Sprintf("abort rolling task: %s", err.Error()))
+			return
+		}
+
+		h.publish(event, resource.RollingStateHealthy, "")
+	}
+
+	h.publish(&resource.RollingEvent{TaskID: task.TaskID, Action: task.Action, Step: total, Total: total},
+		resource.RollingStateFinished, "")
+}
+
+//execOnNode watches status of node before executing cmd, so node restarted
+//is healthy only after ralt stopped by cmd is running again
+func (h *RollingHandler) execOnNode(cli *grpcclient.GrpcClient, event *resource.RollingEvent, cmd pbRalt.CommandType, healthTimeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rollingCmdTimeout+healthTimeout)
+	defer cancel()
+	watcher, err := cli.WatchRaltStatus(ctx, event.NodeIP)
+	if err != nil {
+		return err
+	}
+
+	cmdCtx, cmdCancel := context.WithTimeout(ctx, rollingCmdTimeout)
+	err = cli.ExecRaltCmd(cmdCtx, event.NodeIP, cmd)
+	cmdCancel()
+	if err != nil {
+		return err
+	}
+
+	h.publish(event, resource.RollingStateExecuted, "")
+	return watcher.WaitRunning(cmd == pbRalt.CommandType_ralt_restart)
+}
+
+func (h *RollingHandler) publish(event *resource.RollingEvent, state resource.RollingState, message string) {
+	e := *event
+	e.State = state
+	e.Message = message
+	e.Timestamp = restresource.ISOTime(time.Now())
+	if state == resource.RollingStateFailed {
+		log.Warnf("rolling task %s on node %s failed: %s", e.TaskID, e.NodeIP, message)
+	}
+
+	h.lock.Lock()
+	if h.eventList.Len() > maxRollingEventLen {
+		h.eventList.Remove(h.eventList.Front())
+	}
+	h.eventOffset += 1
+	h.eventList.PushBack(&rollingEvent{index: h.eventOffset, RollingEvent: &e})
+	h.lock.Unlock()
+	h.cond.Broadcast()
+}
+
+func (h *RollingHandler) RegisterWSHandler(router gin.IRoutes) {
+	router.GET(WSRollingEventPath, func(c *gin.Context) {
+		h.OpenRollingEvent(c.Request, c.Writer)
+	})
+}
+
+func (h *RollingHandler) OpenRollingEvent(r *http.Request, w http.ResponseWriter) {
+	conn, err := websocket.Upgrade(w, r, nil, 0, 0)
+	if err != nil {
+		log.Warnf("OpenRollingEvent websocket upgrade failed %s", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	listener := h.AddListener()
+	broadcastCh := listener.EventNotifyChan()
+	defer listener.stop()
+
+	for {
+		data, ok := <-broadcastCh
+		if !ok {
+			break
+		}
+
+		if err = conn.WriteJSON(data); err != nil {
+			if util.IsBrokenPipeErr(err) == false {
+				log.Warnf("send rollingEvent websocket failed:%s", err.Error())
+			}
+			break
+		}
+	}
+}
+
+//AddListener only pushes events published after the listener is added
+func (h *RollingHandler) AddListener() *RollingEventListener {
+	h.lock.RLock()
+	listener := &RollingEventListener{
+		eventCh: make(chan interface{}),
+		stopCh:  make(chan struct{}),
+		offset:  h.eventOffset,
+	}
+	h.lock.RUnlock()
+
+	go h.publicRollingEvent(listener)
+	return listener
+}
+
+func (listener *RollingEventListener) stop() {
+	listener.stopCh <- struct{}{}
+	<-listener.stopCh
+	close(listener.eventCh)
+}
+
+func (listener *RollingEventListener) EventNotifyChan() <-chan interface{} {
+	return listener.eventCh
+}
+
+func (h *RollingHandler) publicRollingEvent(listener *RollingEventListener) {
+	for {
+		select {
+		case <-listener.stopCh:
+			listener.stopCh <- struct{}{}
+			return
+		default:
+		}
+
+		events := h.getEvents(listener.offset)
+		eventsLen := len(events)
+		if eventsLen == 0 {
+			h.lock.Lock()
+			if h.eventOffset == listener.offset {
+				h.cond.Wait()
+			}
+			h.lock.Unlock()
+			continue
+		}
+
+		listener.offset += uint64(eventsLen)
+		for i := eventsLen - 1; i >= 0; i-- {
+			select {
+			case <-listener.stopCh:
+				listener.stopCh <- struct{}{}
+				return
+			case listener.eventCh <- events[i]:
+			}
+		}
+	}
+}
+
+func (h *RollingHandler) getEvents(offset uint64) []*resource.RollingEvent {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	var events []*resource.RollingEvent
+	for e := h.eventList.Back(); e != nil; e = e.Prev() {
+		event := e.Value.(*rollingEvent)
+		if event.index > offset {
+			events = append(events, event.RollingEvent)
+		} else {
+			break
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+	"google.golang.org/grpc"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+type fakeStatusStream struct {
+	grpc.ClientStream
+	statuses []uint32
+}
+
+func (s *fakeStatusStream) Recv() (*pbRalt.RaltStatus, error) {
+	if len(s.statuses) == 0 {
+		return nil, io.EOF
+	}
+
+	status := s.statuses[0]
+	s.statuses = s.statuses[1:]
+	return &pbRalt.RaltStatus{Status: status}, nil
+}
+
+type fakeRaltClient struct {
+	pbRalt.RaltServiceClient
+	lock sync.Mutex
+	//calls records watch and exec in order
+	calls    []string
+	statuses map[string][]uint32
+	failed   map[string]uint32
+}
+
+func (c *fakeRaltClient) GetRaltStatus(ctx context.Context, in *pbRalt.RaltStatusReq, opts ...grpc.CallOption) (pbRalt.RaltService_GetRaltStatusClient, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.calls = append(c.calls, "watch "+in.GetIpAddr())
+	return &fakeStatusStream{statuses: c.statuses[in.GetIpAddr()]}, nil
+}
+
+func (c *fakeRaltClient) ExecCmd(ctx context.Context, in *pbRalt.ExecCmdReq, opts ...grpc.CallOption) (*pbRalt.ExecCmdRsp, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.calls = append(c.calls, "exec "+in.GetIpAddr())
+	return &pbRalt.ExecCmdRsp{Result: c.failed[in.GetIpAddr()]}, nil
+}
+
+func rollingEvents(h *RollingHandler) []string {
+	var events []string
+	for e := h.eventList.Front(); e != nil; e = e.Next() {
+		event := e.Value.(*rollingEvent)
+		events = append(events, fmt.Sprintf("%s %s", event.NodeIP, event.State))
+	}
+	return events
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRollingRestart(t *testing.T) {
+	stopped := grpcclient.RaltStatusRunning + 1
+	restarted := []uint32{grpcclient.RaltStatusRunning, stopped, grpcclient.RaltStatusRunning}
+	devices := []*pbCluster.Device{
+		&pbCluster.Device{HostId: "h1", Ipv4Addr: "10.0.0.1"},
+		&pbCluster.Device{HostId: "h2", Ipv4Addr: "10.0.0.2"},
+		&pbCluster.Device{HostId: "h3", Ipv4Addr: "10.0.0.3"},
+	}
+
+	tests := []struct {
+		name     string
+		statuses map[string][]uint32
+		failed   map[string]uint32
+		calls    []string
+		events   []string
+	}{
+		{
+			name: "all nodes restarted",
+			statuses: map[string][]uint32{
+				"10.0.0.1": restarted, "10.0.0.2": restarted, "10.0.0.3": restarted,
+			},
+			calls: []string{
+				"watch 10.0.0.1", "exec 10.0.0.1", "watch 10.0.0.2", "exec 10.0.0.2", "watch 10.0.0.3", "exec 10.0.0.3",
+			},
+			events: []string{
+				"10.0.0.1 started", "10.0.0.1 executed", "10.0.0.1 healthy",
+				"10.0.0.2 started", "10.0.0.2 executed", "10.0.0.2 healthy",
+				"10.0.0.3 started", "10.0.0.3 executed", "10.0.0.3 healthy",
+				" finished",
+			},
+		},
+		{
+			name: "abort on command failure",
+			statuses: map[string][]uint32{
+				"10.0.0.1": restarted, "10.0.0.2": restarted, "10.0.0.3": restarted,
+			},
+			failed: map[string]uint32{"10.0.0.2": 1},
+			calls:  []string{"watch 10.0.0.1", "exec 10.0.0.1", "watch 10.0.0.2", "exec 10.0.0.2"},
+			events: []string{
+				"10.0.0.1 started", "10.0.0.1 executed", "10.0.0.1 healthy",
+				"10.0.0.2 started", "10.0.0.2 failed",
+			},
+		},
+		{
+			name: "abort when ralt is not restarted",
+			statuses: map[string][]uint32{
+				"10.0.0.1": []uint32{grpcclient.RaltStatusRunning}, "10.0.0.2": restarted, "10.0.0.3": restarted,
+			},
+			calls:  []string{"watch 10.0.0.1", "exec 10.0.0.1"},
+			events: []string{"10.0.0.1 started", "10.0.0.1 executed", "10.0.0.1 failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		ralt := &fakeRaltClient{statuses: tt.statuses, failed: tt.failed}
+		h := NewRollingHandler()
+		h.getClient = func() *grpcclient.GrpcClient {
+			return &grpcclient.GrpcClient{RaltClient: ralt}
+		}
+		h.running = true
+		task := &resource.RollingTask{TaskID: "1", Action: resource.ActionRollingRestart}
+		h.run(task, pbRalt.CommandType_ralt_restart, devices, time.Second)
+
+		if equalStrings(ralt.calls, tt.calls) == false {
+			t.Errorf("%s: expect calls %v but get %v", tt.name, tt.calls, ralt.calls)
+		}
+
+		if events := rollingEvents(h); equalStrings(events, tt.events) == false {
+			t.Errorf("%s: expect events %v but get %v", tt.name, tt.events, events)
+		}
+
+		if h.running {
+			t.Errorf("%s: rolling task should be done", tt.name)
+		}
+	}
+}
+
+type fakeClusterClient struct {
+	pbCluster.ClusterManagerClient
+	clusterIDs []string
+}
+
+func (c *fakeClusterClient) QryOneCluster(ctx context.Context, in *pbCluster.ClusterIDReq, opts ...grpc.CallOption) (*pbCluster.ClusterDetailInfoRsp, error) {
+	c.clusterIDs = append(c.clusterIDs, in.GetClusterId())
+	return &pbCluster.ClusterDetailInfoRsp{}, nil
+}
+
+func TestRollingStartCluster(t *testing.T) {
+	cluster := &fakeClusterClient{}
+	h := NewRollingHandler()
+	h.getClient = func() *grpcclient.GrpcClient {
+		return &grpcclient.GrpcClient{ClusterClient: cluster}
+	}
+
+	if _, err := h.Start("cluster2", resource.ActionRollingRestart, &resource.RollingRequest{}); err == nil {
+		t.Errorf("rolling task on cluster without node should fail")
+	}
+
+	if equalStrings(cluster.clusterIDs, []string{"cluster2"}) == false {
+		t.Errorf("nodes of cluster2 should be queried but get %v", cluster.clusterIDs)
+	}
+
+	if h.running {
+		t.Errorf("rolling task shouldn't be started")
+	}
+}
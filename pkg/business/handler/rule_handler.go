@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+const ruleTypeNamePrefix = "enum_"
+
+type RuleHandler struct{}
+
+func NewRuleHandler() *RuleHandler {
+	return &RuleHandler{}
+}
+
+func RuleTypeName(ruleType int32) string {
+	if name, ok := pbRalt.RuleType_name[ruleType]; ok && ruleType != int32(pbRalt.RuleType_enum_begin) {
+		return strings.TrimPrefix(name, ruleTypeNamePrefix)
+	}
+
+	return strconv.Itoa(int(ruleType))
+}
+
+func RuleTypeFromName(name string) (pbRalt.RuleType, error) {
+	if value, ok := pbRalt.RuleType_value[ruleTypeNamePrefix+name]; ok && value != int32(pbRalt.RuleType_enum_begin) {
+		return pbRalt.RuleType(value), nil
+	}
+
+	return pbRalt.RuleType_enum_begin, fmt.Errorf("unknown rule type %s", name)
+}
+
+func CheckRuleType(ruleType int32) error {
+	if _, ok := pbRalt.RuleType_name[ruleType]; !ok || ruleType == int32(pbRalt.RuleType_enum_begin) {
+		return fmt.Errorf("unknown rule type %d", ruleType)
+	}
+
+	return nil
+}
+
+func (h *RuleHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	rule := ctx.Resource.(*resource.Rule)
+	pbRule, err := ruleToPbRule(rule)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	nodeIP, rules, err := getNodeRules(rule.GetParent().GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	id := ruleID(pbRule)
+	if _, ok := ruleIndex(id, rules); ok {
+		return nil, resterror.NewAPIError(resterror.DuplicateResource, fmt.Sprintf("rule %s already exists", id))
+	}
+
+	rules = append(rules, pbRule)
+	if err := updateNodeRules(nodeIP, rules); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	rule.SetID(id)
+	rule.NodeIP = nodeIP
+	return rule, nil
+}
+
+func (h *RuleHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	rule := ctx.Resource.(*resource.Rule)
+	pbRule, err := ruleToPbRule(rule)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	nodeIP, rules, err := getNodeRules(rule.GetParent().GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	index, ok := ruleIndex(rule.GetID(), rules)
+	if ok == false {
+		return nil, resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("rule %s is non-exists", rule.GetID()))
+	}
+
+	id := ruleID(pbRule)
+	if i, ok := ruleIndex(id, rules); ok && i != index {
+		return nil, resterror.NewAPIError(resterror.DuplicateResource, fmt.Sprintf("rule %s already exists", id))
+	}
+
+	rules[index] = pbRule
+	if err := updateNodeRules(nodeIP, rules); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	rule.SetID(id)
+	rule.NodeIP = nodeIP
+	return rule, nil
+}
+
+func (h *RuleHandler) Delete(ctx *restresource.Context) *resterror.APIError {
+	rule := ctx.Resource.(*resource.Rule)
+	nodeIP, rules, err := getNodeRules(rule.GetParent().GetID())
+	if err != nil {
+		return resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	index, ok := ruleIndex(rule.GetID(), rules)
+	if ok == false {
+		return resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("rule %s is non-exists", rule.GetID()))
+	}
+
+	if err := updateNodeRules(nodeIP, append(rules[:index], rules[index+1:]...)); err != nil {
+		return resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return nil
+}
+
+func (h *RuleHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	rule := ctx.Resource.(*resource.Rule)
+	nodeIP, rules, err := getNodeRules(rule.GetParent().GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	index, ok := ruleIndex(rule.GetID(), rules)
+	if ok == false {
+		return nil, resterror.NewAPIError(resterror.NotFound, fmt.Sprintf("rule %s is non-exists", rule.GetID()))
+	}
+
+	return pbRuleToRule(nodeIP, rules[index]), nil
+}
+
+func (h *RuleHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	nodeIP, rules, err := getNodeRules(ctx.Resource.GetParent().GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	var out []*resource.Rule
+	for _, rule := range rules {
+		out = append(out, pbRuleToRule(nodeIP, rule))
+	}
+
+	return out, nil
+}
+
+func getNodeRules(hostID string) (string, []*pbRalt.Rule, error) {
+	cli := grpcclient.GetGrpcClient()
+	nodeIP, err := cli.GetDeviceIP(context.Background(), hostID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rsp, err := cli.RaltClient.GetRule(context.Background(), &pbRalt.GetRuleReq{IpAddr: nodeIP})
+	if err != nil {
+		return "", nil, fmt.Errorf("grpc service exec GetRule failed: %s", err.Error())
+	}
+
+	return nodeIP, rsp.GetRule(), nil
+}
+
+func updateNodeRules(nodeIP string, rules []*pbRalt.Rule) error {
+	cli := grpcclient.GetGrpcClient()
+	if _, err := cli.RaltClient.UpdateRule(context.Background(), &pbRalt.UpdateRuleReq{IpAddr: nodeIP, Rule: rules}); err != nil {
+		return fmt.Errorf("grpc service exec UpdateRule failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+//ruleID is hash of rule content, since rules on node have no id, it keeps
+//unchanged when other rules are added or deleted
+func ruleID(rule *pbRalt.Rule) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%s\x00%s\x00%s", rule.GetType(), rule.GetSearch(),
+		rule.GetReplace(), rule.GetAppend(), rule.GetRaltDomain())))
+	return hex.EncodeToString(hash[:8])
+}
+
+func ruleIndex(id string, rules []*pbRalt.Rule) (int, bool) {
+	for i, rule := range rules {
+		if ruleID(rule) == id {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func ruleToPbRule(rule *resource.Rule) (*pbRalt.Rule, error) {
+	ruleType, err := RuleTypeFromName(rule.RuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Search == "" {
+		return nil, fmt.Errorf("search of rule should not be empty")
+	}
+
+	return &pbRalt.Rule{
+		Type:       ruleType,
+		Search:     rule.Search,
+		Replace:    rule.Replace,
+		Append:     rule.Append,
+		RaltDomain: rule.RaltDomain,
+	}, nil
+}
+
+func pbRuleToRule(nodeIP string, pbRule *pbRalt.Rule) *resource.Rule {
+	rule := &resource.Rule{
+		NodeIP:     nodeIP,
+		RuleType:   RuleTypeName(int32(pbRule.GetType())),
+		Search:     pbRule.GetSearch(),
+		Replace:    pbRule.GetReplace(),
+		Append:     pbRule.GetAppend(),
+		RaltDomain: pbRule.GetRaltDomain(),
+	}
+	rule.SetID(ruleID(pbRule))
+	return rule
+}
+
+func checkGroupRulesOnNodes(webGroup *resource.WebGroup) ([]*resource.RuleConsistency, error) {
+	clusterID := webGroup.ClusterID
+	if clusterID == "" {
+		clusterID = DefaultClusterID
+	}
+
+	cli := grpcclient.GetGrpcClient()
+	devices, err := cli.GetClusterDevices(context.Background(), clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*resource.RuleConsistency
+	for _, device := range devices {
+		consistency := &resource.RuleConsistency{
+			HostID: device.GetHostId(),
+			NodeIP: grpcclient.DeviceIP(device),
+		}
+		result = append(result, consistency)
+		rsp, err := cli.RaltClient.GetRule(context.Background(), &pbRalt.GetRuleReq{IpAddr: consistency.NodeIP})
+		if err != nil {
+			consistency.ErrMessage = fmt.Sprintf("grpc service exec GetRule failed: %s", err.Error())
+			continue
+		}
+
+		installed := make(map[string]struct{}, len(rsp.GetRule()))
+		for _, rule := range rsp.GetRule() {
+			installed[ruleKey(int32(rule.GetType()), rule.GetSearch(), rule.GetReplace())] = struct{}{}
+		}
+
+		for _, rule := range webGroup.Rules {
+			if _, ok := installed[ruleKey(rule.RuleType, rule.SearchString, rule.ReplaceString)]; !ok {
+				consistency.MissingRules = append(consistency.MissingRules, rule)
+			}
+		}
+
+		consistency.InstalledCount = len(rsp.GetRule())
+		consistency.Consistent = len(consistency.MissingRules) == 0
+	}
+
+	return result, nil
+}
+
+func ruleKey(ruleType int32, search, replace string) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", ruleType, search, replace)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+func TestRuleIDStable(t *testing.T) {
+	rules := []*pbRalt.Rule{
+		&pbRalt.Rule{Type: pbRalt.RuleType_enum_top_level_domain, Search: "a.com", Replace: "b.com"},
+		&pbRalt.Rule{Type: pbRalt.RuleType_enum_top_level_domain, Search: "c.com", Replace: "d.com"},
+		&pbRalt.Rule{Type: pbRalt.RuleType_enum_top_level_domain, Search: "c.com", Replace: "d.com", Append: "x"},
+	}
+
+	ids := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		ids[ruleID(rule)] = struct{}{}
+	}
+	if len(ids) != len(rules) {
+		t.Fatalf("rules with different content should have different id")
+	}
+
+	id := ruleID(rules[2])
+	rules = append(rules[:0], rules[1:]...)
+	if index, ok := ruleIndex(id, rules); ok == false || index != 1 {
+		t.Errorf("rule %s should be found at 1 after deleting previous rule but get %d %v", id, index, ok)
+	}
+
+	if _, ok := ruleIndex(ruleID(&pbRalt.Rule{Search: "a.com"}), rules); ok {
+		t.Errorf("deleted rule should not be found")
+	}
+}
+
+func TestRuleToPbRule(t *testing.T) {
+	tests := []struct {
+		rule     resource.Rule
+		valid    bool
+		ruleType pbRalt.RuleType
+	}{
+		{resource.Rule{RuleType: "top_level_domain", Search: "a.com", Replace: "b.com"}, true, pbRalt.RuleType_enum_top_level_domain},
+		{resource.Rule{RuleType: "just_restore", Search: "a.com"}, true, pbRalt.RuleType_enum_just_restore},
+		{resource.Rule{RuleType: "enum_just_restore", Search: "a.com"}, false, pbRalt.RuleType_enum_begin},
+		{resource.Rule{RuleType: "begin", Search: "a.com"}, false, pbRalt.RuleType_enum_begin},
+		{resource.Rule{RuleType: "unknown", Search: "a.com"}, false, pbRalt.RuleType_enum_begin},
+		{resource.Rule{RuleType: "subs_string"}, false, pbRalt.RuleType_enum_begin},
+	}
+
+	for _, tt := range tests {
+		pbRule, err := ruleToPbRule(&tt.rule)
+		if (err == nil) != tt.valid {
+			t.Errorf("rule %+v expected valid %v but get %v", tt.rule, tt.valid, err)
+		} else if tt.valid && (pbRule.GetType() != tt.ruleType || pbRule.GetSearch() != tt.rule.Search) {
+			t.Errorf("rule %+v expected type %v but get %+v", tt.rule, tt.ruleType, pbRule)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"sort"
+	"time"
+
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	statsresource "github.com/trymanytimes/UpdateWeb/pkg/stats/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/stats/store"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const FilterResolution = "resolution"
+
+//getResolutionFromFilters returns false when the stats should be read from
+//backend instead of the rollups kept by controller
+func getResolutionFromFilters(filters []restresource.Filter) (statsresource.Resolution, bool) {
+	value, ok := util.GetFilterValueWithEqModifierFromFilters(FilterResolution, filters)
+	if ok == false {
+		return "", false
+	}
+
+	resolution := statsresource.Resolution(value)
+	return resolution, resolution.Validate()
+}
+
+func getVisitorDataFromRollups(resolution statsresource.Resolution, from, to time.Time) ([]*pbHomePage.VisitorData, error) {
+	httpRollups, err := store.Query(resolution, statsresource.MetricHttpVisitors, DefaultClusterID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	neteRollups, err := store.Query(resolution, statsresource.MetricNeteVisitors, DefaultClusterID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	visitorDataMap := make(map[int64]*pbHomePage.VisitorData)
+	getVisitorData := func(bucketTime time.Time) *pbHomePage.VisitorData {
+		data, ok := visitorDataMap[bucketTime.Unix()]
+		if ok == false {
+			data = &pbHomePage.VisitorData{Timestamp: uint64(bucketTime.Unix())}
+			visitorDataMap[bucketTime.Unix()] = data
+		}
+		return data
+	}
+
+	for _, rollup := range httpRollups {
+		getVisitorData(rollup.BucketTime).HttpVisitorNumber = rollup.Value
+	}
+
+	for _, rollup := range neteRollups {
+		getVisitorData(rollup.BucketTime).NeteVisitorNumber = rollup.Value
+	}
+
+	visitorData := make([]*pbHomePage.VisitorData, 0, len(visitorDataMap))
+	for _, data := range visitorDataMap {
+		visitorData = append(visitorData, data)
+	}
+
+	return visitorData, nil
+}
+
+func getDomainVisitorsFromRollups(resolution statsresource.Resolution, from, to time.Time) ([]*pbHomePage.DomainVisitor, error) {
+	rollups, err := store.Query(resolution, statsresource.MetricDomainVisits, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	domainVisitorMap := make(map[string]*pbHomePage.DomainVisitor)
+	var domainVisitors []*pbHomePage.DomainVisitor
+	for _, rollup := range rollups {
+		domainVisitor, ok := domainVisitorMap[rollup.Object]
+		if ok == false {
+			domainVisitor = &pbHomePage.DomainVisitor{MemberDomain: rollup.Object}
+			domainVisitorMap[rollup.Object] = domainVisitor
+			domainVisitors = append(domainVisitors, domainVisitor)
+		}
+
+		domainVisitor.VisitHostNumber = append(domainVisitor.VisitHostNumber, &pbHomePage.VisitDomainNum{
+			VisitDomainNum: rollup.Value,
+			Timestamp:      uint64(rollup.BucketTime.Unix()),
+		})
+	}
+
+	return domainVisitors, nil
+}
+
+//getHostFlowsFromRollups returns the bytes transferred in every bucket,
+//connections is the average of samples in bucket
+func getHostFlowsFromRollups(resolution statsresource.Resolution, hostID string, from, to time.Time) ([]*resource.HostFlow, error) {
+	hostFlowMap := make(map[int64]*resource.HostFlow)
+	getHostFlow := func(bucketTime time.Time) *resource.HostFlow {
+		hostFlow, ok := hostFlowMap[bucketTime.Unix()]
+		if ok == false {
+			hostFlow = &resource.HostFlow{Timestamp: restresource.ISOTime(bucketTime)}
+			hostFlowMap[bucketTime.Unix()] = hostFlow
+		}
+		return hostFlow
+	}
+
+	seconds := uint64(resolution.Duration() / time.Second)
+	for _, metric := range []string{
+		statsresource.MetricHostV4UpBytes,
+		statsresource.MetricHostV4DownBytes,
+		statsresource.MetricHostV6UpBytes,
+		statsresource.MetricHostV6DownBytes,
+		statsresource.MetricHostConnections,
+	} {
+		rollups, err := store.Query(resolution, metric, hostID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rollup := range rollups {
+			hostFlow := getHostFlow(rollup.BucketTime)
+			switch metric {
+			case statsresource.MetricHostV4UpBytes:
+				hostFlow.V4UpBytes, hostFlow.V4UpBps = rollup.Value, rollup.Value*8/seconds
+			case statsresource.MetricHostV4DownBytes:
+				hostFlow.V4DownBytes, hostFlow.V4DownBps = rollup.Value, rollup.Value*8/seconds
+			case statsresource.MetricHostV6UpBytes:
+				hostFlow.V6UpBytes, hostFlow.V6UpBps = rollup.Value, rollup.Value*8/seconds
+			case statsresource.MetricHostV6DownBytes:
+				hostFlow.V6DownBytes, hostFlow.V6DownBps = rollup.Value, rollup.Value*8/seconds
+			case statsresource.MetricHostConnections:
+				if rollup.Samples != 0 {
+					hostFlow.Connections = uint32(rollup.Value / rollup.Samples)
+				}
+			}
+		}
+	}
+
+	hostFlows := make([]*resource.HostFlow, 0, len(hostFlowMap))
+	for _, hostFlow := range hostFlowMap {
+		hostFlows = append(hostFlows, hostFlow)
+	}
+
+	sort.Slice(hostFlows, func(i, j int) bool {
+		return time.Time(hostFlows[i].Timestamp).Before(time.Time(hostFlows[j].Timestamp))
+	})
+	return hostFlows, nil
+}
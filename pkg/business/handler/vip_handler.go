@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"math"
+	"math/big"
+	"net"
 	"strconv"
 
 	resterror "github.com/zdnscloud/gorest/error"
@@ -10,6 +14,7 @@ import (
 
 	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
 	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
 	pbWeb "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
 	"github.com/trymanytimes/UpdateWeb/pkg/util"
 )
@@ -48,3 +53,76 @@ func (h *VipHandler) List(ctx *restresource.Context) (interface{}, *resterror.AP
 	}
 	return rspips, nil
 }
+
+//GetVIPUsage returns the count of vips assigned to websites as upgrade
+//address and the count of addresses in vip intervals of cluster
+func GetVIPUsage(ctx context.Context, clusterID string) (uint64, uint64, error) {
+	cli := grpcclient.GetGrpcClient()
+	cluster, err := cli.ClusterClient.QryOneCluster(ctx, &pbCluster.ClusterIDReq{ClusterId: clusterID})
+	if err != nil {
+		return 0, 0, fmt.Errorf("grpc service exec QryOneCluster failed: %s", err.Error())
+	}
+
+	var total uint64
+	intervals := cluster.GetSocsInfo().GetIpv6Vip()
+	for _, interval := range intervals {
+		total += vipIntervalSize(interval)
+	}
+
+	groups, err := cli.WebsiteClient.GetRaltGroup(ctx, &pbWeb.GetRaltGroupReq{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("grpc service exec GetRaltGroup failed: %s", err.Error())
+	}
+
+	usedVIPs := make(map[string]struct{})
+	for _, group := range groups.GetGroupList() {
+		if groupClusterID(group) != clusterID {
+			continue
+		}
+
+		rsp, err := cli.WebsiteClient.GetRaltGroupWebsite(ctx, &pbWeb.GetRaltGroupWebsiteReq{StrgroupId: group.GetStrgroupId()})
+		if err != nil {
+			return 0, 0, fmt.Errorf("grpc service exec GetRaltGroupWebsite failed: %s", err.Error())
+		}
+
+		for _, website := range rsp.GetWebsite() {
+			ip := net.ParseIP(website.GetStripAddr())
+			if ip == nil {
+				continue
+			}
+
+			for _, interval := range intervals {
+				if vipIntervalContains(interval, ip) {
+					usedVIPs[ip.String()] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+
+	return uint64(len(usedVIPs)), total, nil
+}
+
+func vipIntervalSize(interval *pbCluster.VipInterval) uint64 {
+	begin, end := net.ParseIP(interval.GetBeginVip()), net.ParseIP(interval.GetEndVip())
+	if begin == nil || end == nil || bytes.Compare(begin.To16(), end.To16()) > 0 {
+		return 0
+	}
+
+	size := new(big.Int).Sub(new(big.Int).SetBytes(end.To16()), new(big.Int).SetBytes(begin.To16()))
+	size.Add(size, big.NewInt(1))
+	if size.IsUint64() == false {
+		return math.MaxUint64
+	}
+
+	return size.Uint64()
+}
+
+func vipIntervalContains(interval *pbCluster.VipInterval, ip net.IP) bool {
+	begin, end := net.ParseIP(interval.GetBeginVip()), net.ParseIP(interval.GetEndVip())
+	if begin == nil || end == nil {
+		return false
+	}
+
+	return bytes.Compare(ip.To16(), begin.To16()) >= 0 && bytes.Compare(ip.To16(), end.To16()) <= 0
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"math"
+	"net"
+	"testing"
+
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+)
+
+func TestVipIntervalSize(t *testing.T) {
+	tests := []struct {
+		begin  string
+		end    string
+		expect uint64
+	}{
+		{"2001:db8::1", "2001:db8::1", 1},
+		{"2001:db8::", "2001:db8::ffff", 65536},
+		{"2001:db8::ffff", "2001:db8::1:0", 2},
+		{"2001:db8::", "2001:db8::ffff:ffff:ffff:fffe", math.MaxUint64},
+		{"2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", math.MaxUint64},
+		{"2001:db8::", "2001:db9::", math.MaxUint64},
+		{"10.0.0.1", "10.0.0.10", 10},
+		{"2001:db8::2", "2001:db8::1", 0},
+		{"2001:db8::1", "invalid", 0},
+		{"", "2001:db8::1", 0},
+	}
+
+	for _, tt := range tests {
+		interval := &pbCluster.VipInterval{BeginVip: tt.begin, EndVip: tt.end}
+		if size := vipIntervalSize(interval); size != tt.expect {
+			t.Errorf("size of [%s, %s] expect %d but get %d", tt.begin, tt.end, tt.expect, size)
+		}
+	}
+}
+
+func TestVipIntervalContains(t *testing.T) {
+	interval := &pbCluster.VipInterval{BeginVip: "2001:db8::ff00", EndVip: "2001:db8::1:00ff"}
+	tests := []struct {
+		ip     string
+		expect bool
+	}{
+		{"2001:db8::ff00", true},
+		{"2001:db8::1:ff", true},
+		{"2001:db8::ffff", true},
+		{"2001:db8::feff", false},
+		{"2001:db8::1:100", false},
+		{"2001:db9::ff00", false},
+		{"10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if contains := vipIntervalContains(interval, net.ParseIP(tt.ip)); contains != tt.expect {
+			t.Errorf("interval contains %s expect %v but get %v", tt.ip, tt.expect, contains)
+		}
+	}
+
+	if vipIntervalContains(&pbCluster.VipInterval{BeginVip: "invalid", EndVip: "2001:db8::1"},
+		net.ParseIP("2001:db8::1")) {
+		t.Errorf("invalid interval should contain nothing")
+	}
+}
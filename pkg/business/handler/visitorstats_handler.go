@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	defaultVisitorStatsDuration = 24 * time.Hour
+	visitorStatsCSVPrefix       = "visitorstats-"
+	csvFileTimeFormat           = "20060102150405"
+	PublicFilePath              = "/public/"
+)
+
+var visitorStatsCSVHeader = []string{"timestamp", "httpVisitors", "neteVisitors", "upgradeRatio"}
+
+type VisitorStatsHandler struct{}
+
+func NewVisitorStatsHandler() *VisitorStatsHandler {
+	return &VisitorStatsHandler{}
+}
+
+func (h *VisitorStatsHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	stats, err := getVisitorStats(ctx.GetFilters())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return []*resource.VisitorStats{stats}, nil
+}
+
+func (h *VisitorStatsHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	stats, err := getVisitorStats(ctx.GetFilters())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return stats, nil
+}
+
+func (h *VisitorStatsHandler) Action(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	switch ctx.Resource.GetAction().Name {
+	case resource.ActionExportCSV:
+		return h.exportCSV(ctx)
+	default:
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("action %s is unknown", ctx.Resource.GetAction().Name))
+	}
+}
+
+func (h *VisitorStatsHandler) exportCSV(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	stats, err := getVisitorStats(ctx.GetFilters())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	var contents [][]string
+	for _, visit := range stats.Visits {
+		contents = append(contents, []string{
+			time.Time(visit.Timestamp).Format(util.TimeFormat),
+			strconv.FormatUint(visit.HttpVisitors, 10),
+			strconv.FormatUint(visit.NeteVisitors, 10),
+			UpgradeRatio(visit.HttpVisitors, visit.NeteVisitors),
+		})
+	}
+
+	fileName := visitorStatsCSVPrefix + time.Now().Format(csvFileTimeFormat)
+	if err := util.GenCSVFile(fmt.Sprintf(util.CSVFilePath, fileName), visitorStatsCSVHeader, contents); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("export visitor stats failed: %s", err.Error()))
+	}
+
+	return &resource.FileInfo{Path: PublicFilePath + fileName + ".csv"}, nil
+}
+
+func getVisitorStats(filters []restresource.Filter) (*resource.VisitorStats, error) {
+	from, to, err := util.GetTimeRangeFromFilters(filters, defaultVisitorStatsDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolution, ok := getResolutionFromFilters(filters); ok {
+		from = resolution.BucketTime(from)
+		visitorData, err := getVisitorDataFromRollups(resolution, from, to)
+		if err != nil {
+			return nil, err
+		}
+		return visitorDataToStats(visitorData, from, to), nil
+	}
+
+	cli := grpcclient.GetGrpcClient()
+	resp, err := cli.MonitorClient.ShowHomePageVisitorData(context.Background(),
+		&pbHomePage.ShowHomePageVisitorDataReq{ClusterId: DefaultClusterID})
+	if err != nil {
+		return nil, fmt.Errorf("grpc service exec ShowHomePageVisitorData failed: %s", err.Error())
+	}
+
+	return visitorDataToStats(resp.GetAtsNeteVisitorNum(), from, to), nil
+}
+
+func visitorDataToStats(visitorData []*pbHomePage.VisitorData, from, to time.Time) *resource.VisitorStats {
+	stats := &resource.VisitorStats{
+		From: restresource.ISOTime(from),
+		To:   restresource.ISOTime(to),
+	}
+	stats.SetID(DefaultClusterID)
+
+	for _, data := range visitorData {
+		timestamp := time.Unix(int64(data.GetTimestamp()), 0)
+		if timestamp.Before(from) || timestamp.After(to) {
+			continue
+		}
+
+		stats.Visits = append(stats.Visits, &resource.VisitorSample{
+			Timestamp:    restresource.ISOTime(timestamp),
+			HttpVisitors: data.GetHttpVisitorNumber(),
+			NeteVisitors: data.GetNeteVisitorNumber(),
+		})
+	}
+
+	sort.Slice(stats.Visits, func(i, j int) bool {
+		return time.Time(stats.Visits[i].Timestamp).Before(time.Time(stats.Visits[j].Timestamp))
+	})
+
+	for _, visit := range stats.Visits {
+		stats.HttpVisitors += visit.HttpVisitors
+		stats.NeteVisitors += visit.NeteVisitors
+		if total := visit.HttpVisitors + visit.NeteVisitors; total > stats.PeakVisitors {
+			stats.PeakVisitors = total
+			stats.PeakTimestamp = visit.Timestamp
+		}
+	}
+
+	stats.TotalVisitors = stats.HttpVisitors + stats.NeteVisitors
+	stats.UpgradeRatio = UpgradeRatio(stats.HttpVisitors, stats.NeteVisitors)
+	return stats
+}
+
+func UpgradeRatio(httpVisitors, neteVisitors uint64) string {
+	if httpVisitors+neteVisitors == 0 {
+		return "0"
+	}
+
+	return fmt.Sprintf("%.4f", float64(neteVisitors)/float64(httpVisitors+neteVisitors))
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+)
+
+func TestUpgradeRatio(t *testing.T) {
+	tests := []struct {
+		httpVisitors uint64
+		neteVisitors uint64
+		expect       string
+	}{
+		{0, 0, "0"},
+		{10, 0, "0.0000"},
+		{0, 10, "1.0000"},
+		{30, 10, "0.2500"},
+		{2, 1, "0.3333"},
+	}
+
+	for _, tt := range tests {
+		if ratio := UpgradeRatio(tt.httpVisitors, tt.neteVisitors); ratio != tt.expect {
+			t.Errorf("ratio of %d http and %d nete visitors expect %s but get %s",
+				tt.httpVisitors, tt.neteVisitors, tt.expect, ratio)
+		}
+	}
+}
+
+func TestVisitorDataToStats(t *testing.T) {
+	from := time.Date(2020, 11, 4, 0, 0, 0, 0, time.Local)
+	to := from.Add(time.Hour)
+	data := func(at time.Time, httpVisitors, neteVisitors uint64) *pbHomePage.VisitorData {
+		return &pbHomePage.VisitorData{
+			Timestamp:         uint64(at.Unix()),
+			HttpVisitorNumber: httpVisitors,
+			NeteVisitorNumber: neteVisitors,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		visitorData   []*pbHomePage.VisitorData
+		samples       int
+		httpVisitors  uint64
+		neteVisitors  uint64
+		peakVisitors  uint64
+		peakTimestamp time.Time
+		upgradeRatio  string
+	}{
+		{
+			name:         "empty",
+			upgradeRatio: "0",
+		},
+		{
+			name: "samples out of range are ignored",
+			visitorData: []*pbHomePage.VisitorData{
+				data(from.Add(-time.Second), 100, 100),
+				data(from, 30, 10),
+				data(to, 10, 30),
+				data(to.Add(time.Second), 100, 100),
+			},
+			samples:       2,
+			httpVisitors:  40,
+			neteVisitors:  40,
+			peakVisitors:  40,
+			peakTimestamp: from,
+			upgradeRatio:  "0.5000",
+		},
+		{
+			name: "unsorted samples",
+			visitorData: []*pbHomePage.VisitorData{
+				data(from.Add(30*time.Minute), 5, 5),
+				data(from.Add(10*time.Minute), 20, 40),
+				data(from.Add(20*time.Minute), 15, 15),
+			},
+			samples:       3,
+			httpVisitors:  40,
+			neteVisitors:  60,
+			peakVisitors:  60,
+			peakTimestamp: from.Add(10 * time.Minute),
+			upgradeRatio:  "0.6000",
+		},
+		{
+			name:         "no visitors",
+			visitorData:  []*pbHomePage.VisitorData{data(from, 0, 0)},
+			samples:      1,
+			upgradeRatio: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		stats := visitorDataToStats(tt.visitorData, from, to)
+		if len(stats.Visits) != tt.samples {
+			t.Errorf("%s: expect %d samples but get %d", tt.name, tt.samples, len(stats.Visits))
+			continue
+		}
+
+		for i := 1; i < len(stats.Visits); i++ {
+			if time.Time(stats.Visits[i].Timestamp).Before(time.Time(stats.Visits[i-1].Timestamp)) {
+				t.Errorf("%s: samples should be in time order", tt.name)
+			}
+		}
+
+		if stats.HttpVisitors != tt.httpVisitors || stats.NeteVisitors != tt.neteVisitors ||
+			stats.TotalVisitors != tt.httpVisitors+tt.neteVisitors {
+			t.Errorf("%s: expect %d http and %d nete visitors but get %d %d total %d", tt.name,
+				tt.httpVisitors, tt.neteVisitors, stats.HttpVisitors, stats.NeteVisitors, stats.TotalVisitors)
+		}
+
+		if stats.PeakVisitors != tt.peakVisitors ||
+			(tt.peakVisitors != 0 && time.Time(stats.PeakTimestamp).Equal(tt.peakTimestamp) == false) {
+			t.Errorf("%s: expect peak %d at %s but get %d at %s", tt.name, tt.peakVisitors, tt.peakTimestamp,
+				stats.PeakVisitors, time.Time(stats.PeakTimestamp))
+		}
+
+		if stats.UpgradeRatio != tt.upgradeRatio {
+			t.Errorf("%s: expect upgrade ratio %s but get %s", tt.name, tt.upgradeRatio, stats.UpgradeRatio)
+		}
+	}
+}
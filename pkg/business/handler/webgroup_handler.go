@@ -7,7 +7,9 @@ import (
 	resterror "github.com/zdnscloud/gorest/error"
 	restresource "github.com/zdnscloud/gorest/resource"
 
+	"github.com/trymanytimes/UpdateWeb/pkg/auth/authentification"
 	"github.com/trymanytimes/UpdateWeb/pkg/business/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/business/rewrite"
 	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
 	pbWeb "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
 )
@@ -22,29 +24,27 @@ func NewWebGroupHandler() *WebGroupHandler {
 	return &WebGroupHandler{}
 }
 
+//checkWebGroupAuthority requires data of web group is visible to the user
+func checkWebGroupAuthority(ctx *restresource.Context, groupID string) *resterror.APIError {
+	if authentification.WebGroupFilter(ctx, groupID) == false {
+		return resterror.NewAPIError(resterror.PermissionDenied, fmt.Sprintf("webgroup %s is not authorized", groupID))
+	}
+
+	return nil
+}
+
 func (h *WebGroupHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	webGroup := ctx.Resource.(*resource.WebGroup)
-	cli := grpcclient.GetGrpcClient()
-	webGroupIDReq := &pbWeb.OptRaltGroupReq{
-		Iopt:               OperTypeCreate,
-		StrgroupId:         webGroup.ID,
-		StrgroupName:       webGroup.Name,
-		StrgroupHrefDomain: webGroup.HrefDomain,
-		ItransformMod:      TransformModType64,
-		StrclusterId:       DefaultClusterID,
+	if err := checkWebGroupAuthority(ctx, webGroup.GetID()); err != nil {
+		return nil, err
 	}
-	webGroupIDReq.FuncSwitcher = &pbWeb.FuncSwitcherInfo{}
-	webGroupIDReq.FuncSwitcher.BreplaceHref = webGroup.UpdateSwithcher.IsReplaceHrefOn
-	webGroupIDReq.FuncSwitcher.BhttpsToHttp = webGroup.UpdateSwithcher.IsHttpsToHttpOn
-	webGroupIDReq.FuncSwitcher.Binet6Cache = webGroup.UpdateSwithcher.IsCacheOn
-	for _, v := range webGroup.Rules {
-		webGroupIDReq.Rule = append(webGroupIDReq.Rule, &pbWeb.RuleInfo{
-			StrruleId:  v.ID,
-			IruleType:  v.RuleType,
-			Strsearch:  v.SearchString,
-			Strreplace: v.ReplaceString,
-		})
+
+	webGroupIDReq, err := webGroupToOptReq(webGroup, OperTypeCreate)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
 	}
+
+	cli := grpcclient.GetGrpcClient()
 	if _, err := cli.WebsiteClient.OptRaltGroup(context.Background(), webGroupIDReq); err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec OptRaltGroup failed: %s", err.Error()))
 	}
@@ -54,6 +54,10 @@ func (h *WebGroupHandler) Create(ctx *restresource.Context) (restresource.Resour
 
 func (h *WebGroupHandler) Delete(ctx *restresource.Context) *resterror.APIError {
 	webGroup := ctx.Resource.(*resource.WebGroup)
+	if err := checkWebGroupAuthority(ctx, webGroup.GetID()); err != nil {
+		return err
+	}
+
 	cli := grpcclient.GetGrpcClient()
 	webGroupIDReq := &pbWeb.OptRaltGroupReq{
 		Iopt:         OperTypeDelete,
@@ -68,9 +72,25 @@ func (h *WebGroupHandler) Delete(ctx *restresource.Context) *resterror.APIError
 
 func (h *WebGroupHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	webGroup := ctx.Resource.(*resource.WebGroup)
+	if err := checkWebGroupAuthority(ctx, webGroup.GetID()); err != nil {
+		return nil, err
+	}
+
+	webGroupIDReq, err := webGroupToOptReq(webGroup, OperTypeModify)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
 	cli := grpcclient.GetGrpcClient()
+	if _, err := cli.WebsiteClient.OptRaltGroup(context.Background(), webGroupIDReq); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec OptRaltGroup failed: %s", err.Error()))
+	}
+	return webGroup, nil
+}
+
+func webGroupToOptReq(webGroup *resource.WebGroup, operType int32) (*pbWeb.OptRaltGroupReq, error) {
 	webGroupIDReq := &pbWeb.OptRaltGroupReq{
-		Iopt:               OperTypeModify,
+		Iopt:               operType,
 		StrgroupId:         webGroup.ID,
 		StrgroupName:       webGroup.Name,
 		StrgroupHrefDomain: webGroup.HrefDomain,
@@ -82,6 +102,11 @@ func (h *WebGroupHandler) Update(ctx *restresource.Context) (restresource.Resour
 	webGroupIDReq.FuncSwitcher.BhttpsToHttp = webGroup.UpdateSwithcher.IsHttpsToHttpOn
 	webGroupIDReq.FuncSwitcher.Binet6Cache = webGroup.UpdateSwithcher.IsCacheOn
 	for _, v := range webGroup.Rules {
+		if err := CheckRuleType(v.RuleType); err != nil {
+			return nil, fmt.Errorf("rule %s is invalid: %s", v.ID, err.Error())
+		}
+
+		v.RuleTypeName = RuleTypeName(v.RuleType)
 		webGroupIDReq.Rule = append(webGroupIDReq.Rule, &pbWeb.RuleInfo{
 			StrruleId:  v.ID,
 			IruleType:  v.RuleType,
@@ -89,45 +114,61 @@ func (h *WebGroupHandler) Update(ctx *restresource.Context) (restresource.Resour
 			Strreplace: v.ReplaceString,
 		})
 	}
-	if _, err := cli.WebsiteClient.OptRaltGroup(context.Background(), webGroupIDReq); err != nil {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec OptRaltGroup failed: %s", err.Error()))
+
+	return webGroupIDReq, nil
+}
+
+func (h *WebGroupHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	if err := checkWebGroupAuthority(ctx, ctx.Resource.GetID()); err != nil {
+		return nil, err
+	}
+
+	webGroup, err := getWebGroup(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
 	}
+
 	return webGroup, nil
 }
 
-func (h *WebGroupHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
-	webGroup := ctx.Resource.(*resource.WebGroup)
+func getWebGroup(groupID string) (*resource.WebGroup, error) {
 	cli := grpcclient.GetGrpcClient()
 	//query wether exists a WebGroup
-	WebGroupIDReq := pbWeb.GetRaltGroupReq{StrgroupId: webGroup.GetID()}
+	WebGroupIDReq := pbWeb.GetRaltGroupReq{StrgroupId: groupID}
 	defaultWebGroup, err := cli.WebsiteClient.GetRaltGroup(context.Background(), &WebGroupIDReq)
 	if err != nil {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec GetRaltGroup failed: %s", err.Error()))
+		return nil, fmt.Errorf("grpc service exec GetRaltGroup failed: %s", err.Error())
 	}
 	if len(defaultWebGroup.GroupList) == 0 {
-		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec GetRaltGroup failed: group for id %s is not exists", webGroup.GetID()))
+		return nil, fmt.Errorf("grpc service exec GetRaltGroup failed: group for id %s is not exists", groupID)
 	}
+
+	return groupInfoToWebGroup(defaultWebGroup.GroupList[0]), nil
+}
+
+func groupInfoToWebGroup(group *pbWeb.GroupInfo) *resource.WebGroup {
 	c := &resource.WebGroup{
-		Name:         defaultWebGroup.GroupList[0].StrgroupName,
-		HrefDomain:   defaultWebGroup.GroupList[0].StrgroupHrefDomain,
-		TransformMod: defaultWebGroup.GroupList[0].ItransformMod,
-		ClusterID:    defaultWebGroup.GroupList[0].StrclusterId,
+		Name:         group.StrgroupName,
+		HrefDomain:   group.StrgroupHrefDomain,
+		TransformMod: group.ItransformMod,
+		ClusterID:    group.StrclusterId,
 	}
-	c.SetID(defaultWebGroup.GroupList[0].StrgroupId)
+	c.SetID(group.StrgroupId)
 	c.UpdateSwithcher = &resource.FuncSwitcherInfo{}
-	c.UpdateSwithcher.IsCacheOn = defaultWebGroup.GroupList[0].FuncSwitcher.Binet6Cache
-	c.UpdateSwithcher.IsHttpsToHttpOn = defaultWebGroup.GroupList[0].FuncSwitcher.BhttpsToHttp
-	c.UpdateSwithcher.IsReplaceHrefOn = defaultWebGroup.GroupList[0].FuncSwitcher.BreplaceHref
-	for _, v := range defaultWebGroup.GroupList[0].Rule {
+	c.UpdateSwithcher.IsCacheOn = group.GetFuncSwitcher().GetBinet6Cache()
+	c.UpdateSwithcher.IsHttpsToHttpOn = group.GetFuncSwitcher().GetBhttpsToHttp()
+	c.UpdateSwithcher.IsReplaceHrefOn = group.GetFuncSwitcher().GetBreplaceHref()
+	for _, rule := range group.Rule {
 		c.Rules = append(c.Rules, &resource.RuleInfo{
-			ID:            v.StrruleId,
-			RuleType:      v.IruleType,
-			SearchString:  v.Strsearch,
-			ReplaceString: v.Strreplace,
+			ID:            rule.StrruleId,
+			RuleType:      rule.IruleType,
+			RuleTypeName:  RuleTypeName(rule.IruleType),
+			SearchString:  rule.Strsearch,
+			ReplaceString: rule.Strreplace,
 		})
 	}
 
-	return c, nil
+	return c
 }
 
 func (h *WebGroupHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
@@ -139,27 +180,84 @@ func (h *WebGroupHandler) List(ctx *restresource.Context) (interface{}, *resterr
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec GetRaltGroup failed: %s", err.Error()))
 	}
 	for _, v := range defaultWebGroups.GroupList {
-		c := &resource.WebGroup{
-			Name:         v.StrgroupName,
-			HrefDomain:   v.StrgroupHrefDomain,
-			TransformMod: v.ItransformMod,
-			ClusterID:    v.StrclusterId,
-		}
-		c.SetID(v.StrgroupId)
-		c.UpdateSwithcher = &resource.FuncSwitcherInfo{}
-		c.UpdateSwithcher.IsCacheOn = v.FuncSwitcher.Binet6Cache
-		c.UpdateSwithcher.IsHttpsToHttpOn = v.FuncSwitcher.BhttpsToHttp
-		c.UpdateSwithcher.IsReplaceHrefOn = v.FuncSwitcher.BreplaceHref
-		for _, rule := range v.Rule {
-			c.Rules = append(c.Rules, &resource.RuleInfo{
-				ID:            rule.StrruleId,
-				RuleType:      rule.IruleType,
-				SearchString:  rule.Strsearch,
-				ReplaceString: rule.Strreplace,
-			})
+		if authentification.WebGroupFilter(ctx, v.GetStrgroupId()) {
+			webGroups = append(webGroups, groupInfoToWebGroup(v))
 		}
-		webGroups = append(webGroups, c)
 	}
 
 	return webGroups, nil
 }
+
+func (h *WebGroupHandler) Action(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	if err := checkWebGroupAuthority(ctx, ctx.Resource.GetID()); err != nil {
+		return nil, err
+	}
+
+	switch ctx.Resource.GetAction().Name {
+	case resource.ActionCheckRules:
+		return h.checkRules(ctx)
+	case resource.ActionSimulate:
+		return h.simulate(ctx)
+	default:
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("action %s is unknown", ctx.Resource.GetAction().Name))
+	}
+}
+
+func (h *WebGroupHandler) checkRules(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	webGroup, err := getWebGroup(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	result, err := checkGroupRulesOnNodes(webGroup)
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("check rules of group %s failed: %s", webGroup.GetID(), err.Error()))
+	}
+
+	return result, nil
+}
+
+func (h *WebGroupHandler) simulate(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	req := ctx.Resource.GetAction().Input.(*resource.SimulateRequest)
+	webGroup, err := getWebGroup(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	result, err := rewrite.Simulate(req.Body, webGroupToRewriteOptions(webGroup, req.BaseURL))
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, fmt.Sprintf("simulate rules of group %s failed: %s", webGroup.GetID(), err.Error()))
+	}
+
+	out := &resource.SimulateResult{Body: result.Body, HrefCount: result.HrefCount}
+	for _, hit := range result.RuleHits {
+		out.RuleHits = append(out.RuleHits, &resource.SimulateRuleHit{RuleID: hit.RuleID, Count: hit.Count})
+	}
+
+	for _, line := range result.Diff {
+		out.Diff = append(out.Diff, &resource.SimulateDiffLine{Type: string(line.Type), LineNo: line.LineNo, Text: line.Text})
+	}
+
+	return out, nil
+}
+
+func webGroupToRewriteOptions(webGroup *resource.WebGroup, baseURL string) rewrite.Options {
+	opts := rewrite.Options{
+		BaseURL:      baseURL,
+		HrefDomain:   webGroup.HrefDomain,
+		TransformMod: rewrite.TransformMod(webGroup.TransformMod),
+		ReplaceHref:  webGroup.UpdateSwithcher.IsReplaceHrefOn,
+		HttpsToHttp:  webGroup.UpdateSwithcher.IsHttpsToHttpOn,
+	}
+	for _, rule := range webGroup.Rules {
+		opts.Rules = append(opts.Rules, rewrite.Rule{
+			ID:      rule.ID,
+			Type:    rewrite.RuleType(rule.RuleType),
+			Search:  rule.SearchString,
+			Replace: rule.ReplaceString,
+		})
+	}
+
+	return opts
+}
@@ -20,8 +20,46 @@ func NewWebsiteHandler() *WebsiteHandler {
 	return &WebsiteHandler{}
 }
 
+//checkWebsiteAuthority requires both parent and group of website are
+//visible to the user, so website can't be moved to other groups
+func checkWebsiteAuthority(ctx *restresource.Context, groupID string) *resterror.APIError {
+	if err := checkWebGroupAuthority(ctx, ctx.Resource.GetParent().GetID()); err != nil {
+		return err
+	}
+
+	if groupID != "" && groupID != ctx.Resource.GetParent().GetID() {
+		return checkWebGroupAuthority(ctx, groupID)
+	}
+
+	return nil
+}
+
+//checkExistingWebsiteAuthority also requires the group which website belongs
+//to is visible to the user, since id of website in url may be of other groups
+func checkExistingWebsiteAuthority(ctx *restresource.Context, groupID string) *resterror.APIError {
+	if err := checkWebsiteAuthority(ctx, groupID); err != nil {
+		return err
+	}
+
+	rsp, err := grpcclient.GetGrpcClient().WebsiteClient.GetRaltSpecWebsite(context.Background(),
+		&pbWeb.GetRaltSpecWebsiteReq{StrdomainId: ctx.Resource.GetID()})
+	if err != nil {
+		return resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("grpc service exec GetRaltSpecWebsite failed: %s", err.Error()))
+	}
+
+	if len(rsp.GetWebsite()) != 0 {
+		return checkWebsiteAuthority(ctx, rsp.GetWebsite()[0].GetStrgroupId())
+	}
+
+	return nil
+}
+
 func (h *WebsiteHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	website := ctx.Resource.(*resource.Website)
+	if err := checkWebsiteAuthority(ctx, website.GroupID); err != nil {
+		return nil, err
+	}
+
 	if err := h.OptRaltWebsite(website, OperTypeCreate); err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("OptRaltWebsite error: %s", err.Error()))
 	}
@@ -63,6 +101,10 @@ func (h *WebsiteHandler) OptRaltWebsite(website *resource.Website, operType int3
 
 func (h *WebsiteHandler) Delete(ctx *restresource.Context) *resterror.APIError {
 	website := ctx.Resource.(*resource.Website)
+	if err := checkExistingWebsiteAuthority(ctx, website.GroupID); err != nil {
+		return err
+	}
+
 	cli := grpcclient.GetGrpcClient()
 	websiteReq := &pbWeb.OptRaltWebsiteReq{Iopt: OperTypeDelete}
 	web := &pbWeb.WebsiteReqInfo{
@@ -78,6 +120,10 @@ func (h *WebsiteHandler) Delete(ctx *restresource.Context) *resterror.APIError {
 
 func (h *WebsiteHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
 	website := ctx.Resource.(*resource.Website)
+	if err := checkExistingWebsiteAuthority(ctx, website.GroupID); err != nil {
+		return nil, err
+	}
+
 	if err := h.OptRaltWebsite(website, OperTypeModify); err != nil {
 		return nil, resterror.NewAPIError(resterror.ServerError, fmt.Sprintf("OptRaltWebsite error: %s", err.Error()))
 	}
@@ -96,6 +142,9 @@ func (h *WebsiteHandler) Get(ctx *restresource.Context) (restresource.Resource,
 	if len(rsp.Website) == 0 {
 		return website, nil
 	}
+	if err := checkWebsiteAuthority(ctx, rsp.GetWebsite()[0].GetStrgroupId()); err != nil {
+		return nil, err
+	}
 	website.GroupID = rsp.GetWebsite()[0].GetStrgroupId()
 	website.SourceDomain = rsp.GetWebsite()[0].GetStrsrcDomain()
 	website.DestDomain = rsp.GetWebsite()[0].GetStrdstDomain()
@@ -115,6 +164,10 @@ func (h *WebsiteHandler) Get(ctx *restresource.Context) (restresource.Resource,
 }
 
 func (h *WebsiteHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	if err := checkWebsiteAuthority(ctx, ""); err != nil {
+		return nil, err
+	}
+
 	var websites []*resource.Website
 	req := pbWeb.GetRaltGroupWebsiteReq{StrgroupId: ctx.Resource.GetParent().GetID()}
 	cli := grpcclient.GetGrpcClient()
@@ -13,3 +13,18 @@ type Cluster struct {
 	LogInfo               LogInfo     `json:"logInfo" rest:"required=true"`
 	Cache                 Cache       `json:"cache" rest:"required=true"`
 }
+
+func (c Cluster) GetActions() []resource.Action {
+	return []resource.Action{
+		resource.Action{
+			Name:   ActionRollingRestart,
+			Input:  &RollingRequest{},
+			Output: &RollingTask{},
+		},
+		resource.Action{
+			Name:   ActionRollingReload,
+			Input:  &RollingRequest{},
+			Output: &RollingTask{},
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package resource
+
+import "github.com/zdnscloud/gorest/resource"
+
+const (
+	WebsiteStatusNormal   = "normal"
+	WebsiteStatusAbnormal = "abnormal"
+)
+
+const (
+	DashboardSectionWebsite = "website"
+	DashboardSectionVisit   = "visit"
+	DashboardSectionTraffic = "clusterTraffic"
+	DashboardSectionCache   = "clusterCache"
+)
+
+// GroupDashboard id is the id of WebGroup, traffic and cache are counted by
+// node, so they are figures of the whole cluster serving the group and named
+// with cluster prefix, groups of one cluster share the same figures
+type GroupDashboard struct {
+	resource.ResourceBase `json:",inline"`
+	GroupName             string          `json:"groupName" rest:"description=readonly"`
+	ClusterID             string          `json:"clusterID" rest:"description=readonly"`
+	Health                WebsiteHealth   `json:"health" rest:"description=readonly"`
+	Visits                uint64          `json:"visits" rest:"description=readonly"`
+	PreviousVisits        uint64          `json:"previousVisits" rest:"description=readonly"`
+	ChangePercent         string          `json:"changePercent" rest:"description=readonly"`
+	WebsiteVisits         []*DomainVisit  `json:"websiteVisits" rest:"description=readonly"`
+	ClusterTraffic        Traffic         `json:"clusterTraffic" rest:"description=readonly"`
+	ClusterCache          CacheStats      `json:"clusterCache" rest:"description=readonly"`
+	Errors                []*SectionError `json:"errors" rest:"description=readonly"`
+}
+
+// WebsiteDashboard id is the id of Website, traffic and cache are figures of
+// the cluster serving the website as GroupDashboard
+type WebsiteDashboard struct {
+	resource.ResourceBase `json:",inline"`
+	GroupID               string               `json:"groupID" rest:"description=readonly"`
+	SourceDomain          string               `json:"sourceDomain" rest:"description=readonly"`
+	Status                string               `json:"status" rest:"description=readonly"`
+	Visits                uint64               `json:"visits" rest:"description=readonly"`
+	PreviousVisits        uint64               `json:"previousVisits" rest:"description=readonly"`
+	ChangePercent         string               `json:"changePercent" rest:"description=readonly"`
+	History               []*DomainVisitSample `json:"history" rest:"description=readonly"`
+	ClusterTraffic        Traffic              `json:"clusterTraffic" rest:"description=readonly"`
+	ClusterCache          CacheStats           `json:"clusterCache" rest:"description=readonly"`
+	Errors                []*SectionError      `json:"errors" rest:"description=readonly"`
+}
+
+type WebsiteHealth struct {
+	Total    uint32 `json:"total"`
+	Normal   uint32 `json:"normal"`
+	Abnormal uint32 `json:"abnormal"`
+}
+
+// Traffic is the bytes transferred in period
+type Traffic struct {
+	V4UpBytes   uint64 `json:"v4UpBytes"`
+	V4DownBytes uint64 `json:"v4DownBytes"`
+	V6UpBytes   uint64 `json:"v6UpBytes"`
+	V6DownBytes uint64 `json:"v6DownBytes"`
+}
+
+type CacheStats struct {
+	Hits     uint64 `json:"hits"`
+	HitRatio string `json:"hitRatio"`
+}
@@ -0,0 +1,18 @@
+package resource
+
+import "github.com/zdnscloud/gorest/resource"
+
+type DomainVisit struct {
+	resource.ResourceBase `json:",inline"`
+	Domain                string               `json:"domain" rest:"description=readonly"`
+	GroupID               string               `json:"groupID" rest:"description=readonly"`
+	Visits                uint64               `json:"visits" rest:"description=readonly"`
+	PreviousVisits        uint64               `json:"previousVisits" rest:"description=readonly"`
+	ChangePercent         string               `json:"changePercent" rest:"description=readonly"`
+	History               []*DomainVisitSample `json:"history" rest:"description=readonly"`
+}
+
+type DomainVisitSample struct {
+	Timestamp resource.ISOTime `json:"timestamp"`
+	Visits    uint64           `json:"visits"`
+}
@@ -0,0 +1,9 @@
+package resource
+
+const (
+	ActionExportCSV = "exportcsv"
+)
+
+type FileInfo struct {
+	Path string `json:"path"`
+}
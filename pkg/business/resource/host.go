@@ -4,9 +4,24 @@ import "github.com/zdnscloud/gorest/resource"
 
 type Host struct {
 	resource.ResourceBase `json:",inline"`
-	V4UpFlow              uint64 `json:"v4UpFlow" rest:"description=readonly"`
-	V4DownFlow            uint64 `json:"v4DownFlow" rest:"description=readonly"`
-	V6UpFlow              uint64 `json:"v6UpFlow" rest:"description=readonly"`
-	V6DownFlow            uint64 `json:"v6DownFlow" rest:"description=readonly"`
-	TimeStamp             uint64 `json:"timeStamp" rest:"description=readonly"`
+	V4UpFlow              uint64      `json:"v4UpFlow" rest:"description=readonly"`
+	V4DownFlow            uint64      `json:"v4DownFlow" rest:"description=readonly"`
+	V6UpFlow              uint64      `json:"v6UpFlow" rest:"description=readonly"`
+	V6DownFlow            uint64      `json:"v6DownFlow" rest:"description=readonly"`
+	Connections           uint32      `json:"connections" rest:"description=readonly"`
+	TimeStamp             uint64      `json:"timeStamp" rest:"description=readonly"`
+	Flows                 []*HostFlow `json:"flows" rest:"description=readonly"`
+}
+
+type HostFlow struct {
+	Timestamp   resource.ISOTime `json:"timestamp"`
+	V4UpBytes   uint64           `json:"v4UpBytes"`
+	V4DownBytes uint64           `json:"v4DownBytes"`
+	V6UpBytes   uint64           `json:"v6UpBytes"`
+	V6DownBytes uint64           `json:"v6DownBytes"`
+	V4UpBps     uint64           `json:"v4UpBps"`
+	V4DownBps   uint64           `json:"v4DownBps"`
+	V6UpBps     uint64           `json:"v6UpBps"`
+	V6DownBps   uint64           `json:"v6DownBps"`
+	Connections uint32           `json:"connections"`
 }
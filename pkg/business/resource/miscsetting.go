@@ -0,0 +1,21 @@
+package resource
+
+import "github.com/zdnscloud/gorest/resource"
+
+type MiscSetting struct {
+	resource.ResourceBase `json:",inline"`
+	Value                 string           `json:"value" rest:"required=true,options=on|off"`
+	Consistent            bool             `json:"consistent" rest:"description=readonly"`
+	Nodes                 []*MiscNodeValue `json:"nodes" rest:"description=readonly"`
+}
+
+type MiscNodeValue struct {
+	HostID     string `json:"hostID"`
+	NodeIP     string `json:"nodeIP"`
+	Value      string `json:"value"`
+	ErrMessage string `json:"errMessage"`
+}
+
+func (m MiscSetting) GetParents() []resource.ResourceKind {
+	return []resource.ResourceKind{Cluster{}, Host{}}
+}
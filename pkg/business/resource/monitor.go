@@ -16,7 +16,22 @@ type HomePage struct {
 	AbnormalNode          uint32 `json:"abnormalNodeCount" rest:"description=readonly"`
 	NodesCount            uint32 `json:"nodesCount" rest:"description=readonly"`
 	//DomainVisits          []DomainVisit `json:"domainVisits" rest:"description=readonly"`
-	GroupVisit []*GroupVisit `json:"roupVisits" rest:"description=readonly"`
+	GroupVisit []*GroupVisit   `json:"roupVisits" rest:"description=readonly"`
+	Errors     []*SectionError `json:"errors" rest:"description=readonly"`
+}
+
+const (
+	HomePageSectionSummary     = "summary"
+	HomePageSectionDomainVisit = "domainVisit"
+	HomePageSectionWebGroup    = "webGroup"
+)
+
+//SectionError marks the section of HomePage failed to load, the other
+//sections are still returned
+type SectionError struct {
+	Section string `json:"section"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
 }
 
 /*type DomainVisit struct {
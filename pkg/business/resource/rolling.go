@@ -0,0 +1,42 @@
+package resource
+
+import (
+	"github.com/zdnscloud/gorest/resource"
+)
+
+const (
+	ActionRollingRestart = "rollingrestart"
+	ActionRollingReload  = "rollingreload"
+)
+
+type RollingState string
+
+const (
+	RollingStateStarted  RollingState = "started"
+	RollingStateExecuted RollingState = "executed"
+	RollingStateHealthy  RollingState = "healthy"
+	RollingStateFailed   RollingState = "failed"
+	RollingStateFinished RollingState = "finished"
+)
+
+type RollingRequest struct {
+	HealthTimeout uint32 `json:"healthTimeout"`
+}
+
+type RollingTask struct {
+	TaskID  string   `json:"taskId"`
+	Action  string   `json:"action"`
+	NodeIPs []string `json:"nodeIps"`
+}
+
+type RollingEvent struct {
+	TaskID    string           `json:"taskId"`
+	Action    string           `json:"action"`
+	HostID    string           `json:"hostId"`
+	NodeIP    string           `json:"nodeIp"`
+	Step      int              `json:"step"`
+	Total     int              `json:"total"`
+	State     RollingState     `json:"state"`
+	Message   string           `json:"message"`
+	Timestamp resource.ISOTime `json:"timestamp"`
+}
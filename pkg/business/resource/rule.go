@@ -0,0 +1,26 @@
+package resource
+
+import "github.com/zdnscloud/gorest/resource"
+
+type Rule struct {
+	resource.ResourceBase `json:",inline"`
+	NodeIP                string `json:"nodeIP" rest:"description=readonly"`
+	RuleType              string `json:"ruleType" rest:"required=true,options=top_level_domain|hex_domain|js_buildin_var|ipv4_addr|email_atsign|no_modify|unhandle_protocol|assist_char|subs_string|rsubs_string|ipv6_addr|just_replace|just_restore"`
+	Search                string `json:"search" rest:"required=true"`
+	Replace               string `json:"replace"`
+	Append                string `json:"append"`
+	RaltDomain            string `json:"raltDomain"`
+}
+
+func (r Rule) GetParents() []resource.ResourceKind {
+	return []resource.ResourceKind{Host{}}
+}
+
+type RuleConsistency struct {
+	HostID         string      `json:"hostID"`
+	NodeIP         string      `json:"nodeIP"`
+	Consistent     bool        `json:"consistent"`
+	InstalledCount int         `json:"installedCount"`
+	MissingRules   []*RuleInfo `json:"missingRules"`
+	ErrMessage     string      `json:"errMessage"`
+}
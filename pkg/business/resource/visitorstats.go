@@ -0,0 +1,31 @@
+package resource
+
+import "github.com/zdnscloud/gorest/resource"
+
+type VisitorStats struct {
+	resource.ResourceBase `json:",inline"`
+	From                  resource.ISOTime `json:"from" rest:"description=readonly"`
+	To                    resource.ISOTime `json:"to" rest:"description=readonly"`
+	HttpVisitors          uint64           `json:"httpVisitors" rest:"description=readonly"`
+	NeteVisitors          uint64           `json:"neteVisitors" rest:"description=readonly"`
+	TotalVisitors         uint64           `json:"totalVisitors" rest:"description=readonly"`
+	UpgradeRatio          string           `json:"upgradeRatio" rest:"description=readonly"`
+	PeakVisitors          uint64           `json:"peakVisitors" rest:"description=readonly"`
+	PeakTimestamp         resource.ISOTime `json:"peakTimestamp" rest:"description=readonly"`
+	Visits                []*VisitorSample `json:"visits" rest:"description=readonly"`
+}
+
+type VisitorSample struct {
+	Timestamp    resource.ISOTime `json:"timestamp"`
+	HttpVisitors uint64           `json:"httpVisitors"`
+	NeteVisitors uint64           `json:"neteVisitors"`
+}
+
+func (v VisitorStats) GetActions() []resource.Action {
+	return []resource.Action{
+		resource.Action{
+			Name:   ActionExportCSV,
+			Output: &FileInfo{},
+		},
+	}
+}
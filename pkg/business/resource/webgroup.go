@@ -21,6 +21,7 @@ type FuncSwitcherInfo struct {
 type RuleInfo struct {
 	ID            string `json:"id" rest:"required=true"`
 	RuleType      int32  `json:"ruleType" rest:"required=true"`
+	RuleTypeName  string `json:"ruleTypeName" rest:"description=readonly"`
 	SearchString  string `json:"searchString" rest:"required=true"`
 	ReplaceString string `json:"replaceString" rest:"required=true"`
 }
@@ -28,3 +29,45 @@ type RuleInfo struct {
 func (wg WebGroup) GetParents() []resource.ResourceKind {
 	return []resource.ResourceKind{Cluster{}}
 }
+
+const (
+	ActionCheckRules = "checkrules"
+	ActionSimulate   = "simulate"
+)
+
+type SimulateRequest struct {
+	Body    string `json:"body" rest:"required=true"`
+	BaseURL string `json:"baseURL" rest:"required=true"`
+}
+
+type SimulateResult struct {
+	Body      string              `json:"body"`
+	HrefCount int                 `json:"hrefCount"`
+	RuleHits  []*SimulateRuleHit  `json:"ruleHits"`
+	Diff      []*SimulateDiffLine `json:"diff"`
+}
+
+type SimulateRuleHit struct {
+	RuleID string `json:"ruleId"`
+	Count  int    `json:"count"`
+}
+
+type SimulateDiffLine struct {
+	Type   string `json:"type"`
+	LineNo int    `json:"lineNo"`
+	Text   string `json:"text"`
+}
+
+func (wg WebGroup) GetActions() []resource.Action {
+	return []resource.Action{
+		resource.Action{
+			Name:   ActionCheckRules,
+			Output: &[]*RuleConsistency{},
+		},
+		resource.Action{
+			Name:   ActionSimulate,
+			Input:  &SimulateRequest{},
+			Output: &SimulateResult{},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package rewrite
+
+import "strings"
+
+type DiffType string
+
+const (
+	DiffTypeDelete DiffType = "delete"
+	DiffTypeInsert DiffType = "insert"
+)
+
+//maxDiffCells limits the lcs table, bigger bodies are compared line by line
+const maxDiffCells = 4000000
+
+type DiffLine struct {
+	Type   DiffType
+	LineNo int
+	Text   string
+}
+
+//Diff returns the lines deleted from origin and inserted into rewritten,
+//LineNo is the line number in origin for delete and in rewritten for insert
+func Diff(origin, rewritten string) []DiffLine {
+	if origin == rewritten {
+		return nil
+	}
+
+	a := strings.Split(origin, "\n")
+	b := strings.Split(rewritten, "\n")
+	if len(a)*len(b) > maxDiffCells {
+		return diffByLine(a, b)
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Type: DiffTypeDelete, LineNo: i + 1, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Type: DiffTypeInsert, LineNo: j + 1, Text: b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Type: DiffTypeDelete, LineNo: i + 1, Text: a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Type: DiffTypeInsert, LineNo: j + 1, Text: b[j]})
+	}
+
+	return lines
+}
+
+func diffByLine(a, b []string) []DiffLine {
+	var lines []DiffLine
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i < len(a) && i < len(b) && a[i] == b[i] {
+			continue
+		}
+
+		if i < len(a) {
+			lines = append(lines, DiffLine{Type: DiffTypeDelete, LineNo: i + 1, Text: a[i]})
+		}
+
+		if i < len(b) {
+			lines = append(lines, DiffLine{Type: DiffTypeInsert, LineNo: i + 1, Text: b[i]})
+		}
+	}
+
+	return lines
+}
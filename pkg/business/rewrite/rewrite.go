@@ -0,0 +1,261 @@
+package rewrite
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type RuleType int32
+
+//values are the same as ate_proto.RuleType
+const (
+	RuleTypeTopLevelDomain   RuleType = 1
+	RuleTypeHexDomain        RuleType = 2
+	RuleTypeJSBuildinVar     RuleType = 3
+	RuleTypeIPv4Addr         RuleType = 4
+	RuleTypeEmailAtsign      RuleType = 5
+	RuleTypeNoModify         RuleType = 6
+	RuleTypeUnhandleProtocol RuleType = 7
+	RuleTypeAssistChar       RuleType = 8
+	RuleTypeSubsString       RuleType = 9
+	RuleTypeRsubsString      RuleType = 10
+	RuleTypeIPv6Addr         RuleType = 11
+	RuleTypeJustReplace      RuleType = 12
+	RuleTypeJustRestore      RuleType = 13
+)
+
+type TransformMod int32
+
+const (
+	TransformModPath   TransformMod = 1
+	TransformModDomain TransformMod = 2
+)
+
+const (
+	schemeHttp  = "http"
+	schemeHttps = "https"
+	maskFormat  = "\x00ralt-mask-%d\x00"
+)
+
+var (
+	linkAttrRegexp = regexp.MustCompile(`(?i)((?:href|src|action)\s*=\s*["'])([^"']+)(["'])`)
+	cssURLRegexp   = regexp.MustCompile(`(?i)(url\(\s*["']?)([^"')\s]+)(["']?\s*\))`)
+)
+
+type Rule struct {
+	ID      string
+	Type    RuleType
+	Search  string
+	Replace string
+}
+
+type Options struct {
+	BaseURL      string
+	HrefDomain   string
+	TransformMod TransformMod
+	ReplaceHref  bool
+	HttpsToHttp  bool
+	Rules        []Rule
+}
+
+type RuleHit struct {
+	RuleID string
+	Count  int
+}
+
+type Result struct {
+	Body      string
+	RuleHits  []RuleHit
+	HrefCount int
+	Diff      []DiffLine
+}
+
+//Simulate rewrites body the way a ralt node would with the given group
+//settings, it never talks to the backend, rules of type HexDomain,
+//JSBuildinVar and AssistChar can't be simulated and are rejected
+func Simulate(body string, opts Options) (*Result, error) {
+	baseURL, err := url.Parse(opts.BaseURL)
+	if err != nil || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid base url %s", opts.BaseURL)
+	}
+
+	if opts.ReplaceHref && opts.HrefDomain == "" {
+		return nil, fmt.Errorf("href domain should not be empty when replace href is on")
+	}
+
+	result := &Result{}
+	masks := make(map[string]string)
+	rewritten := body
+	for _, rule := range opts.Rules {
+		if rule.Search == "" {
+			continue
+		}
+
+		var count int
+		switch rule.Type {
+		case RuleTypeNoModify, RuleTypeUnhandleProtocol:
+			rewritten, count = mask(rewritten, rule.Search, masks)
+		case RuleTypeRsubsString, RuleTypeJustRestore:
+			if rule.Replace != "" {
+				count = strings.Count(rewritten, rule.Replace)
+				rewritten = strings.Replace(rewritten, rule.Replace, rule.Search, -1)
+			}
+		case RuleTypeSubsString, RuleTypeJustReplace:
+			count = strings.Count(rewritten, rule.Search)
+			rewritten = strings.Replace(rewritten, rule.Search, rule.Replace, -1)
+		case RuleTypeTopLevelDomain:
+			rewritten, count = replaceBounded(rewritten, rule.Search, rule.Replace, isDomainChar, true)
+		case RuleTypeIPv4Addr:
+			rewritten, count = replaceBounded(rewritten, rule.Search, rule.Replace, isIPv4Char, false)
+		case RuleTypeIPv6Addr:
+			rewritten, count = replaceBounded(rewritten, rule.Search, rule.Replace, isIPv6Char, false)
+		case RuleTypeEmailAtsign:
+			rewritten, count = replaceBounded(rewritten, "@"+rule.Search, "@"+rule.Replace, isDomainChar, false)
+		default:
+			return nil, fmt.Errorf("rule %s of type %d is unsupported in simulation", rule.ID, rule.Type)
+		}
+		result.RuleHits = append(result.RuleHits, RuleHit{RuleID: rule.ID, Count: count})
+	}
+
+	if opts.ReplaceHref || opts.HttpsToHttp {
+		rewritten, result.HrefCount = rewriteLinks(rewritten, baseURL, opts)
+	}
+
+	for token, origin := range masks {
+		rewritten = strings.Replace(rewritten, token, origin, -1)
+	}
+
+	result.Body = rewritten
+	result.Diff = Diff(body, rewritten)
+	return result, nil
+}
+
+func mask(body, search string, masks map[string]string) (string, int) {
+	count := strings.Count(body, search)
+	if count == 0 {
+		return body, 0
+	}
+
+	token := fmt.Sprintf(maskFormat, len(masks))
+	masks[token] = search
+	return strings.Replace(body, search, token, -1), count
+}
+
+//replaceBounded replaces search which isn't a part of a longer word made of
+//chars accepted by isWordChar, a matched domain may be preceded by a label
+//when allowSubdomain is true, like a.example.com for example.com, a dot
+//following the match is taken as the end of a sentence
+func replaceBounded(body, search, replace string, isWordChar func(byte) bool, allowSubdomain bool) (string, int) {
+	var buf strings.Builder
+	count := 0
+	last := 0
+	for offset := 0; offset < len(body); {
+		i := strings.Index(body[offset:], search)
+		if i == -1 {
+			break
+		}
+
+		start := offset + i
+		end := start + len(search)
+		offset = start + 1
+		if isWordChar(search[0]) && start > 0 && isWordChar(body[start-1]) &&
+			(allowSubdomain == false || body[start-1] != '.') {
+			continue
+		}
+
+		next := end
+		if next < len(body) && body[next] == '.' {
+			next++
+		}
+		if isWordChar(search[len(search)-1]) && next < len(body) && isWordChar(body[next]) {
+			continue
+		}
+
+		buf.WriteString(body[last:start])
+		buf.WriteString(replace)
+		last = end
+		offset = end
+		count++
+	}
+
+	if count == 0 {
+		return body, 0
+	}
+
+	buf.WriteString(body[last:])
+	return buf.String(), count
+}
+
+func isDomainChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.'
+}
+
+func isIPv4Char(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.'
+}
+
+func isIPv6Char(c byte) bool {
+	return c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' || c >= '0' && c <= '9' || c == ':' || c == '.'
+}
+
+func rewriteLinks(body string, baseURL *url.URL, opts Options) (string, int) {
+	var count int
+	replace := func(re *regexp.Regexp, s string) string {
+		return re.ReplaceAllStringFunc(s, func(match string) string {
+			groups := re.FindStringSubmatch(match)
+			if link, ok := rewriteLink(groups[2], baseURL, opts); ok {
+				count++
+				return groups[1] + link + groups[3]
+			}
+
+			return match
+		})
+	}
+
+	body = replace(linkAttrRegexp, body)
+	body = replace(cssURLRegexp, body)
+	return body, count
+}
+
+//rewriteLink resolves link against base url, links to other hosts are moved
+//under href domain, links to base host only have their scheme changed
+func rewriteLink(link string, baseURL *url.URL, opts Options) (string, bool) {
+	if strings.HasPrefix(link, "#") {
+		return link, false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link, false
+	}
+
+	u = baseURL.ResolveReference(u)
+	if u.Scheme != schemeHttp && u.Scheme != schemeHttps {
+		return link, false
+	}
+
+	resolved := u.String()
+	if opts.HttpsToHttp && u.Scheme == schemeHttps {
+		u.Scheme = schemeHttp
+	}
+
+	if opts.ReplaceHref && strings.EqualFold(u.Hostname(), baseURL.Hostname()) == false {
+		switch opts.TransformMod {
+		case TransformModDomain:
+			u.Host = u.Hostname() + "." + opts.HrefDomain
+		default:
+			u.Path = "/" + u.Host + u.Path
+			u.Host = opts.HrefDomain
+		}
+	}
+
+	//link not rewritten keeps its origin form even if it's relative
+	rewritten := u.String()
+	if rewritten == resolved {
+		return link, false
+	}
+
+	return rewritten, true
+}
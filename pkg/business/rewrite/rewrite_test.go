@@ -0,0 +1,140 @@
+package rewrite
+
+import (
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	tests := []struct {
+		body     string
+		opts     Options
+		expected string
+	}{
+		{
+			body:     `a.example.com myexample.com example.com.cn example.com/x example.com.`,
+			opts:     Options{Rules: []Rule{{ID: "r1", Type: RuleTypeTopLevelDomain, Search: "example.com", Replace: "example.cn"}}},
+			expected: `a.example.cn myexample.com example.com.cn example.cn/x example.cn.`,
+		},
+		{
+			body:     `10.0.0.1 110.0.0.1 10.0.0.10 [10.0.0.1]:80`,
+			opts:     Options{Rules: []Rule{{ID: "r1", Type: RuleTypeIPv4Addr, Search: "10.0.0.1", Replace: "2001:db8::1"}}},
+			expected: `2001:db8::1 110.0.0.1 10.0.0.10 [2001:db8::1]:80`,
+		},
+		{
+			body:     `[2001:db8::1]:80 2001:db8::10`,
+			opts:     Options{Rules: []Rule{{ID: "r1", Type: RuleTypeIPv6Addr, Search: "2001:db8::1", Replace: "2001:db8::2"}}},
+			expected: `[2001:db8::2]:80 2001:db8::10`,
+		},
+		{
+			body:     `mail user@example.com or visit www.example.com`,
+			opts:     Options{Rules: []Rule{{ID: "r1", Type: RuleTypeEmailAtsign, Search: "example.com", Replace: "example.cn"}}},
+			expected: `mail user@example.cn or visit www.example.com`,
+		},
+		{
+			body:     `var host = "www.example.com";`,
+			opts:     Options{Rules: []Rule{{ID: "r1", Type: RuleTypeJustReplace, Search: "www.example.com", Replace: "www6.example.com"}}},
+			expected: `var host = "www6.example.com";`,
+		},
+		{
+			body:     `var host = "www.example.com";`,
+			opts:     Options{Rules: []Rule{{ID: "r1", Type: RuleTypeSubsString, Search: "www.example.com", Replace: "www6.example.com"}}},
+			expected: `var host = "www6.example.com";`,
+		},
+		{
+			body:     `var host = "www6.example.com";`,
+			opts:     Options{Rules: []Rule{{ID: "r1", Type: RuleTypeRsubsString, Search: "www.example.com", Replace: "www6.example.com"}}},
+			expected: `var host = "www.example.com";`,
+		},
+		{
+			body: `a.example.com keep.example.com`,
+			opts: Options{Rules: []Rule{
+				{ID: "r1", Type: RuleTypeNoModify, Search: "keep.example.com"},
+				{ID: "r2", Type: RuleTypeSubsString, Search: "example.com", Replace: "example.cn"},
+			}},
+			expected: `a.example.cn keep.example.com`,
+		},
+		{
+			body:     `<a href="https://ext.com/a/b?c=1">x</a><img src="/local.png"><a href="http://www.example.com/self">`,
+			opts:     Options{ReplaceHref: true, HttpsToHttp: true, HrefDomain: "href.example.com", TransformMod: TransformModPath},
+			expected: `<a href="http://href.example.com/ext.com/a/b?c=1">x</a><img src="/local.png"><a href="http://www.example.com/self">`,
+		},
+		{
+			body:     `body { background: url('//cdn.ext.com/bg.png'); }`,
+			opts:     Options{ReplaceHref: true, HrefDomain: "href.example.com", TransformMod: TransformModDomain},
+			expected: `body { background: url('http://cdn.ext.com.href.example.com/bg.png'); }`,
+		},
+		{
+			body:     `<script src="https://ext.com/app.js"></script>`,
+			opts:     Options{HttpsToHttp: true},
+			expected: `<script src="http://ext.com/app.js"></script>`,
+		},
+		{
+			body: `<a href="https://www.example.com/self"><img src="/local.png"><a href="#top"><a href="javascript:void(0)">`,
+			opts: Options{BaseURL: "https://www.example.com/index.html", HttpsToHttp: true},
+			expected: `<a href="http://www.example.com/self"><img src="http://www.example.com/local.png">` +
+				`<a href="#top"><a href="javascript:void(0)">`,
+		},
+		{
+			body:     `<img src="../a.png"><a href="b/c.html">`,
+			opts:     Options{BaseURL: "http://www.example.com/dir/index.html", ReplaceHref: true, HttpsToHttp: true, HrefDomain: "href.example.com"},
+			expected: `<img src="../a.png"><a href="b/c.html">`,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.opts.BaseURL == "" {
+			tt.opts.BaseURL = "http://www.example.com/index.html"
+		}
+		result, err := Simulate(tt.body, tt.opts)
+		if err != nil {
+			t.Errorf("simulate %s failed: %s", tt.body, err.Error())
+			continue
+		}
+
+		if result.Body != tt.expected {
+			t.Errorf("simulate failed: expected %s but get %s", tt.expected, result.Body)
+		}
+	}
+}
+
+func TestSimulateUnsupportedRule(t *testing.T) {
+	for _, typ := range []RuleType{RuleTypeHexDomain, RuleTypeJSBuildinVar, RuleTypeAssistChar, 0} {
+		opts := Options{BaseURL: "http://www.example.com", Rules: []Rule{{ID: "r1", Type: typ, Search: "a", Replace: "b"}}}
+		if _, err := Simulate("a", opts); err == nil {
+			t.Errorf("rule of type %d should be unsupported in simulation", typ)
+		}
+	}
+}
+
+func TestSimulateInvalidOptions(t *testing.T) {
+	if _, err := Simulate("", Options{BaseURL: "www.example.com"}); err == nil {
+		t.Errorf("base url without scheme should be rejected")
+	}
+
+	if _, err := Simulate("", Options{BaseURL: "http://www.example.com", ReplaceHref: true}); err == nil {
+		t.Errorf("replace href without href domain should be rejected")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	lines := Diff("a\nb\nc", "a\nB\nc\nd")
+	expected := []DiffLine{
+		{Type: DiffTypeDelete, LineNo: 2, Text: "b"},
+		{Type: DiffTypeInsert, LineNo: 2, Text: "B"},
+		{Type: DiffTypeInsert, LineNo: 4, Text: "d"},
+	}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("diff failed: expected %+v but get %+v", expected, lines)
+	}
+
+	for i := range lines {
+		if lines[i] != expected[i] {
+			t.Errorf("diff failed: expected %+v but get %+v", expected[i], lines[i])
+		}
+	}
+
+	if lines := Diff("same", "same"); len(lines) != 0 {
+		t.Errorf("diff of same body should be empty but get %+v", lines)
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"strings"
 
 	restdb "github.com/zdnscloud/gorest/db"
 	"github.com/zdnscloud/gorest/resource"
@@ -17,6 +18,51 @@ func RegisterResources(resources ...resource.Resource) {
 	globalResources = append(globalResources, resources...)
 }
 
+//Migration lists columns added to resource after its table was created,
+//restdb only creates missing tables, so these columns are added to existing
+//table with zero value as default to keep old rows readable
+type Migration struct {
+	Resource resource.Resource
+	Columns  []string
+}
+
+var globalMigrations []Migration
+
+func RegisterMigrations(migrations ...Migration) {
+	globalMigrations = append(globalMigrations, migrations...)
+}
+
+//Index is created on table of resource if not exists, since restdb only
+//creates primary key
+type Index struct {
+	Resource resource.Resource
+	Columns  []string
+}
+
+var globalIndexes []Index
+
+func RegisterIndexes(indexes ...Index) {
+	globalIndexes = append(globalIndexes, indexes...)
+}
+
+var columnTypes = map[restdb.Datatype]struct {
+	sqlType      string
+	defaultValue string
+}{
+	restdb.Bool:          {"boolean", "false"},
+	restdb.SmallInt:      {"integer", "0"},
+	restdb.BigInt:        {"bigint", "0"},
+	restdb.SuperInt:      {"numeric", "0"},
+	restdb.Float32:       {"float4", "0"},
+	restdb.String:        {"text", "''"},
+	restdb.Time:          {"timestamp with time zone", "'0001-01-01 00:00:00+00'"},
+	restdb.SmallIntArray: {"integer[]", "'{}'"},
+	restdb.BigIntArray:   {"bigint[]", "'{}'"},
+	restdb.SuperIntArray: {"numeric[]", "'{}'"},
+	restdb.Float32Array:  {"float4[]", "'{}'"},
+	restdb.StringArray:   {"text[]", "'{}'"},
+}
+
 var globalDB restdb.ResourceStore
 
 func GetDB() restdb.ResourceStore {
@@ -29,8 +75,96 @@ func Init(conf *config.DDIControllerConfig) error {
 		return err
 	}
 
+	sqls, err := MigrationSqls(meta, globalMigrations)
+	if err != nil {
+		return err
+	}
+
+	indexSqls, err := IndexSqls(meta, globalIndexes)
+	if err != nil {
+		return err
+	}
+	sqls = append(sqls, indexSqls...)
+
 	globalDB, err = restdb.NewRStore(fmt.Sprintf(ConnStr, conf.DB.User, conf.DB.Password, conf.DB.Host, conf.DB.Port, conf.DB.Name), meta)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return restdb.WithTx(globalDB, func(tx restdb.Transaction) error {
+		for _, sql := range sqls {
+			if _, err := tx.Exec(sql); err != nil {
+				return fmt.Errorf("migrate db with %s failed: %s", sql, err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+//MigrationSqls returns alter table sqls of migrations, type of column is the
+//same as the one restdb creates for the field
+func MigrationSqls(meta *restdb.ResourceMeta, migrations []Migration) ([]string, error) {
+	var sqls []string
+	for _, migration := range migrations {
+		descriptor, err := meta.GetDescriptor(restdb.ResourceDBType(migration.Resource))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, column := range migration.Columns {
+			field, ok := getDescriptorField(descriptor, column)
+			if ok == false {
+				return nil, fmt.Errorf("model %s has no field %s", descriptor.Typ, column)
+			}
+
+			columnType, ok := columnTypes[field.Type]
+			if ok == false {
+				return nil, fmt.Errorf("field %s of model %s has no default value", column, descriptor.Typ)
+			}
+
+			sqls = append(sqls, fmt.Sprintf("alter table %s%s add column if not exists %s %s default %s",
+				restdb.TablePrefix, descriptor.Typ, column, columnType.sqlType, columnType.defaultValue))
+		}
+	}
+
+	return sqls, nil
+}
+
+//IndexSqls returns create index sqls, index is named by table and columns
+func IndexSqls(meta *restdb.ResourceMeta, indexes []Index) ([]string, error) {
+	var sqls []string
+	for _, index := range indexes {
+		descriptor, err := meta.GetDescriptor(restdb.ResourceDBType(index.Resource))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(index.Columns) == 0 {
+			return nil, fmt.Errorf("index of model %s has no column", descriptor.Typ)
+		}
+
+		for _, column := range index.Columns {
+			if _, ok := getDescriptorField(descriptor, column); ok == false {
+				return nil, fmt.Errorf("model %s has no field %s", descriptor.Typ, column)
+			}
+		}
+
+		table := restdb.TablePrefix + string(descriptor.Typ)
+		sqls = append(sqls, fmt.Sprintf("create index if not exists %s_%s_idx on %s (%s)",
+			table, strings.Join(index.Columns, "_"), table, strings.Join(index.Columns, ", ")))
+	}
+
+	return sqls, nil
+}
+
+func getDescriptorField(descriptor *restdb.ResourceDescriptor, name string) (restdb.ResourceField, bool) {
+	for _, field := range descriptor.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+
+	return restdb.ResourceField{}, false
 }
 
 func GetResources(conditions map[string]interface{}, resources interface{}) error {
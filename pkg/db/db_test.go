@@ -0,0 +1,81 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	"github.com/zdnscloud/gorest/resource"
+)
+
+type migratedResource struct {
+	resource.ResourceBase `json:",inline"`
+	Name                  string
+	Enabled               bool
+	Step                  int64
+	Codes                 []string
+	ChangedTime           time.Time
+	Token                 string `db:"-"`
+}
+
+func TestMigrationSqls(t *testing.T) {
+	meta, err := restdb.NewResourceMeta([]resource.Resource{&migratedResource{}})
+	if err != nil {
+		t.Fatalf("create resource meta failed: %s", err.Error())
+	}
+
+	sqls, err := MigrationSqls(meta, []Migration{
+		Migration{Resource: &migratedResource{}, Columns: []string{"enabled", "step", "codes", "changed_time"}},
+	})
+	if err != nil {
+		t.Fatalf("gen migration sqls failed: %s", err.Error())
+	}
+
+	expected := []string{
+		"alter table gr_migrated_resource add column if not exists enabled boolean default false",
+		"alter table gr_migrated_resource add column if not exists step bigint default 0",
+		"alter table gr_migrated_resource add column if not exists codes text[] default '{}'",
+		"alter table gr_migrated_resource add column if not exists changed_time timestamp with time zone default '0001-01-01 00:00:00+00'",
+	}
+	if reflect.DeepEqual(sqls, expected) == false {
+		t.Errorf("migration sqls expected %v but get %v", expected, sqls)
+	}
+
+	for _, column := range []string{"unknown", "token", "Enabled"} {
+		if _, err := MigrationSqls(meta, []Migration{
+			Migration{Resource: &migratedResource{}, Columns: []string{column}}}); err == nil {
+			t.Errorf("migration of column %s should fail", column)
+		}
+	}
+}
+
+func TestIndexSqls(t *testing.T) {
+	meta, err := restdb.NewResourceMeta([]resource.Resource{&migratedResource{}})
+	if err != nil {
+		t.Fatalf("create resource meta failed: %s", err.Error())
+	}
+
+	sqls, err := IndexSqls(meta, []Index{
+		Index{Resource: &migratedResource{}, Columns: []string{"name", "changed_time"}},
+		Index{Resource: &migratedResource{}, Columns: []string{"step"}},
+	})
+	if err != nil {
+		t.Fatalf("gen index sqls failed: %s", err.Error())
+	}
+
+	expected := []string{
+		"create index if not exists gr_migrated_resource_name_changed_time_idx on gr_migrated_resource (name, changed_time)",
+		"create index if not exists gr_migrated_resource_step_idx on gr_migrated_resource (step)",
+	}
+	if reflect.DeepEqual(sqls, expected) == false {
+		t.Errorf("index sqls expected %v but get %v", expected, sqls)
+	}
+
+	for _, columns := range [][]string{nil, []string{"unknown"}, []string{"name", "token"}} {
+		if _, err := IndexSqls(meta, []Index{
+			Index{Resource: &migratedResource{}, Columns: columns}}); err == nil {
+			t.Errorf("index of columns %v should fail", columns)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zdnscloud/cement/log"
+
+	businesshandler "github.com/trymanytimes/UpdateWeb/pkg/business/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+const namespace = "ddi"
+
+var (
+	websitesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "websites"),
+		"Number of upgraded websites by status.", []string{"status"}, nil)
+	vipsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "vips"),
+		"Number of upgrade vips by state.", []string{"state"}, nil)
+	domainVisitsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "domain_visits"),
+		"Visits of domain in the latest sample of backend.", []string{"domain"}, nil)
+	hostFlowBytesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "host_flow_bytes_total"),
+		"Bytes transferred by host.", []string{"host", "family", "direction"}, nil)
+	cacheHitRatioDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "cache_hit_ratio"),
+		"Cache hit ratio of host.", []string{"host"}, nil)
+	scrapeErrorDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "scrape_error"),
+		"1 if the section failed to refresh from backend in the last refresh.", []string{"section"}, nil)
+)
+
+const (
+	sectionWebsite     = "website"
+	sectionVIP         = "vip"
+	sectionDomainVisit = "domainvisit"
+	sectionHostFlow    = "hostflow"
+	sectionCache       = "cache"
+)
+
+type hostFlow struct {
+	v4UpBytes   uint64
+	v4DownBytes uint64
+	v6UpBytes   uint64
+	v6DownBytes uint64
+}
+
+type snapshot struct {
+	normalWebsites   uint32
+	abnormalWebsites uint32
+	usedVIPs         uint64
+	totalVIPs        uint64
+	domainVisits     map[string]uint64
+	hostFlows        map[string]*hostFlow
+	cacheHitRatios   map[string]float64
+	errors           map[string]bool
+}
+
+//businessCollector exports the snapshot refreshed from backend periodically
+//so scraping doesn't call backend
+type businessCollector struct {
+	interval  time.Duration
+	timeout   time.Duration
+	lock      sync.RWMutex
+	snapshot  *snapshot
+	getClient func() *grpcclient.GrpcClient
+	vipUsage  func(context.Context, string) (uint64, uint64, error)
+}
+
+func newBusinessCollector(interval, timeout time.Duration) *businessCollector {
+	return &businessCollector{
+		interval:  interval,
+		timeout:   timeout,
+		snapshot:  &snapshot{},
+		getClient: grpcclient.GetGrpcClient,
+		vipUsage:  businesshandler.GetVIPUsage,
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- websitesDesc
+	ch <- vipsDesc
+	ch <- domainVisitsDesc
+	ch <- hostFlowBytesDesc
+	ch <- cacheHitRatioDesc
+	ch <- scrapeErrorDesc
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.RLock()
+	s := c.snapshot
+	c.lock.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(websitesDesc, prometheus.GaugeValue, float64(s.normalWebsites), "normal")
+	ch <- prometheus.MustNewConstMetric(websitesDesc, prometheus.GaugeValue, float64(s.abnormalWebsites), "abnormal")
+	ch <- prometheus.MustNewConstMetric(vipsDesc, prometheus.GaugeValue, float64(s.usedVIPs), "used")
+	var freeVIPs uint64
+	if s.totalVIPs > s.usedVIPs {
+		freeVIPs = s.totalVIPs - s.usedVIPs
+	}
+	ch <- prometheus.MustNewConstMetric(vipsDesc, prometheus.GaugeValue, float64(freeVIPs), "free")
+
+	for domain, visits := range s.domainVisits {
+		ch <- prometheus.MustNewConstMetric(domainVisitsDesc, prometheus.GaugeValue, float64(visits), domain)
+	}
+
+	for host, flow := range s.hostFlows {
+		ch <- prometheus.MustNewConstMetric(hostFlowBytesDesc, prometheus.CounterValue, float64(flow.v4UpBytes), host, "ipv4", "up")
+		ch <- prometheus.MustNewConstMetric(hostFlowBytesDesc, prometheus.CounterValue, float64(flow.v4DownBytes), host, "ipv4", "down")
+		ch <- prometheus.MustNewConstMetric(hostFlowBytesDesc, prometheus.CounterValue, float64(flow.v6UpBytes), host, "ipv6", "up")
+		ch <- prometheus.MustNewConstMetric(hostFlowBytesDesc, prometheus.CounterValue, float64(flow.v6DownBytes), host, "ipv6", "down")
+	}
+
+	for host, ratio := range s.cacheHitRatios {
+		ch <- prometheus.MustNewConstMetric(cacheHitRatioDesc, prometheus.GaugeValue, ratio, host)
+	}
+
+	for _, section := range []string{sectionWebsite, sectionVIP, sectionDomainVisit, sectionHostFlow, sectionCache} {
+		var value float64
+		if s.errors[section] {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, value, section)
+	}
+}
+
+func (c *businessCollector) run() {
+	c.refresh()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.refresh()
+		}
+	}
+}
+
+//refresh keeps values of the previous snapshot for the section failed
+func (c *businessCollector) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	c.lock.RLock()
+	prev := c.snapshot
+	c.lock.RUnlock()
+
+	s := *prev
+	s.errors = make(map[string]bool)
+	cli := c.getClient()
+	if resp, err := cli.MonitorClient.ShowHomePageData(ctx,
+		&pbHomePage.ShowHomePageDataReq{ClusterId: businesshandler.DefaultClusterID}); err != nil {
+		c.markError(&s, sectionWebsite, fmt.Errorf("grpc service exec ShowHomePageData failed: %s", err.Error()))
+	} else {
+		s.normalWebsites = resp.GetDomainIsNormal()
+		s.abnormalWebsites = resp.GetDomainIsAbnormal()
+	}
+
+	if used, total, err := c.vipUsage(ctx, businesshandler.DefaultClusterID); err != nil {
+		c.markError(&s, sectionVIP, err)
+	} else {
+		s.usedVIPs, s.totalVIPs = used, total
+	}
+
+	if resp, err := cli.MonitorClient.ShowDomainVisitorData(ctx,
+		&pbHomePage.ShowDomainVisitorDataReq{ClusterId: businesshandler.DefaultClusterID}); err != nil {
+		c.markError(&s, sectionDomainVisit, fmt.Errorf("grpc service exec ShowDomainVisitorData failed: %s", err.Error()))
+	} else {
+		s.domainVisits = make(map[string]uint64)
+		for _, domainVisitor := range resp.GetDomainVisitNum() {
+			if nums := domainVisitor.GetVisitHostNumber(); len(nums) != 0 {
+				s.domainVisits[domainVisitor.GetMemberDomain()] = nums[len(nums)-1].GetVisitDomainNum()
+			}
+		}
+	}
+
+	c.refreshHosts(ctx, &s)
+
+	c.lock.Lock()
+	c.snapshot = &s
+	c.lock.Unlock()
+}
+
+func (c *businessCollector) refreshHosts(ctx context.Context, s *snapshot) {
+	cli := c.getClient()
+	devices, err := cli.GetClusterDevices(ctx, businesshandler.DefaultClusterID)
+	if err != nil {
+		c.markError(s, sectionHostFlow, err)
+		c.markError(s, sectionCache, err)
+		return
+	}
+
+	hostFlows := make(map[string]*hostFlow)
+	cacheHitRatios := make(map[string]float64)
+	for _, device := range devices {
+		hostID := device.GetHostId()
+		if resp, err := cli.MonitorClient.ShowHomePageFlowData(ctx,
+			&pbHomePage.ShowHomePageFlowDataReq{DeviceId: hostID}); err != nil {
+			c.markError(s, sectionHostFlow, fmt.Errorf("grpc service exec ShowHomePageFlowData of %s failed: %s", hostID, err.Error()))
+			if flow, ok := s.hostFlows[hostID]; ok {
+				hostFlows[hostID] = flow
+			}
+		} else if flow := latestDeviceFlow(resp.GetUpDowmFlow()); flow != nil {
+			hostFlows[hostID] = &hostFlow{
+				v4UpBytes:   flow.GetCurrentDeviceV4UpBytes(),
+				v4DownBytes: flow.GetCurrentDeviceV4DownBytes(),
+				v6UpBytes:   flow.GetCurrentDeviceV6UpBytes(),
+				v6DownBytes: flow.GetCurrentDeviceV6DownBytes(),
+			}
+		}
+
+		if resp, err := cli.RaltClient.GetRaltStats(ctx,
+			&pbRalt.GetRaltStatsReq{IpAddr: grpcclient.DeviceIP(device)}); err != nil {
+			c.markError(s, sectionCache, fmt.Errorf("grpc service exec GetRaltStats of %s failed: %s", hostID, err.Error()))
+			if ratio, ok := s.cacheHitRatios[hostID]; ok {
+				cacheHitRatios[hostID] = ratio
+			}
+		} else {
+			cacheHitRatios[hostID] = float64(resp.GetCacheHitRatio())
+		}
+	}
+
+	s.hostFlows = hostFlows
+	s.cacheHitRatios = cacheHitRatios
+}
+
+func (c *businessCollector) markError(s *snapshot, section string, err error) {
+	if s.errors[section] == false {
+		log.Warnf("refresh %s metrics failed: %s", section, err.Error())
+	}
+	s.errors[section] = true
+}
+
+func latestDeviceFlow(flows []*pbHomePage.DeviceFlowData) *pbHomePage.DeviceFlowData {
+	var latest *pbHomePage.DeviceFlowData
+	for _, flow := range flows {
+		if flow != nil && (latest == nil || flow.GetTimestamp() > latest.GetTimestamp()) {
+			latest = flow
+		}
+	}
+
+	return latest
+}
@@ -0,0 +1,194 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zdnscloud/cement/log"
+	"google.golang.org/grpc"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+//fakeBackend fails the section whose err is set
+type fakeBackend struct {
+	pbHomePage.AteStatsHomePageClient
+	pbCluster.ClusterManagerClient
+	pbRalt.RaltServiceClient
+	websites     uint32
+	websiteErr   error
+	visits       uint64
+	visitErr     error
+	flowBytes    uint64
+	flowErrHosts map[string]bool
+	ratio        float32
+	cacheErr     error
+	devicesErr   error
+	used         uint64
+	vipErr       error
+}
+
+func (b *fakeBackend) ShowHomePageData(ctx context.Context, in *pbHomePage.ShowHomePageDataReq, opts ...grpc.CallOption) (*pbHomePage.ShowHomePageDataRsp, error) {
+	if b.websiteErr != nil {
+		return nil, b.websiteErr
+	}
+	return &pbHomePage.ShowHomePageDataRsp{DomainIsNormal: b.websites, DomainIsAbnormal: 1}, nil
+}
+
+func (b *fakeBackend) ShowDomainVisitorData(ctx context.Context, in *pbHomePage.ShowDomainVisitorDataReq, opts ...grpc.CallOption) (*pbHomePage.ShowDomainVisitorDataRsp, error) {
+	if b.visitErr != nil {
+		return nil, b.visitErr
+	}
+	return &pbHomePage.ShowDomainVisitorDataRsp{DomainVisitNum: []*pbHomePage.DomainVisitor{
+		&pbHomePage.DomainVisitor{
+			MemberDomain: "a.com",
+			VisitHostNumber: []*pbHomePage.VisitDomainNum{
+				&pbHomePage.VisitDomainNum{VisitDomainNum: 1},
+				&pbHomePage.VisitDomainNum{VisitDomainNum: b.visits},
+			},
+		},
+	}}, nil
+}
+
+func (b *fakeBackend) ShowHomePageFlowData(ctx context.Context, in *pbHomePage.ShowHomePageFlowDataReq, opts ...grpc.CallOption) (*pbHomePage.ShowHomePageFlowDataRsp, error) {
+	if b.flowErrHosts[in.GetDeviceId()] {
+		return nil, errors.New("flow unavailable")
+	}
+	return &pbHomePage.ShowHomePageFlowDataRsp{UpDowmFlow: []*pbHomePage.DeviceFlowData{
+		&pbHomePage.DeviceFlowData{Timestamp: 2, CurrentDeviceV6UpBytes: b.flowBytes},
+		&pbHomePage.DeviceFlowData{Timestamp: 1, CurrentDeviceV6UpBytes: 1},
+	}}, nil
+}
+
+func (b *fakeBackend) QryOneCluster(ctx context.Context, in *pbCluster.ClusterIDReq, opts ...grpc.CallOption) (*pbCluster.ClusterDetailInfoRsp, error) {
+	if b.devicesErr != nil {
+		return nil, b.devicesErr
+	}
+	return &pbCluster.ClusterDetailInfoRsp{SocsInfo: &pbCluster.ClusterBalanceInfo{
+		NodeHost: []*pbCluster.NodeHost{&pbCluster.NodeHost{HostId: "h1"}, &pbCluster.NodeHost{HostId: "h2"}},
+	}}, nil
+}
+
+func (b *fakeBackend) GetDevices(ctx context.Context, in *pbCluster.DeviceIDReq, opts ...grpc.CallOption) (*pbCluster.DevicesRsp, error) {
+	return &pbCluster.DevicesRsp{Device: []*pbCluster.Device{
+		&pbCluster.Device{HostId: "h1", Ipv4Addr: "10.0.0.1"},
+		&pbCluster.Device{HostId: "h2", Ipv4Addr: "10.0.0.2"},
+	}}, nil
+}
+
+func (b *fakeBackend) GetRaltStats(ctx context.Context, in *pbRalt.GetRaltStatsReq, opts ...grpc.CallOption) (*pbRalt.GetRaltStatsRsp, error) {
+	if b.cacheErr != nil {
+		return nil, b.cacheErr
+	}
+	return &pbRalt.GetRaltStatsRsp{CacheHitRatio: b.ratio}, nil
+}
+
+func (b *fakeBackend) vipUsage(ctx context.Context, clusterID string) (uint64, uint64, error) {
+	if b.vipErr != nil {
+		return 0, 0, b.vipErr
+	}
+	return b.used, 10, nil
+}
+
+func newFakeCollector(backend *fakeBackend) *businessCollector {
+	c := newBusinessCollector(0, 0)
+	c.getClient = func() *grpcclient.GrpcClient {
+		return &grpcclient.GrpcClient{
+			ClusterClient: backend,
+			MonitorClient: backend,
+			RaltClient:    backend,
+		}
+	}
+	c.vipUsage = backend.vipUsage
+	return c
+}
+
+func TestRefresh(t *testing.T) {
+	backend := &fakeBackend{websites: 5, visits: 20, flowBytes: 100, ratio: 0.5, used: 3}
+	c := newFakeCollector(backend)
+	c.refresh()
+
+	s := c.snapshot
+	if s.normalWebsites != 5 || s.abnormalWebsites != 1 || s.usedVIPs != 3 || s.totalVIPs != 10 {
+		t.Errorf("unexpected websites %d %d or vips %d %d", s.normalWebsites, s.abnormalWebsites, s.usedVIPs, s.totalVIPs)
+	}
+	if s.domainVisits["a.com"] != 20 {
+		t.Errorf("domain visits should be the latest sample but get %d", s.domainVisits["a.com"])
+	}
+	if len(s.hostFlows) != 2 || s.hostFlows["h1"].v6UpBytes != 100 {
+		t.Errorf("host flows should be the latest sample of each host")
+	}
+	if len(s.cacheHitRatios) != 2 || s.cacheHitRatios["h2"] != 0.5 {
+		t.Errorf("cache hit ratio should be set for each host")
+	}
+	if len(s.errors) != 0 {
+		t.Errorf("no section should fail but get %v", s.errors)
+	}
+}
+
+func TestRefreshKeepsPreviousOnFailure(t *testing.T) {
+	backend := &fakeBackend{websites: 5, visits: 20, flowBytes: 100, ratio: 0.5, used: 3}
+	c := newFakeCollector(backend)
+	c.refresh()
+
+	backend.websites, backend.visits, backend.flowBytes, backend.ratio, backend.used = 6, 30, 200, 0.8, 4
+	backend.websiteErr = errors.New("home page unavailable")
+	backend.vipErr = errors.New("cluster unavailable")
+	backend.flowErrHosts = map[string]bool{"h1": true}
+	backend.cacheErr = errors.New("ralt unavailable")
+	c.refresh()
+
+	s := c.snapshot
+	if s.normalWebsites != 5 || s.usedVIPs != 3 {
+		t.Errorf("websites and vips failed should keep previous values but get %d %d", s.normalWebsites, s.usedVIPs)
+	}
+	if s.domainVisits["a.com"] != 30 {
+		t.Errorf("domain visits refreshed should be updated but get %d", s.domainVisits["a.com"])
+	}
+	if s.hostFlows["h1"].v6UpBytes != 100 || s.hostFlows["h2"].v6UpBytes != 200 {
+		t.Errorf("only flow of host failed should keep previous value but get %d %d",
+			s.hostFlows["h1"].v6UpBytes, s.hostFlows["h2"].v6UpBytes)
+	}
+	if s.cacheHitRatios["h1"] != 0.5 || s.cacheHitRatios["h2"] != 0.5 {
+		t.Errorf("cache hit ratios failed should keep previous values")
+	}
+	for _, section := range []string{sectionWebsite, sectionVIP, sectionHostFlow, sectionCache} {
+		if s.errors[section] == false {
+			t.Errorf("section %s should be marked failed", section)
+		}
+	}
+	if s.errors[sectionDomainVisit] {
+		t.Errorf("section %s should not be marked failed", sectionDomainVisit)
+	}
+
+	expect := `
+# HELP ddi_scrape_error 1 if the section failed to refresh from backend in the last refresh.
+# TYPE ddi_scrape_error gauge
+ddi_scrape_error{section="cache"} 1
+ddi_scrape_error{section="domainvisit"} 0
+ddi_scrape_error{section="hostflow"} 1
+ddi_scrape_error{section="vip"} 1
+ddi_scrape_error{section="website"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expect), "ddi_scrape_error"); err != nil {
+		t.Errorf("unexpected scrape errors: %s", err.Error())
+	}
+
+	backend.devicesErr = errors.New("cluster unavailable")
+	c.refresh()
+	if s := c.snapshot; len(s.hostFlows) != 2 || len(s.cacheHitRatios) != 2 {
+		t.Errorf("hosts should keep previous values when devices failed to load")
+	}
+}
@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+)
+
+const (
+	MetricsPath            = "/metrics"
+	defaultRefreshInterval = 60
+	defaultRefreshTimeout  = 30
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		grpcRequests,
+		grpcErrors,
+		grpcLatency,
+	)
+}
+
+//Run serves metrics on a port separated from api server, it is disabled
+//when no address is configured
+func Run(conf *config.DDIControllerConfig) {
+	if conf.Exporter.Addr == "" {
+		return
+	}
+
+	collector := newBusinessCollector(
+		time.Duration(valueOrDefault(conf.Exporter.RefreshInterval, defaultRefreshInterval))*time.Second,
+		time.Duration(valueOrDefault(conf.Exporter.RefreshTimeout, defaultRefreshTimeout))*time.Second)
+	registry.MustRegister(collector)
+	go collector.run()
+
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	go func() {
+		if err := http.ListenAndServe(conf.Exporter.Addr, mux); err != nil {
+			log.Errorf("metrics exporter listen on %s failed: %s", conf.Exporter.Addr, err.Error())
+		}
+	}()
+}
+
+func valueOrDefault(value, defaultValue uint32) uint32 {
+	if value == 0 {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,45 @@
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_requests_total",
+		Help:      "Total number of grpc calls to backend by method and code.",
+	}, []string{"method", "code"})
+
+	grpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_errors_total",
+		Help:      "Total number of failed grpc calls to backend by method.",
+	}, []string{"method"})
+
+	grpcLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_request_duration_seconds",
+		Help:      "Latency of grpc calls to backend by method.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"method"})
+)
+
+//UnaryClientInterceptor records latency and result of every unary call
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	begin := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	grpcLatency.WithLabelValues(method).Observe(time.Since(begin).Seconds())
+	grpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	if err != nil {
+		grpcErrors.WithLabelValues(method).Inc()
+	}
+
+	return err
+}
@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	method := "/test.Service/Call"
+	invoke := func(err error) error {
+		return UnaryClientInterceptor(context.Background(), method, nil, nil, nil,
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return err
+			})
+	}
+
+	if err := invoke(nil); err != nil {
+		t.Fatalf("interceptor should return result of invoker but get %s", err.Error())
+	}
+	unavailable := status.Error(codes.Unavailable, "backend down")
+	if err := invoke(unavailable); err != unavailable {
+		t.Fatalf("interceptor should return error of invoker but get %v", err)
+	}
+	invoke(unavailable)
+
+	if count := testutil.ToFloat64(grpcRequests.WithLabelValues(method, codes.OK.String())); count != 1 {
+		t.Errorf("expect 1 succeed request but get %v", count)
+	}
+	if count := testutil.ToFloat64(grpcRequests.WithLabelValues(method, codes.Unavailable.String())); count != 2 {
+		t.Errorf("expect 2 unavailable requests but get %v", count)
+	}
+	if count := testutil.ToFloat64(grpcErrors.WithLabelValues(method)); count != 2 {
+		t.Errorf("expect 2 errors but get %v", count)
+	}
+	if count := testutil.CollectAndCount(grpcLatency); count != 1 {
+		t.Errorf("expect latency of 1 method but get %d", count)
+	}
+}
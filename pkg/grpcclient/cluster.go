@@ -0,0 +1,60 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+
+	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+)
+
+func (c *GrpcClient) GetClusterDevices(ctx context.Context, clusterID string) ([]*pbCluster.Device, error) {
+	cluster, err := c.ClusterClient.QryOneCluster(ctx, &pbCluster.ClusterIDReq{ClusterId: clusterID})
+	if err != nil {
+		return nil, fmt.Errorf("grpc service exec QryOneCluster failed: %s", err.Error())
+	}
+
+	var hostIDs []string
+	for _, nodeHost := range cluster.GetSocsInfo().GetNodeHost() {
+		hostIDs = append(hostIDs, nodeHost.GetHostId())
+	}
+
+	if len(hostIDs) == 0 {
+		return nil, nil
+	}
+
+	return c.GetDevices(ctx, hostIDs...)
+}
+
+func (c *GrpcClient) GetDevices(ctx context.Context, hostIDs ...string) ([]*pbCluster.Device, error) {
+	devices, err := c.ClusterClient.GetDevices(ctx, &pbCluster.DeviceIDReq{HostId: hostIDs})
+	if err != nil {
+		return nil, fmt.Errorf("grpc service exec GetDevices failed: %s", err.Error())
+	}
+
+	return devices.GetDevice(), nil
+}
+
+func (c *GrpcClient) GetDeviceIP(ctx context.Context, hostID string) (string, error) {
+	devices, err := c.GetDevices(ctx, hostID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, device := range devices {
+		if device.GetHostId() == hostID {
+			if ip := DeviceIP(device); ip != "" {
+				return ip, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no management address found for host %s", hostID)
+}
+
+func DeviceIP(device *pbCluster.Device) string {
+	if device.GetIpv4Addr() != "" {
+		return device.GetIpv4Addr()
+	}
+
+	return device.GetIpv6Addr()
+}
@@ -5,6 +5,7 @@ import (
 
 	pbMonitor "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
 	pbCluster "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_cluster"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
 	pbWebsite "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
 )
 
@@ -12,6 +13,7 @@ type GrpcClient struct {
 	ClusterClient pbCluster.ClusterManagerClient
 	WebsiteClient pbWebsite.RaltConfServClient
 	MonitorClient pbMonitor.AteStatsHomePageClient
+	RaltClient    pbRalt.RaltServiceClient
 }
 
 var grpcClient *GrpcClient
@@ -21,6 +23,7 @@ func NewGrpcClient(conn *grpc.ClientConn) {
 		ClusterClient: pbCluster.NewClusterManagerClient(conn),
 		WebsiteClient: pbWebsite.NewRaltConfServClient(conn),
 		MonitorClient: pbMonitor.NewAteStatsHomePageClient(conn),
+		RaltClient:    pbRalt.NewRaltServiceClient(conn),
 	}
 }
 
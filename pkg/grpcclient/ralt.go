@@ -0,0 +1,79 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+const (
+	RaltStatusRunning = uint32(1)
+	RaltResultSucceed = uint32(0)
+)
+
+func (c *GrpcClient) ExecRaltCmd(ctx context.Context, nodeIP string, cmd pbRalt.CommandType) error {
+	rsp, err := c.RaltClient.ExecCmd(ctx, &pbRalt.ExecCmdReq{IpAddr: nodeIP, Cmd: cmd})
+	if err != nil {
+		return fmt.Errorf("grpc service exec ExecCmd %s failed: %s", cmd.String(), err.Error())
+	}
+
+	if rsp.GetResult() != RaltResultSucceed {
+		return fmt.Errorf("exec %s on node %s failed with result %d", cmd.String(), nodeIP, rsp.GetResult())
+	}
+
+	return nil
+}
+
+//RaltStatusWatcher reads the status stream of node opened before a command
+//is executed, so status changed by the command isn't missed
+type RaltStatusWatcher struct {
+	nodeIP string
+	stream pbRalt.RaltService_GetRaltStatusClient
+}
+
+//WatchRaltStatus ctx bounds the whole watch, it should carry a deadline or
+//WaitRunning may block forever
+func (c *GrpcClient) WatchRaltStatus(ctx context.Context, nodeIP string) (*RaltStatusWatcher, error) {
+	stream, err := c.RaltClient.GetRaltStatus(ctx, &pbRalt.RaltStatusReq{IpAddr: nodeIP})
+	if err != nil {
+		return nil, fmt.Errorf("grpc service exec GetRaltStatus failed: %s", err.Error())
+	}
+
+	return &RaltStatusWatcher{nodeIP: nodeIP, stream: stream}, nil
+}
+
+//WaitRunning returns when ralt reports running, if restarted is true running
+//reported before ralt stops is skipped since it's the process being restarted
+func (w *RaltStatusWatcher) WaitRunning(restarted bool) error {
+	stopped := restarted == false
+	for {
+		status, err := w.stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("status stream of node %s closed before ralt is running", w.nodeIP)
+		} else if err != nil {
+			return fmt.Errorf("receive status of node %s failed: %s", w.nodeIP, err.Error())
+		}
+
+		if status.GetStatus() != RaltStatusRunning {
+			stopped = true
+		} else if stopped {
+			return nil
+		}
+	}
+}
+
+func (c *GrpcClient) GetRaltStatus(ctx context.Context, nodeIP string) (uint32, error) {
+	stream, err := c.RaltClient.GetRaltStatus(ctx, &pbRalt.RaltStatusReq{IpAddr: nodeIP})
+	if err != nil {
+		return 0, fmt.Errorf("grpc service exec GetRaltStatus failed: %s", err.Error())
+	}
+
+	status, err := stream.Recv()
+	if err != nil {
+		return 0, fmt.Errorf("receive status of node %s failed: %s", nodeIP, err.Error())
+	}
+
+	return status.GetStatus(), nil
+}
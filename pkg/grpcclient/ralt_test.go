@@ -0,0 +1,63 @@
+package grpcclient
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+)
+
+type fakeStatusStream struct {
+	grpc.ClientStream
+	statuses []uint32
+	err      error
+	received int
+}
+
+func (s *fakeStatusStream) Recv() (*pbRalt.RaltStatus, error) {
+	if s.received == len(s.statuses) {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+
+	status := s.statuses[s.received]
+	s.received += 1
+	return &pbRalt.RaltStatus{Status: status}, nil
+}
+
+func TestWaitRunning(t *testing.T) {
+	stopped := RaltStatusRunning + 1
+	tests := []struct {
+		name      string
+		statuses  []uint32
+		err       error
+		restarted bool
+		succeed   bool
+		received  int
+	}{
+		{"restarted", []uint32{RaltStatusRunning, RaltStatusRunning, stopped, stopped, RaltStatusRunning, stopped}, nil, true, true, 5},
+		{"restarted before stopped", []uint32{stopped, RaltStatusRunning}, nil, true, true, 2},
+		{"never stopped", []uint32{RaltStatusRunning, RaltStatusRunning}, nil, true, false, 2},
+		{"never running again", []uint32{RaltStatusRunning, stopped}, nil, true, false, 2},
+		{"stream broken", []uint32{stopped}, errors.New("deadline exceeded"), true, false, 1},
+		{"reloaded", []uint32{RaltStatusRunning, stopped}, nil, false, true, 1},
+		{"reloaded after stopped", []uint32{stopped, RaltStatusRunning}, nil, false, true, 2},
+	}
+
+	for _, tt := range tests {
+		stream := &fakeStatusStream{statuses: tt.statuses, err: tt.err}
+		watcher := &RaltStatusWatcher{nodeIP: "10.0.0.1", stream: stream}
+		if err := watcher.WaitRunning(tt.restarted); (err == nil) != tt.succeed {
+			t.Errorf("%s: expect succeed %v but get err %v", tt.name, tt.succeed, err)
+		}
+
+		if stream.received != tt.received {
+			t.Errorf("%s: expect %d status received but get %d", tt.name, tt.received, stream.received)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	restresource "github.com/zdnscloud/gorest/resource"
 
 	authhandler "github.com/trymanytimes/UpdateWeb/pkg/auth/handler"
+	authresource "github.com/trymanytimes/UpdateWeb/pkg/auth/resource"
 	"github.com/trymanytimes/UpdateWeb/pkg/db"
 	"github.com/trymanytimes/UpdateWeb/pkg/log/resource"
 	"github.com/trymanytimes/UpdateWeb/pkg/util"
@@ -110,6 +111,11 @@ func (h *AuditLogHandler) AuditLoggerHandler() gorest.EndHandlerFunc {
 			Expire:       time.Now().AddDate(0, 0, h.defaultAuditLogValidPeriod),
 		}
 
+		if apiToken, ok := ctx.Get(authresource.AuthApiToken); ok {
+			auditLog.ApiTokenId = apiToken.(*authresource.ApiToken).GetID()
+			auditLog.ApiTokenName = apiToken.(*authresource.ApiToken).Name
+		}
+
 		if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
 			_, err := tx.Insert(auditLog)
 			return err
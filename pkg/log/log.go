@@ -5,6 +5,7 @@ import (
 	"github.com/zdnscloud/gorest"
 	restresource "github.com/zdnscloud/gorest/resource"
 
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
 	"github.com/trymanytimes/UpdateWeb/pkg/log/handler"
 	"github.com/trymanytimes/UpdateWeb/pkg/log/resource"
 )
@@ -28,3 +29,10 @@ func PersistentResources() []restresource.Resource {
 		&resource.AuditLog{},
 	}
 }
+
+//Migrations returns columns added to tables of old version
+func Migrations() []db.Migration {
+	return []db.Migration{
+		db.Migration{Resource: &resource.AuditLog{}, Columns: []string{"api_token_id", "api_token_name"}},
+	}
+}
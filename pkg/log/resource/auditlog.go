@@ -10,6 +10,8 @@ import (
 type AuditLog struct {
 	restresource.ResourceBase `json:",inline"`
 	Username                  string    `json:"username" rest:"description=readonly"`
+	ApiTokenId                string    `json:"apiTokenId" rest:"description=readonly"`
+	ApiTokenName              string    `json:"apiTokenName" rest:"description=readonly"`
 	SourceIp                  string    `json:"sourceIp" rest:"description=readonly"`
 	Method                    string    `json:"method" rest:"description=readonly"`
 	ResourceKind              string    `json:"resourceKind" rest:"description=readonly"`
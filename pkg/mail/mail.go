@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type Message struct {
+	To          []string
+	Subject     string
+	HTMLBody    string
+	Attachments []string
+}
+
+func (c *Config) Validate() error {
+	if c.Host == "" || c.Port == 0 {
+		return fmt.Errorf("mail server is not configured")
+	}
+
+	if c.From == "" {
+		return fmt.Errorf("mail sender is not configured")
+	}
+
+	return nil
+}
+
+func Send(conf *Config, msg *Message) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	if len(msg.To) == 0 {
+		return fmt.Errorf("mail %s has no recipient", msg.Subject)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", conf.From)
+	m.SetHeader("To", msg.To...)
+	m.SetHeader("Subject", msg.Subject)
+	m.SetBody("text/html", msg.HTMLBody)
+	for _, attachment := range msg.Attachments {
+		m.Attach(attachment)
+	}
+
+	if err := gomail.NewDialer(conf.Host, conf.Port, conf.Username, conf.Password).DialAndSend(m); err != nil {
+		return fmt.Errorf("send mail %s to %v failed: %s", msg.Subject, msg.To, err.Error())
+	}
+
+	return nil
+}
+
+//SendWithRetry waits backoff before the first retry and doubles it after
+//each failure, invalid config or message is not retried
+func SendWithRetry(conf *Config, msg *Message, attempts int, backoff time.Duration) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i != 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		if err = Send(conf, msg); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s after %d attempts", err.Error(), attempts)
+}
@@ -0,0 +1,95 @@
+package mail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/mail/mailtest"
+)
+
+func TestSend(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("new smtp stub failed: %s", err.Error())
+	}
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "mail")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	attachment := filepath.Join(dir, "report.csv")
+	if err := ioutil.WriteFile(attachment, []byte("domain,visits\n"), 0644); err != nil {
+		t.Fatalf("write attachment failed: %s", err.Error())
+	}
+
+	conf := &Config{Host: server.Host(), Port: server.Port(), From: "ddi@example.com"}
+	if err := Send(conf, &Message{
+		To:          []string{"a@example.com", "b@example.com"},
+		Subject:     "monthly report",
+		HTMLBody:    "<p>report</p>",
+		Attachments: []string{attachment},
+	}); err != nil {
+		t.Fatalf("send mail failed: %s", err.Error())
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expect 1 mail but get %d", len(messages))
+	}
+
+	if messages[0].From != "ddi@example.com" || len(messages[0].To) != 2 {
+		t.Errorf("unexpected envelope from %s to %v", messages[0].From, messages[0].To)
+	}
+
+	for _, s := range []string{"Subject: monthly report", "<p>report</p>", "report.csv"} {
+		if strings.Contains(messages[0].Data, s) == false {
+			t.Errorf("mail data should contain %s", s)
+		}
+	}
+}
+
+func TestSendWithoutServer(t *testing.T) {
+	if err := Send(&Config{From: "ddi@example.com"}, &Message{To: []string{"a@example.com"}}); err == nil {
+		t.Errorf("send mail without server should fail")
+	}
+
+	if err := Send(&Config{Host: "127.0.0.1", Port: 25, From: "ddi@example.com"}, &Message{}); err == nil {
+		t.Errorf("send mail without recipient should fail")
+	}
+}
+
+func TestSendWithRetry(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("new smtp stub failed: %s", err.Error())
+	}
+
+	conf := &Config{Host: server.Host(), Port: server.Port(), From: "ddi@example.com"}
+	msg := &Message{To: []string{"a@example.com"}, Subject: "alarm", HTMLBody: "<p>alarm</p>"}
+	if err := SendWithRetry(conf, msg, 3, time.Millisecond); err != nil {
+		t.Fatalf("send mail failed: %s", err.Error())
+	}
+
+	if len(server.Messages()) != 1 {
+		t.Errorf("expect 1 mail but get %d", len(server.Messages()))
+	}
+
+	server.Close()
+	begin := time.Now()
+	if err := SendWithRetry(conf, msg, 3, 10*time.Millisecond); err == nil {
+		t.Fatalf("send mail to closed server should fail")
+	} else if strings.Contains(err.Error(), "after 3 attempts") == false {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+
+	if time.Since(begin) < 30*time.Millisecond {
+		t.Errorf("send mail should back off between attempts")
+	}
+}
@@ -0,0 +1,144 @@
+package mailtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Server is a SMTP stub listening on loopback which keeps the mails it
+//received, it supports no authentication and no TLS
+type Server struct {
+	listener net.Listener
+	lock     sync.Mutex
+	messages []*Message
+	wg       sync.WaitGroup
+}
+
+type Message struct {
+	From string
+	To   []string
+	Data string
+}
+
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen smtp stub failed: %s", err.Error())
+	}
+
+	s := &Server{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *Server) Messages() []*Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*Message(nil), s.messages...)
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	reply := func(code int, msg string) error {
+		return tc.PrintfLine("%s %s", strconv.Itoa(code), msg)
+	}
+
+	if err := reply(220, "mailtest ESMTP"); err != nil {
+		return
+	}
+
+	msg := &Message{}
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO", "NOOP":
+			err = reply(250, "mailtest")
+		case "RSET":
+			msg = &Message{}
+			err = reply(250, "OK")
+		case "MAIL":
+			msg.From = addressOf(line)
+			err = reply(250, "OK")
+		case "RCPT":
+			msg.To = append(msg.To, addressOf(line))
+			err = reply(250, "OK")
+		case "DATA":
+			if err = reply(354, "end data with <CR><LF>.<CR><LF>"); err != nil {
+				return
+			}
+
+			data, readErr := ioutil.ReadAll(tc.DotReader())
+			if readErr != nil {
+				return
+			}
+
+			msg.Data = string(data)
+			s.lock.Lock()
+			s.messages = append(s.messages, msg)
+			s.lock.Unlock()
+			msg = &Message{}
+			err = reply(250, "OK")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			err = reply(502, "command not implemented")
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func addressOf(line string) string {
+	if i := strings.Index(line, "<"); i >= 0 {
+		if j := strings.Index(line[i:], ">"); j > 0 {
+			return line[i+1 : i+j]
+		}
+	}
+
+	return ""
+}
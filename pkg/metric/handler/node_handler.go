@@ -17,6 +17,7 @@ import (
 	"github.com/trymanytimes/UpdateWeb/config"
 	"github.com/trymanytimes/UpdateWeb/pkg/db"
 	"github.com/trymanytimes/UpdateWeb/pkg/metric/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
 )
 
 var (
@@ -28,8 +29,6 @@ var (
 	schema          = "http://"
 )
 
-const DefaultPeriodValue = "6"
-
 type NodeHandler struct {
 	prometheusAddr string
 	exportPort     int
@@ -42,24 +41,7 @@ type TimePeriodParams struct {
 }
 
 func getTimePeriodParamFromFilter(filters []restresource.Filter) *TimePeriodParams {
-	periodStr := getPeriodFromFilters(filters)
-	period, _ := strconv.Atoi(periodStr)
-
-	return genTimePeriodParams(period)
-}
-func getPeriodFromFilters(filters []restresource.Filter) string {
-	for _, filter := range filters {
-		if filter.Name == "period" && filter.Modifier == restresource.Eq {
-			for _, value := range filter.Values {
-				switch value {
-				case "6", "12", "24", "168", "720", "2160":
-					return value
-				}
-			}
-		}
-	}
-
-	return DefaultPeriodValue
+	return genTimePeriodParams(util.GetPeriodFromFilters(filters))
 }
 
 func genTimePeriodParams(period int) *TimePeriodParams {
@@ -9,6 +9,7 @@ import (
 	restresource "github.com/zdnscloud/gorest/resource"
 
 	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
 	"github.com/trymanytimes/UpdateWeb/pkg/metric/handler"
 	"github.com/trymanytimes/UpdateWeb/pkg/metric/resource"
 )
@@ -27,12 +28,24 @@ func RegisterHandler(apiServer *gorest.Server, router gin.IRoutes) error {
 	return nil
 }
 
+func Run(conf *config.DDIControllerConfig) {
+	go NewRaltPoller(conf).Run()
+}
+
 func PersistentResources() []restresource.Resource {
 	return []restresource.Resource{
 		&resource.Node{},
 	}
 }
 
+//Migrations returns columns added to tables of old version
+func Migrations() []db.Migration {
+	return []db.Migration{
+		db.Migration{Resource: &resource.Node{}, Columns: []string{"ralt_is_alive", "ralt_status_change_time",
+			"ralt_check_time"}},
+	}
+}
+
 //for gen api doc
 func RegistHandler(schemas restresource.SchemaManager) {
 	schemas.MustImport(&Version, resource.Node{}, &handler.NodeHandler{})
@@ -0,0 +1,20 @@
+package metric
+
+import (
+	"testing"
+
+	restdb "github.com/zdnscloud/gorest/db"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+)
+
+func TestMigrations(t *testing.T) {
+	meta, err := restdb.NewResourceMeta(PersistentResources())
+	if err != nil {
+		t.Fatalf("create resource meta failed: %s", err.Error())
+	}
+
+	if _, err := db.MigrationSqls(meta, Migrations()); err != nil {
+		t.Errorf("migrations of metric don't match its resources: %s", err.Error())
+	}
+}
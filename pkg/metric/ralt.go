@@ -0,0 +1,143 @@
+package metric
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+	restdb "github.com/zdnscloud/gorest/db"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	businesshandler "github.com/trymanytimes/UpdateWeb/pkg/business/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	"github.com/trymanytimes/UpdateWeb/pkg/metric/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/metric/resource"
+)
+
+const (
+	defaultRaltCheckInterval = 20 * time.Second
+	raltCheckConcurrency     = 8
+)
+
+type raltStatusGetter func(ctx context.Context, nodeIP string) (uint32, error)
+
+type raltState struct {
+	node    *resource.Node
+	isAlive bool
+}
+
+//RaltPoller checks ralt of cluster devices periodically, only the nodes
+//registered by node monitor are updated
+type RaltPoller struct {
+	interval time.Duration
+}
+
+func NewRaltPoller(conf *config.DDIControllerConfig) *RaltPoller {
+	interval := defaultRaltCheckInterval
+	if conf.MonitorNode.RaltCheckInterval > 0 {
+		interval = time.Duration(conf.MonitorNode.RaltCheckInterval) * time.Second
+	}
+
+	return &RaltPoller{interval: interval}
+}
+
+func (p *RaltPoller) Run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		//only the master controller updates nodes like node monitor
+		if config.GetConfig().Server.Master == "" {
+			p.poll()
+		}
+	}
+}
+
+func (p *RaltPoller) poll() {
+	cli := grpcclient.GetGrpcClient()
+	if cli == nil {
+		return
+	}
+
+	var nodes []*resource.Node
+	if err := db.GetResources(map[string]interface{}{}, &nodes); err != nil {
+		log.Warnf("list nodes failed: %s", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+	defer cancel()
+	devices, err := cli.GetClusterDevices(ctx, businesshandler.DefaultClusterID)
+	if err != nil {
+		log.Warnf("list cluster devices failed: %s", err.Error())
+		return
+	}
+
+	var ips []string
+	for _, device := range devices {
+		ips = append(ips, grpcclient.DeviceIP(device))
+	}
+
+	now := time.Now()
+	for _, state := range checkRaltAlive(ctx, nodes, ips, cli.GetRaltStatus) {
+		if err := updateRaltAlive(state, now); err != nil {
+			log.Warnf("update ralt state of node %s failed: %s", state.node.GetID(), err.Error())
+		}
+	}
+}
+
+//checkRaltAlive gets ralt status of devices which are known nodes, at most
+//raltCheckConcurrency calls are made at the same time
+func checkRaltAlive(ctx context.Context, nodes []*resource.Node, ips []string, getStatus raltStatusGetter) []*raltState {
+	nodeMap := make(map[string]*resource.Node, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.GetID()] = node
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var states []*raltState
+	sem := make(chan struct{}, raltCheckConcurrency)
+	for _, ip := range ips {
+		node, ok := nodeMap[ip]
+		if ok == false {
+			log.Debugf("skip ralt status of device %s which isn't a registered node", ip)
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(ip string, node *resource.Node) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			status, err := getStatus(ctx, ip)
+			if err != nil {
+				log.Debugf("get ralt status of node %s failed: %s", ip, err.Error())
+			}
+
+			lock.Lock()
+			states = append(states, &raltState{node: node, isAlive: err == nil && status == grpcclient.RaltStatusRunning})
+			lock.Unlock()
+		}(ip, node)
+	}
+
+	wg.Wait()
+	return states
+}
+
+func updateRaltAlive(state *raltState, now time.Time) error {
+	attrs := map[string]interface{}{"ralt_check_time": now}
+	if state.node.RaltIsAlive != state.isAlive || state.node.RaltStatusChangeTime.IsZero() {
+		attrs["ralt_is_alive"] = state.isAlive
+		attrs["ralt_status_change_time"] = now
+	}
+
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Update(handler.TableNode, attrs, map[string]interface{}{restdb.IDField: state.node.GetID()})
+		return err
+	})
+}
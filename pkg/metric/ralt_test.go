@@ -0,0 +1,92 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	"github.com/trymanytimes/UpdateWeb/pkg/metric/resource"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger(log.Error)
+	os.Exit(m.Run())
+}
+
+func newTestNode(ip string) *resource.Node {
+	node := &resource.Node{Ip: ip}
+	node.SetID(ip)
+	return node
+}
+
+func TestCheckRaltAlive(t *testing.T) {
+	nodes := []*resource.Node{newTestNode("10.0.0.1"), newTestNode("10.0.0.2"), newTestNode("10.0.0.3")}
+	statuses := map[string]uint32{"10.0.0.1": grpcclient.RaltStatusRunning, "10.0.0.2": 0}
+	var lock sync.Mutex
+	var called []string
+	states := checkRaltAlive(context.Background(), nodes, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.9"},
+		func(ctx context.Context, ip string) (uint32, error) {
+			lock.Lock()
+			called = append(called, ip)
+			lock.Unlock()
+			if status, ok := statuses[ip]; ok {
+				return status, nil
+			}
+			return 0, fmt.Errorf("node %s is unreachable", ip)
+		})
+
+	if len(called) != 3 {
+		t.Errorf("only registered nodes should be checked but get %v", called)
+	}
+
+	expected := map[string]bool{"10.0.0.1": true, "10.0.0.2": false, "10.0.0.3": false}
+	if len(states) != len(expected) {
+		t.Fatalf("states expected %d but get %d", len(expected), len(states))
+	}
+
+	for _, state := range states {
+		if isAlive, ok := expected[state.node.GetID()]; ok == false || isAlive != state.isAlive {
+			t.Errorf("ralt of node %s expected alive %v but get %v", state.node.GetID(), isAlive, state.isAlive)
+		}
+	}
+}
+
+func TestCheckRaltAliveConcurrency(t *testing.T) {
+	var nodes []*resource.Node
+	var ips []string
+	for i := 0; i < raltCheckConcurrency*3; i++ {
+		ip := fmt.Sprintf("10.0.1.%d", i)
+		nodes = append(nodes, newTestNode(ip))
+		ips = append(ips, ip)
+	}
+
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	states := checkRaltAlive(context.Background(), nodes, ips, func(ctx context.Context, ip string) (uint32, error) {
+		lock.Lock()
+		running += 1
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running -= 1
+		lock.Unlock()
+		return grpcclient.RaltStatusRunning, nil
+	})
+
+	if len(states) != len(ips) {
+		t.Errorf("states expected %d but get %d", len(ips), len(states))
+	}
+
+	if maxRunning > raltCheckConcurrency || maxRunning < 2 {
+		t.Errorf("concurrent calls should be in [2, %d] but get %d", raltCheckConcurrency, maxRunning)
+	}
+}
@@ -25,6 +25,10 @@ type Node struct {
 	ControllerIp string               `json:"controllerIP"`
 	StartTime    time.Time            `json:"startTime"`
 	Vip          string               `json:"vip"`
+
+	RaltIsAlive          bool      `json:"raltIsAlive"`
+	RaltStatusChangeTime time.Time `json:"raltStatusChangeTime"`
+	RaltCheckTime        time.Time `json:"raltCheckTime"`
 }
 
 type RatioWithTimestamp struct {
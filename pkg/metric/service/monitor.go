@@ -16,11 +16,14 @@ import (
 	pgha "github.com/linkingthing/pg-ha/pkg/rpcserver"
 
 	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/alarm"
 	"github.com/trymanytimes/UpdateWeb/pkg/db"
 	metrichandler "github.com/trymanytimes/UpdateWeb/pkg/metric/handler"
 	"github.com/trymanytimes/UpdateWeb/pkg/metric/resource"
 )
 
+const defaultNodeTimeout = 20 //second
+
 type NodeMonitorHandler struct {
 	Conf       *config.DDIControllerConfig
 	LastTime   map[string]int64
@@ -33,10 +36,15 @@ type NodeMonitorHandler struct {
 }
 
 func NewNodeMonitorHandler(conf *config.DDIControllerConfig) *NodeMonitorHandler {
+	timeout := conf.MonitorNode.TimeOut
+	if timeout <= 0 {
+		timeout = defaultNodeTimeout
+	}
+
 	return &NodeMonitorHandler{
 		Conf:       conf,
 		LastTime:   make(map[string]int64, 5),
-		timeout:    conf.MonitorNode.TimeOut,
+		timeout:    timeout,
 		startTime:  time.Now(),
 		configFile: conf.Path,
 		masterIp:   conf.Server.Master,
@@ -200,20 +208,5 @@ func (handler *NodeMonitorHandler) MasterOper(masterIp string) error {
 }
 
 func (handler *NodeMonitorHandler) sendHAEventIfNeed(cmd pgha.PGHACmd, req pghapb.DDICtrlRequest) {
-	/*var thresholds []*alarm.Threshold
-	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
-		err := tx.Fill(map[string]interface{}{restdb.IDField: strings.ToLower(string(alarm.ThresholdNameHATrigger))}, &thresholds)
-		return err
-	}); err != nil {
-		log.Warnf("get threshold failed: %s", err.Error())
-		return
-	}
-
-	if len(thresholds) != 1 {
-		return
-	}
-
-	alarm.NewEvent().Name(thresholds[0].Name).Level(thresholds[0].Level).ThresholdType(thresholds[0].ThresholdType).Time(time.Now()).
-		SendMail(thresholds[0].SendMail).HaCmd(string(cmd)).MasterIp(req.GetMasterIp()).SlaveIp(req.GetSlaveIp()).Publish()
-	*/
+	alarm.PublishHATrigger(string(cmd), req.GetMasterIp(), req.GetSlaveIp())
 }
@@ -0,0 +1,239 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	businesshandler "github.com/trymanytimes/UpdateWeb/pkg/business/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbWeb "github.com/trymanytimes/UpdateWeb/pkg/proto/rcs"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/resource"
+	statsresource "github.com/trymanytimes/UpdateWeb/pkg/stats/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/stats/store"
+)
+
+type Data struct {
+	Name     string
+	Schedule string
+	Scope    string
+	ScopeID  string
+	From     time.Time
+	To       time.Time
+	Visitors *VisitorSummary
+	Domains  []*DomainSummary
+	Hosts    []*HostSummary
+}
+
+type VisitorSummary struct {
+	HttpVisitors  uint64
+	NeteVisitors  uint64
+	TotalVisitors uint64
+	UpgradeRatio  string
+	Samples       []*VisitorSample
+}
+
+type VisitorSample struct {
+	Time         time.Time
+	HttpVisitors uint64
+	NeteVisitors uint64
+}
+
+type DomainSummary struct {
+	Domain string
+	Visits uint64
+}
+
+type HostSummary struct {
+	HostID      string
+	V4UpBytes   uint64
+	V4DownBytes uint64
+	V6UpBytes   uint64
+	V6DownBytes uint64
+}
+
+//resolutionOf keeps at most about one month of rows in a report
+func resolutionOf(schedule string) statsresource.Resolution {
+	if schedule == resource.ScheduleDaily {
+		return statsresource.Resolution1h
+	}
+
+	return statsresource.Resolution1d
+}
+
+func collectData(report *resource.Report, from, to time.Time) (*Data, error) {
+	data := &Data{
+		Name:     report.Name,
+		Schedule: report.Schedule,
+		Scope:    report.Scope,
+		ScopeID:  report.ScopeID,
+		From:     from,
+		To:       to,
+	}
+
+	resolution := resolutionOf(report.Schedule)
+	for _, metric := range report.Metrics {
+		var err error
+		switch metric {
+		case resource.MetricVisitors:
+			data.Visitors, err = collectVisitors(resolution, from, to)
+		case resource.MetricDomainVisits:
+			data.Domains, err = collectDomainVisits(resolution, report, from, to)
+		case resource.MetricHostFlows:
+			data.Hosts, err = collectHostFlows(resolution, from, to)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func collectVisitors(resolution statsresource.Resolution, from, to time.Time) (*VisitorSummary, error) {
+	httpRollups, err := store.Query(resolution, statsresource.MetricHttpVisitors, businesshandler.DefaultClusterID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	neteRollups, err := store.Query(resolution, statsresource.MetricNeteVisitors, businesshandler.DefaultClusterID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &VisitorSummary{}
+	sampleMap := make(map[int64]*VisitorSample)
+	getSample := func(bucketTime time.Time) *VisitorSample {
+		sample, ok := sampleMap[bucketTime.Unix()]
+		if ok == false {
+			sample = &VisitorSample{Time: bucketTime}
+			sampleMap[bucketTime.Unix()] = sample
+			summary.Samples = append(summary.Samples, sample)
+		}
+		return sample
+	}
+
+	for _, rollup := range httpRollups {
+		getSample(rollup.BucketTime).HttpVisitors = rollup.Value
+		summary.HttpVisitors += rollup.Value
+	}
+
+	for _, rollup := range neteRollups {
+		getSample(rollup.BucketTime).NeteVisitors = rollup.Value
+		summary.NeteVisitors += rollup.Value
+	}
+
+	sort.Slice(summary.Samples, func(i, j int) bool {
+		return summary.Samples[i].Time.Before(summary.Samples[j].Time)
+	})
+	summary.TotalVisitors = summary.HttpVisitors + summary.NeteVisitors
+	summary.UpgradeRatio = businesshandler.UpgradeRatio(summary.HttpVisitors, summary.NeteVisitors)
+	return summary, nil
+}
+
+func collectDomainVisits(resolution statsresource.Resolution, report *resource.Report, from, to time.Time) ([]*DomainSummary, error) {
+	domains, err := scopeDomains(report)
+	if err != nil {
+		return nil, err
+	}
+
+	rollups, err := store.Query(resolution, statsresource.MetricDomainVisits, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	summaryMap := make(map[string]*DomainSummary)
+	var summaries []*DomainSummary
+	for _, rollup := range rollups {
+		if domains != nil && domains[rollup.Object] == false {
+			continue
+		}
+
+		summary, ok := summaryMap[rollup.Object]
+		if ok == false {
+			summary = &DomainSummary{Domain: rollup.Object}
+			summaryMap[rollup.Object] = summary
+			summaries = append(summaries, summary)
+		}
+		summary.Visits += rollup.Value
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Visits > summaries[j].Visits
+	})
+	return summaries, nil
+}
+
+//scopeDomains returns nil for cluster which means all domains
+func scopeDomains(report *resource.Report) (map[string]bool, error) {
+	cli := grpcclient.GetGrpcClient()
+	var websites []*pbWeb.WebsiteReqInfo
+	switch report.Scope {
+	case resource.ScopeWebGroup:
+		rsp, err := cli.WebsiteClient.GetRaltGroupWebsite(context.Background(),
+			&pbWeb.GetRaltGroupWebsiteReq{StrgroupId: report.ScopeID})
+		if err != nil {
+			return nil, fmt.Errorf("grpc service exec GetRaltGroupWebsite failed: %s", err.Error())
+		}
+		websites = rsp.GetWebsite()
+	case resource.ScopeWebsite:
+		rsp, err := cli.WebsiteClient.GetRaltSpecWebsite(context.Background(),
+			&pbWeb.GetRaltSpecWebsiteReq{StrdomainId: report.ScopeID})
+		if err != nil {
+			return nil, fmt.Errorf("grpc service exec GetRaltSpecWebsite failed: %s", err.Error())
+		}
+		websites = rsp.GetWebsite()
+	default:
+		return nil, nil
+	}
+
+	domains := make(map[string]bool)
+	for _, website := range websites {
+		domains[website.GetStrsrcDomain()] = true
+	}
+
+	return domains, nil
+}
+
+func collectHostFlows(resolution statsresource.Resolution, from, to time.Time) ([]*HostSummary, error) {
+	summaryMap := make(map[string]*HostSummary)
+	var summaries []*HostSummary
+	for _, metric := range []string{
+		statsresource.MetricHostV4UpBytes,
+		statsresource.MetricHostV4DownBytes,
+		statsresource.MetricHostV6UpBytes,
+		statsresource.MetricHostV6DownBytes,
+	} {
+		rollups, err := store.Query(resolution, metric, "", from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rollup := range rollups {
+			summary, ok := summaryMap[rollup.Object]
+			if ok == false {
+				summary = &HostSummary{HostID: rollup.Object}
+				summaryMap[rollup.Object] = summary
+				summaries = append(summaries, summary)
+			}
+
+			switch metric {
+			case statsresource.MetricHostV4UpBytes:
+				summary.V4UpBytes += rollup.Value
+			case statsresource.MetricHostV4DownBytes:
+				summary.V4DownBytes += rollup.Value
+			case statsresource.MetricHostV6UpBytes:
+				summary.V6UpBytes += rollup.Value
+			case statsresource.MetricHostV6DownBytes:
+				summary.V6DownBytes += rollup.Value
+			}
+		}
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].HostID < summaries[j].HostID
+	})
+	return summaries, nil
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	alarmhandler "github.com/trymanytimes/UpdateWeb/pkg/alarm/handler"
+	businesshandler "github.com/trymanytimes/UpdateWeb/pkg/business/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/mail"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	reportFileTimeFormat = "20060102"
+	//reports are served without authentication, the random suffix keeps
+	//file names unguessable
+	reportFileTokenLen = 24
+	ReportPublicPath   = businesshandler.PublicFilePath + "report/"
+)
+
+//Generate renders report of [from, to) and mails it to recipients, the files
+//are kept under util.ReportPath even if mail is not sent
+func Generate(report *resource.Report, from, to time.Time) (*resource.ReportFile, error) {
+	data, err := collectData(report, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("collect data of report %s failed: %s", report.Name, err.Error())
+	}
+
+	htmlFile, csvFile, err := deliver(mailConfig(), report.Recipients, data, util.ReportPath,
+		report.GetID()+"-"+from.Format(reportFileTimeFormat)+"-"+util.CreateRandomString(reportFileTokenLen))
+	reportFile := &resource.ReportFile{
+		From: restresource.ISOTime(from),
+		To:   restresource.ISOTime(to),
+	}
+	if htmlFile != "" {
+		reportFile.HTMLPath = ReportPublicPath + filepath.Base(htmlFile)
+		reportFile.CSVPath = ReportPublicPath + filepath.Base(csvFile)
+	}
+
+	return reportFile, err
+}
+
+//mailConfig prefers the enabled mail sender of alarm, the mail section of
+//config file is used if no mail sender is enabled
+func mailConfig() *mail.Config {
+	if conf, err := alarmhandler.GetMailConfig(); err != nil {
+		log.Warnf("get mail sender failed: %s", err.Error())
+	} else if conf != nil {
+		return conf
+	}
+
+	conf := config.GetConfig().Mail
+	return &mail.Config{
+		Host:     conf.Host,
+		Port:     conf.Port,
+		Username: conf.Username,
+		Password: conf.Password,
+		From:     conf.From,
+	}
+}
+
+//deliver returns the files written even if sending mail failed
+func deliver(conf *mail.Config, recipients []string, data *Data, dir, fileName string) (string, string, error) {
+	body, err := renderHTML(data)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("create report dir %s failed: %s", dir, err.Error())
+	}
+
+	htmlFile := filepath.Join(dir, fileName+".html")
+	if err := ioutil.WriteFile(htmlFile, []byte(body), 0644); err != nil {
+		return "", "", fmt.Errorf("write report file %s failed: %s", htmlFile, err.Error())
+	}
+
+	csvFile := filepath.Join(dir, fileName+".csv")
+	if err := util.GenCSVFile(csvFile, reportCSVHeader, csvContents(data)); err != nil {
+		return "", "", err
+	}
+
+	return htmlFile, csvFile, mail.Send(conf, &mail.Message{
+		To: recipients,
+		Subject: fmt.Sprintf("%s %s - %s", data.Name,
+			data.From.Format(util.DateFormat), data.To.Format(util.DateFormat)),
+		HTMLBody:    body,
+		Attachments: []string{csvFile},
+	})
+}
+
+//RemoveExpiredFiles removes report files in dir modified before expireTime
+func RemoveExpiredFiles(dir string, expireTime time.Time) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read report dir %s failed: %s", dir, err.Error())
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || info.ModTime().Before(expireTime) == false {
+			continue
+		}
+
+		if ext := filepath.Ext(info.Name()); ext != ".html" && ext != ".csv" {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil {
+			return fmt.Errorf("remove report file %s failed: %s", info.Name(), err.Error())
+		}
+	}
+
+	return nil
+}
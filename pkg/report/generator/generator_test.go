@@ -0,0 +1,171 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/mail"
+	"github.com/trymanytimes/UpdateWeb/pkg/mail/mailtest"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/resource"
+)
+
+func TestLastPeriod(t *testing.T) {
+	now := time.Date(2020, 11, 4, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		schedule string
+		from     time.Time
+		to       time.Time
+	}{
+		{resource.ScheduleDaily, time.Date(2020, 11, 3, 0, 0, 0, 0, time.Local), time.Date(2020, 11, 4, 0, 0, 0, 0, time.Local)},
+		{resource.ScheduleWeekly, time.Date(2020, 10, 26, 0, 0, 0, 0, time.Local), time.Date(2020, 11, 2, 0, 0, 0, 0, time.Local)},
+		{resource.ScheduleMonthly, time.Date(2020, 10, 1, 0, 0, 0, 0, time.Local), time.Date(2020, 11, 1, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		from, to := LastPeriod(tt.schedule, now)
+		if from.Equal(tt.from) == false || to.Equal(tt.to) == false {
+			t.Errorf("%s period expect [%s, %s) but get [%s, %s)", tt.schedule, tt.from, tt.to, from, to)
+		}
+	}
+
+	if from, to := LastPeriod(resource.ScheduleWeekly, time.Date(2020, 11, 2, 0, 0, 0, 0, time.Local)); from.Day() != 26 || to.Day() != 2 {
+		t.Errorf("weekly period of monday should end at the monday but get [%s, %s)", from, to)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("new smtp stub failed: %s", err.Error())
+	}
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	from := time.Date(2020, 10, 1, 0, 0, 0, 0, time.Local)
+	data := &Data{
+		Name:     "ipv6-monthly",
+		Schedule: resource.ScheduleMonthly,
+		Scope:    resource.ScopeCluster,
+		From:     from,
+		To:       from.AddDate(0, 1, 0),
+		Visitors: &VisitorSummary{
+			HttpVisitors:  30,
+			NeteVisitors:  10,
+			TotalVisitors: 40,
+			UpgradeRatio:  "0.2500",
+			Samples:       []*VisitorSample{{Time: from, HttpVisitors: 30, NeteVisitors: 10}},
+		},
+		Domains: []*DomainSummary{{Domain: "www.example.com", Visits: 100}},
+		Hosts:   []*HostSummary{{HostID: "host1", V4UpBytes: 1024}},
+	}
+
+	conf := &mail.Config{Host: server.Host(), Port: server.Port(), From: "ddi@example.com"}
+	htmlFile, csvFile, err := deliver(conf, []string{"ops@example.com"}, data, dir, "report1-20201001")
+	if err != nil {
+		t.Fatalf("deliver report failed: %s", err.Error())
+	}
+
+	if htmlFile != filepath.Join(dir, "report1-20201001.html") || csvFile != filepath.Join(dir, "report1-20201001.csv") {
+		t.Errorf("unexpected report files %s %s", htmlFile, csvFile)
+	}
+
+	html, err := ioutil.ReadFile(htmlFile)
+	if err != nil {
+		t.Fatalf("read html report failed: %s", err.Error())
+	}
+
+	for _, s := range []string{"ipv6-monthly", "0.2500", "www.example.com", "1024"} {
+		if strings.Contains(string(html), s) == false {
+			t.Errorf("html report should contain %s", s)
+		}
+	}
+
+	csv, err := ioutil.ReadFile(csvFile)
+	if err != nil {
+		t.Fatalf("read csv report failed: %s", err.Error())
+	}
+
+	if strings.Contains(string(csv), "domain,www.example.com,visits,100") == false {
+		t.Errorf("csv report should contain visits of www.example.com")
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 || len(messages[0].To) != 1 || messages[0].To[0] != "ops@example.com" {
+		t.Fatalf("report should be mailed to ops@example.com but get %v", messages)
+	}
+
+	if strings.Contains(messages[0].Data, "Subject: ipv6-monthly 2020-10-01 - 2020-11-01") == false {
+		t.Errorf("unexpected mail subject in %s", messages[0].Data)
+	}
+}
+
+func TestDeliverWithoutMailServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	data := &Data{Name: "daily", Scope: resource.ScopeCluster}
+	htmlFile, _, err := deliver(&mail.Config{}, []string{"ops@example.com"}, data, dir, "report2")
+	if err == nil {
+		t.Errorf("deliver without mail server should fail")
+	}
+
+	if _, err := os.Stat(htmlFile); err != nil {
+		t.Errorf("report file should be kept when mail failed: %s", err.Error())
+	}
+}
+
+func TestRemoveExpiredFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	files := []struct {
+		name    string
+		modTime time.Time
+		removed bool
+	}{
+		{"old.html", now.AddDate(0, 0, -100), true},
+		{"old.csv", now.AddDate(0, 0, -91), true},
+		{"new.html", now.AddDate(0, 0, -1), false},
+		{"old.txt", now.AddDate(0, 0, -100), false},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(path, []byte(f.name), 0644); err != nil {
+			t.Fatalf("write file %s failed: %s", f.name, err.Error())
+		}
+		if err := os.Chtimes(path, f.modTime, f.modTime); err != nil {
+			t.Fatalf("set time of %s failed: %s", f.name, err.Error())
+		}
+	}
+
+	if err := RemoveExpiredFiles(dir, now.AddDate(0, 0, -90)); err != nil {
+		t.Fatalf("remove expired files failed: %s", err.Error())
+	}
+
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(dir, f.name))
+		if removed := os.IsNotExist(err); removed != f.removed {
+			t.Errorf("file %s removed should be %v but get %v", f.name, f.removed, removed)
+		}
+	}
+
+	if err := RemoveExpiredFiles(filepath.Join(dir, "none"), now); err != nil {
+		t.Errorf("missing report dir should be ignored but get %s", err.Error())
+	}
+}
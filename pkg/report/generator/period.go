@@ -0,0 +1,23 @@
+package generator
+
+import (
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/report/resource"
+)
+
+//LastPeriod returns the last period of schedule ended before now, weeks begin
+//at monday
+func LastPeriod(schedule string, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch schedule {
+	case resource.ScheduleWeekly:
+		end := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return end.AddDate(0, 0, -7), end
+	case resource.ScheduleMonthly:
+		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return end.AddDate(0, -1, 0), end
+	default:
+		return today.AddDate(0, 0, -1), today
+	}
+}
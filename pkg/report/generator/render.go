@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"time"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+var reportCSVHeader = []string{"category", "object", "metric", "value"}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string { return t.Format(util.TimeFormat) },
+}).Parse(`<html>
+<head><meta charset="utf-8"><title>{{.Name}}</title></head>
+<body>
+<h2>{{.Name}}</h2>
+<p>{{.Schedule}} report of {{.Scope}} {{.ScopeID}} from {{datetime .From}} to {{datetime .To}}</p>
+{{with .Visitors}}
+<h3>Visitors</h3>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>http visitors</th><th>ipv6 visitors</th><th>total visitors</th><th>upgrade ratio</th></tr>
+<tr><td>{{.HttpVisitors}}</td><td>{{.NeteVisitors}}</td><td>{{.TotalVisitors}}</td><td>{{.UpgradeRatio}}</td></tr>
+</table>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>time</th><th>http visitors</th><th>ipv6 visitors</th></tr>
+{{range .Samples}}<tr><td>{{datetime .Time}}</td><td>{{.HttpVisitors}}</td><td>{{.NeteVisitors}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Domains}}
+<h3>Domain visits</h3>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>domain</th><th>visits</th></tr>
+{{range .Domains}}<tr><td>{{.Domain}}</td><td>{{.Visits}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Hosts}}
+<h3>Host flows (bytes)</h3>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>host</th><th>ipv4 up</th><th>ipv4 down</th><th>ipv6 up</th><th>ipv6 down</th></tr>
+{{range .Hosts}}<tr><td>{{.HostID}}</td><td>{{.V4UpBytes}}</td><td>{{.V4DownBytes}}</td><td>{{.V6UpBytes}}</td><td>{{.V6DownBytes}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+func renderHTML(data *Data) (string, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render report %s failed: %s", data.Name, err.Error())
+	}
+
+	return buf.String(), nil
+}
+
+func csvContents(data *Data) [][]string {
+	var contents [][]string
+	if data.Visitors != nil {
+		contents = append(contents,
+			[]string{"visitors", data.Scope, "httpVisitors", strconv.FormatUint(data.Visitors.HttpVisitors, 10)},
+			[]string{"visitors", data.Scope, "neteVisitors", strconv.FormatUint(data.Visitors.NeteVisitors, 10)},
+			[]string{"visitors", data.Scope, "totalVisitors", strconv.FormatUint(data.Visitors.TotalVisitors, 10)},
+			[]string{"visitors", data.Scope, "upgradeRatio", data.Visitors.UpgradeRatio})
+		for _, sample := range data.Visitors.Samples {
+			timestamp := sample.Time.Format(util.TimeFormat)
+			contents = append(contents,
+				[]string{"visitors", timestamp, "httpVisitors", strconv.FormatUint(sample.HttpVisitors, 10)},
+				[]string{"visitors", timestamp, "neteVisitors", strconv.FormatUint(sample.NeteVisitors, 10)})
+		}
+	}
+
+	for _, domain := range data.Domains {
+		contents = append(contents, []string{"domain", domain.Domain, "visits", strconv.FormatUint(domain.Visits, 10)})
+	}
+
+	for _, host := range data.Hosts {
+		contents = append(contents,
+			[]string{"host", host.HostID, "v4UpBytes", strconv.FormatUint(host.V4UpBytes, 10)},
+			[]string{"host", host.HostID, "v4DownBytes", strconv.FormatUint(host.V4DownBytes, 10)},
+			[]string{"host", host.HostID, "v6UpBytes", strconv.FormatUint(host.V6UpBytes, 10)},
+			[]string{"host", host.HostID, "v6DownBytes", strconv.FormatUint(host.V6DownBytes, 10)})
+	}
+
+	return contents
+}
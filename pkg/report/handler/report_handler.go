@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"fmt"
+	netmail "net/mail"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	resterror "github.com/zdnscloud/gorest/error"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/generator"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/resource"
+)
+
+type ReportHandler struct{}
+
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{}
+}
+
+func (h *ReportHandler) Create(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	report := ctx.Resource.(*resource.Report)
+	if err := checkReport(report); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	//the first report is sent when the current period ends
+	_, report.PeriodEnd = generator.LastPeriod(report.Schedule, time.Now())
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if _, err := tx.Insert(report); err != nil {
+			return fmt.Errorf("insert report %s into db failed: %s", report.Name, err.Error())
+		}
+
+		return nil
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return report, nil
+}
+
+func (h *ReportHandler) Update(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	report := ctx.Resource.(*resource.Report)
+	if err := checkReport(report); err != nil {
+		return nil, resterror.NewAPIError(resterror.InvalidFormat, err.Error())
+	}
+
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if _, err := tx.Update(resource.TableReport, map[string]interface{}{
+			"schedule":   report.Schedule,
+			"scope":      report.Scope,
+			"scope_id":   report.ScopeID,
+			"metrics":    report.Metrics,
+			"recipients": report.Recipients,
+			"comment":    report.Comment,
+		}, map[string]interface{}{restdb.IDField: report.GetID()}); err != nil {
+			return fmt.Errorf("update report %s failed: %s", report.GetID(), err.Error())
+		}
+
+		return nil
+	}); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return report, nil
+}
+
+func (h *ReportHandler) Delete(ctx *restresource.Context) *resterror.APIError {
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		_, err := tx.Delete(resource.TableReport, map[string]interface{}{restdb.IDField: ctx.Resource.GetID()})
+		return err
+	}); err != nil {
+		return resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("delete report %s from db failed: %s", ctx.Resource.GetID(), err.Error()))
+	}
+
+	return nil
+}
+
+func (h *ReportHandler) Get(ctx *restresource.Context) (restresource.Resource, *resterror.APIError) {
+	report, err := getReport(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return report, nil
+}
+
+func (h *ReportHandler) List(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	var reports []*resource.Report
+	if err := db.GetResources(map[string]interface{}{"orderby": "create_time"}, &reports); err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError,
+			fmt.Sprintf("list reports from db failed: %s", err.Error()))
+	}
+
+	return reports, nil
+}
+
+func (h *ReportHandler) Action(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	switch ctx.Resource.GetAction().Name {
+	case resource.ActionRun:
+		return h.run(ctx)
+	default:
+		return nil, resterror.NewAPIError(resterror.InvalidAction,
+			fmt.Sprintf("action %s is unknown", ctx.Resource.GetAction().Name))
+	}
+}
+
+//run sends report of the last period at once, the schedule is not changed
+func (h *ReportHandler) run(ctx *restresource.Context) (interface{}, *resterror.APIError) {
+	report, err := getReport(ctx.Resource.GetID())
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	from, to := generator.LastPeriod(report.Schedule, time.Now())
+	reportFile, err := generator.Generate(report, from, to)
+	if updateErr := SaveReportResult(report.GetID(), report.PeriodEnd, reportFile, err); updateErr != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, updateErr.Error())
+	}
+
+	if err != nil {
+		return nil, resterror.NewAPIError(resterror.ServerError, err.Error())
+	}
+
+	return reportFile, nil
+}
+
+func getReport(id string) (*resource.Report, error) {
+	var reports []*resource.Report
+	if err := db.GetResources(map[string]interface{}{restdb.IDField: id}, &reports); err != nil {
+		return nil, fmt.Errorf("get report %s from db failed: %s", id, err.Error())
+	}
+
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no found report %s", id)
+	}
+
+	return reports[0], nil
+}
+
+//SaveReportResult records the last report generated, periodEnd is the end
+//of the last period reported by schedule
+func SaveReportResult(id string, periodEnd time.Time, reportFile *resource.ReportFile, reportErr error) error {
+	lastFile, lastError := "", ""
+	if reportFile != nil {
+		lastFile = reportFile.HTMLPath
+	}
+
+	if reportErr != nil {
+		lastError = reportErr.Error()
+	}
+
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if _, err := tx.Update(resource.TableReport, map[string]interface{}{
+			"period_end": periodEnd,
+			"last_file":  lastFile,
+			"last_error": lastError,
+		}, map[string]interface{}{restdb.IDField: id}); err != nil {
+			return fmt.Errorf("update result of report %s failed: %s", id, err.Error())
+		}
+
+		return nil
+	})
+}
+
+func checkReport(report *resource.Report) error {
+	if report.Scope == resource.ScopeCluster {
+		report.ScopeID = ""
+	} else if report.ScopeID == "" {
+		return fmt.Errorf("scopeID is required by scope %s", report.Scope)
+	}
+
+	if len(report.Metrics) == 0 {
+		return fmt.Errorf("metrics is required")
+	}
+
+	for _, metric := range report.Metrics {
+		if stringsContain(resource.Metrics, metric) == false {
+			return fmt.Errorf("metric %s is unknown", metric)
+		}
+
+		if report.Scope != resource.ScopeCluster && stringsContain(resource.ClusterMetrics, metric) {
+			return fmt.Errorf("metric %s is only supported by scope %s", metric, resource.ScopeCluster)
+		}
+	}
+
+	if len(report.Recipients) == 0 {
+		return fmt.Errorf("recipients is required")
+	}
+
+	for _, recipient := range report.Recipients {
+		if _, err := netmail.ParseAddress(recipient); err != nil {
+			return fmt.Errorf("recipient %s is invalid: %s", recipient, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func stringsContain(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zdnscloud/cement/log"
+	"github.com/zdnscloud/gorest"
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/generator"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/report/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/util"
+)
+
+const (
+	scheduleInterval = 10 * time.Minute
+	cleanInterval    = 24 * time.Hour
+	defaultRetention = 90
+)
+
+var (
+	Version = restresource.APIVersion{
+		Version: "v1",
+		Group:   "linkingthing.com/report",
+	}
+)
+
+func RegisterHandler(apiServer *gorest.Server, router gin.IRoutes) error {
+	apiServer.Schemas.MustImport(&Version, resource.Report{}, handler.NewReportHandler())
+	return nil
+}
+
+func PersistentResources() []restresource.Resource {
+	return []restresource.Resource{
+		&resource.Report{},
+	}
+}
+
+//Run sends reports by schedule and removes report files older than
+//retention days
+func Run(conf *config.DDIControllerConfig) {
+	retention := conf.Report.Retention
+	if retention == 0 {
+		retention = defaultRetention
+	}
+
+	go func() {
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+		cleanTicker := time.NewTicker(cleanInterval)
+		defer cleanTicker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				runDueReports(time.Now())
+			case <-cleanTicker.C:
+				if err := generator.RemoveExpiredFiles(util.ReportPath,
+					time.Now().AddDate(0, 0, -int(retention))); err != nil {
+					log.Warnf("remove expired reports failed: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+//runDueReports sends reports whose last period is not reported, a period is
+//reported once even if the mail is failed and the error is kept in report
+func runDueReports(now time.Time) {
+	var reports []*resource.Report
+	if err := db.GetResources(map[string]interface{}{}, &reports); err != nil {
+		log.Warnf("list reports failed: %s", err.Error())
+		return
+	}
+
+	for _, report := range reports {
+		from, to := generator.LastPeriod(report.Schedule, now)
+		if report.PeriodEnd.Before(to) == false {
+			continue
+		}
+
+		reportFile, err := generator.Generate(report, from, to)
+		if err != nil {
+			log.Warnf("generate report %s failed: %s", report.Name, err.Error())
+		}
+
+		if err := handler.SaveReportResult(report.GetID(), to, reportFile, err); err != nil {
+			log.Warnf("save report %s failed: %s", report.Name, err.Error())
+		}
+	}
+}
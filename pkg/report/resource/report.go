@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+const (
+	ScheduleDaily   = "daily"
+	ScheduleWeekly  = "weekly"
+	ScheduleMonthly = "monthly"
+)
+
+const (
+	ScopeCluster  = "cluster"
+	ScopeWebGroup = "webgroup"
+	ScopeWebsite  = "website"
+)
+
+const (
+	MetricVisitors     = "visitors"
+	MetricDomainVisits = "domainvisits"
+	MetricHostFlows    = "hostflows"
+)
+
+//ClusterMetrics are only reported for cluster since visitors and flows are
+//not counted by domain
+var ClusterMetrics = []string{MetricVisitors, MetricHostFlows}
+var Metrics = []string{MetricVisitors, MetricDomainVisits, MetricHostFlows}
+
+const ActionRun = "run"
+
+type Report struct {
+	restresource.ResourceBase `json:",inline"`
+	Name                      string    `json:"name" rest:"required=true,minLen=1,maxLen=40" db:"uk"`
+	Schedule                  string    `json:"schedule" rest:"required=true,options=daily|weekly|monthly"`
+	Scope                     string    `json:"scope" rest:"required=true,options=cluster|webgroup|website"`
+	ScopeID                   string    `json:"scopeID"`
+	Metrics                   []string  `json:"metrics" rest:"required=true"`
+	Recipients                []string  `json:"recipients" rest:"required=true"`
+	Comment                   string    `json:"comment"`
+	PeriodEnd                 time.Time `json:"periodEnd" rest:"description=readonly"`
+	LastFile                  string    `json:"lastFile" rest:"description=readonly"`
+	LastError                 string    `json:"lastError" rest:"description=readonly"`
+}
+
+type ReportFile struct {
+	From     restresource.ISOTime `json:"from"`
+	To       restresource.ISOTime `json:"to"`
+	HTMLPath string               `json:"htmlPath"`
+	CSVPath  string               `json:"csvPath"`
+}
+
+var TableReport = restdb.ResourceDBType(&Report{})
+
+func (r Report) GetActions() []restresource.Action {
+	return []restresource.Action{
+		restresource.Action{
+			Name:   ActionRun,
+			Output: &ReportFile{},
+		},
+	}
+}
@@ -0,0 +1,241 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zdnscloud/cement/log"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	businesshandler "github.com/trymanytimes/UpdateWeb/pkg/business/handler"
+	"github.com/trymanytimes/UpdateWeb/pkg/grpcclient"
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+	pbRalt "github.com/trymanytimes/UpdateWeb/pkg/proto/ate_proto"
+	"github.com/trymanytimes/UpdateWeb/pkg/stats/resource"
+	"github.com/trymanytimes/UpdateWeb/pkg/stats/store"
+)
+
+const (
+	defaultCollectInterval = 300
+	defaultRetention5m     = 7
+	defaultRetention1h     = 90
+	defaultRetention1d     = 730
+	collectTimeout         = 30 * time.Second
+)
+
+type Collector struct {
+	interval    time.Duration
+	retentions  map[resource.Resolution]int
+	lastCleanup time.Time
+}
+
+func NewCollector(conf *config.DDIControllerConfig) *Collector {
+	return &Collector{
+		interval: time.Duration(valueOrDefault(conf.Stats.CollectInterval, defaultCollectInterval)) * time.Second,
+		retentions: map[resource.Resolution]int{
+			resource.Resolution5m: int(valueOrDefault(conf.Stats.Retention5m, defaultRetention5m)),
+			resource.Resolution1h: int(valueOrDefault(conf.Stats.Retention1h, defaultRetention1h)),
+			resource.Resolution1d: int(valueOrDefault(conf.Stats.Retention1d, defaultRetention1d)),
+		},
+	}
+}
+
+func valueOrDefault(value, defaultValue uint32) uint32 {
+	if value == 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+func (c *Collector) Run() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			c.collect(now)
+			c.rollup(now)
+			c.cleanup(now)
+		}
+	}
+}
+
+func (c *Collector) collect(now time.Time) {
+	cli := grpcclient.GetGrpcClient()
+	if err := collectHomePage(cli, now); err != nil {
+		log.Warnf("collect home page stats failed: %s", err.Error())
+	}
+
+	if err := collectVisitors(cli, now); err != nil {
+		log.Warnf("collect visitor stats failed: %s", err.Error())
+	}
+
+	if err := collectDomainVisits(cli, now); err != nil {
+		log.Warnf("collect domain visit stats failed: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	devices, err := cli.GetClusterDevices(ctx, businesshandler.DefaultClusterID)
+	cancel()
+	if err != nil {
+		log.Warnf("collect host stats failed: %s", err.Error())
+		return
+	}
+
+	for _, device := range devices {
+		if err := collectHostFlows(cli, device.GetHostId(), now); err != nil {
+			log.Warnf("collect flow stats of host %s failed: %s", device.GetHostId(), err.Error())
+		}
+
+		if err := collectRaltStats(cli, device.GetHostId(), grpcclient.DeviceIP(device), now); err != nil {
+			log.Warnf("collect ralt stats of host %s failed: %s", device.GetHostId(), err.Error())
+		}
+	}
+}
+
+func collectHomePage(cli *grpcclient.GrpcClient, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	resp, err := cli.MonitorClient.ShowHomePageData(ctx, &pbHomePage.ShowHomePageDataReq{ClusterId: businesshandler.DefaultClusterID})
+	if err != nil {
+		return fmt.Errorf("grpc service exec ShowHomePageData failed: %s", err.Error())
+	}
+
+	object := businesshandler.DefaultClusterID
+	return store.AddSamples([]store.Sample{
+		{Metric: resource.MetricNormalDomains, Object: object, Time: now, Value: uint64(resp.GetDomainIsNormal())},
+		{Metric: resource.MetricAbnormalDomains, Object: object, Time: now, Value: uint64(resp.GetDomainIsAbnormal())},
+		{Metric: resource.MetricSessions, Object: object, Time: now, Value: resp.GetCurrentSessionTotalNumber()},
+		{Metric: resource.MetricNormalIPv6, Object: object, Time: now, Value: uint64(resp.GetNormalIpv6IpaddrNumber())},
+		{Metric: resource.MetricAbnormalIPv6, Object: object, Time: now, Value: uint64(resp.GetAbnormalIpv6IpaddrNumber())},
+		{Metric: resource.MetricNormalNodes, Object: object, Time: now, Value: uint64(resp.GetNormalNodeNumber())},
+		{Metric: resource.MetricAbnormalNodes, Object: object, Time: now, Value: uint64(resp.GetAbnormalNodeNumber())},
+	})
+}
+
+func collectVisitors(cli *grpcclient.GrpcClient, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	resp, err := cli.MonitorClient.ShowHomePageVisitorData(ctx, &pbHomePage.ShowHomePageVisitorDataReq{ClusterId: businesshandler.DefaultClusterID})
+	if err != nil {
+		return fmt.Errorf("grpc service exec ShowHomePageVisitorData failed: %s", err.Error())
+	}
+
+	var samples []store.Sample
+	window := newWindow()
+	object := businesshandler.DefaultClusterID
+	for _, data := range resp.GetAtsNeteVisitorNum() {
+		t := window.add(data.GetTimestamp())
+		samples = append(samples,
+			store.Sample{Metric: resource.MetricHttpVisitors, Object: object, Time: t, Value: data.GetHttpVisitorNumber()},
+			store.Sample{Metric: resource.MetricNeteVisitors, Object: object, Time: t, Value: data.GetNeteVisitorNumber()})
+	}
+
+	return store.ReplaceBuckets(samples, window.begin, now)
+}
+
+func collectDomainVisits(cli *grpcclient.GrpcClient, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	resp, err := cli.MonitorClient.ShowDomainVisitorData(ctx, &pbHomePage.ShowDomainVisitorDataReq{ClusterId: businesshandler.DefaultClusterID})
+	if err != nil {
+		return fmt.Errorf("grpc service exec ShowDomainVisitorData failed: %s", err.Error())
+	}
+
+	var samples []store.Sample
+	window := newWindow()
+	for _, domainVisitor := range resp.GetDomainVisitNum() {
+		for _, num := range domainVisitor.GetVisitHostNumber() {
+			samples = append(samples, store.Sample{
+				Metric: resource.MetricDomainVisits,
+				Object: domainVisitor.GetMemberDomain(),
+				Time:   window.add(num.GetTimestamp()),
+				Value:  num.GetVisitDomainNum(),
+			})
+		}
+	}
+
+	return store.ReplaceBuckets(samples, window.begin, now)
+}
+
+//collectHostFlows saves bytes transferred between samples since byte counters
+//of backend are cumulative, so the window begins at the second sample
+func collectHostFlows(cli *grpcclient.GrpcClient, hostID string, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	resp, err := cli.MonitorClient.ShowHomePageFlowData(ctx, &pbHomePage.ShowHomePageFlowDataReq{DeviceId: hostID})
+	if err != nil {
+		return fmt.Errorf("grpc service exec ShowHomePageFlowData failed: %s", err.Error())
+	}
+
+	var samples []store.Sample
+	window := newWindow()
+	var prev *pbHomePage.DeviceFlowData
+	for _, flow := range sortDeviceFlows(resp.GetUpDowmFlow()) {
+		t := time.Unix(int64(flow.GetTimestamp()), 0)
+		samples = append(samples, store.Sample{Metric: resource.MetricHostConnections, Object: hostID, Time: t,
+			Value: uint64(flow.GetCurrentDeviceConnections())})
+		if prev != nil {
+			window.add(flow.GetTimestamp())
+			samples = append(samples,
+				store.Sample{Metric: resource.MetricHostV4UpBytes, Object: hostID, Time: t,
+					Value: counterDelta(prev.GetCurrentDeviceV4UpBytes(), flow.GetCurrentDeviceV4UpBytes())},
+				store.Sample{Metric: resource.MetricHostV4DownBytes, Object: hostID, Time: t,
+					Value: counterDelta(prev.GetCurrentDeviceV4DownBytes(), flow.GetCurrentDeviceV4DownBytes())},
+				store.Sample{Metric: resource.MetricHostV6UpBytes, Object: hostID, Time: t,
+					Value: counterDelta(prev.GetCurrentDeviceV6UpBytes(), flow.GetCurrentDeviceV6UpBytes())},
+				store.Sample{Metric: resource.MetricHostV6DownBytes, Object: hostID, Time: t,
+					Value: counterDelta(prev.GetCurrentDeviceV6DownBytes(), flow.GetCurrentDeviceV6DownBytes())})
+		}
+		prev = flow
+	}
+
+	return store.ReplaceBuckets(samples, window.begin, now)
+}
+
+func collectRaltStats(cli *grpcclient.GrpcClient, hostID, nodeIP string, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	resp, err := cli.RaltClient.GetRaltStats(ctx, &pbRalt.GetRaltStatsReq{IpAddr: nodeIP})
+	if err != nil {
+		return fmt.Errorf("grpc service exec GetRaltStats failed: %s", err.Error())
+	}
+
+	return store.AddSamples([]store.Sample{
+		{Metric: resource.MetricRaltIncomingRequests, Object: hostID, Time: now, Value: uint64(resp.GetFlowIncomingRequests())},
+		{Metric: resource.MetricRaltClientConnectionsV4, Object: hostID, Time: now, Value: uint64(resp.GetFlowTotalClientConnectionsIpv4())},
+		{Metric: resource.MetricRaltClientConnectionsV6, Object: hostID, Time: now, Value: uint64(resp.GetFlowTotalClientConnectionsIpv6())},
+		{Metric: resource.MetricRaltServerConnections, Object: hostID, Time: now, Value: uint64(resp.GetFlowTotalServerConnections())},
+		{Metric: resource.MetricRaltCacheHits, Object: hostID, Time: now, Value: uint64(resp.GetCacheTotalHits())},
+		{Metric: resource.MetricRaltCacheHitRatio, Object: hostID, Time: now, Value: uint64(resp.GetCacheHitRatio() * resource.RatioScale)},
+		{Metric: resource.MetricRaltCacheUsedMB, Object: hostID, Time: now, Value: resp.GetCacheUsedMb()},
+	})
+}
+
+//rollup refreshes the current and previous bucket of 1h and 1d so late 5m
+//buckets are always counted
+func (c *Collector) rollup(now time.Time) {
+	if err := store.Rollup(resource.Resolution1h, resource.Resolution5m, now.Add(-time.Hour), now); err != nil {
+		log.Warnf("rollup stats to %s failed: %s", resource.Resolution1h, err.Error())
+	}
+
+	if err := store.Rollup(resource.Resolution1d, resource.Resolution1h, now.AddDate(0, 0, -1), now); err != nil {
+		log.Warnf("rollup stats to %s failed: %s", resource.Resolution1d, err.Error())
+	}
+}
+
+func (c *Collector) cleanup(now time.Time) {
+	if now.Sub(c.lastCleanup) < 24*time.Hour {
+		return
+	}
+
+	c.lastCleanup = now
+	for resolution, days := range c.retentions {
+		if err := store.Cleanup(resolution, now.AddDate(0, 0, -days)); err != nil {
+			log.Warnf("cleanup stats failed: %s", err.Error())
+		}
+	}
+}
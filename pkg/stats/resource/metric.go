@@ -0,0 +1,33 @@
+package resource
+
+const (
+	MetricNormalDomains   = "homepage.normal_domains"
+	MetricAbnormalDomains = "homepage.abnormal_domains"
+	MetricSessions        = "homepage.sessions"
+	MetricNormalIPv6      = "homepage.normal_ipv6"
+	MetricAbnormalIPv6    = "homepage.abnormal_ipv6"
+	MetricNormalNodes     = "homepage.normal_nodes"
+	MetricAbnormalNodes   = "homepage.abnormal_nodes"
+
+	MetricHostV4UpBytes   = "host.v4_up_bytes"
+	MetricHostV4DownBytes = "host.v4_down_bytes"
+	MetricHostV6UpBytes   = "host.v6_up_bytes"
+	MetricHostV6DownBytes = "host.v6_down_bytes"
+	MetricHostConnections = "host.connections"
+
+	MetricHttpVisitors = "visitor.http"
+	MetricNeteVisitors = "visitor.nete"
+
+	MetricDomainVisits = "domain.visits"
+
+	MetricRaltIncomingRequests    = "ralt.incoming_requests"
+	MetricRaltClientConnectionsV4 = "ralt.client_connections_ipv4"
+	MetricRaltClientConnectionsV6 = "ralt.client_connections_ipv6"
+	MetricRaltServerConnections   = "ralt.server_connections"
+	MetricRaltCacheHits           = "ralt.cache_hits"
+	MetricRaltCacheHitRatio       = "ralt.cache_hit_ratio"
+	MetricRaltCacheUsedMB         = "ralt.cache_used_mb"
+)
+
+// RatioScale is used to store float ratios as integers
+const RatioScale = 10000
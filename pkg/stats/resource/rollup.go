@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+type Resolution string
+
+const (
+	Resolution5m Resolution = "5m"
+	Resolution1h Resolution = "1h"
+	Resolution1d Resolution = "1d"
+)
+
+var Resolutions = []Resolution{Resolution5m, Resolution1h, Resolution1d}
+
+func (r Resolution) Duration() time.Duration {
+	switch r {
+	case Resolution1h:
+		return time.Hour
+	case Resolution1d:
+		return 24 * time.Hour
+	default:
+		return 5 * time.Minute
+	}
+}
+
+func (r Resolution) Validate() bool {
+	for _, resolution := range Resolutions {
+		if r == resolution {
+			return true
+		}
+	}
+
+	return false
+}
+
+//BucketTime returns the begin of the bucket t belongs to, days are split
+//in local time
+func (r Resolution) BucketTime(t time.Time) time.Time {
+	if r == Resolution1d {
+		year, month, day := t.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+
+	return t.Truncate(r.Duration())
+}
+
+//StatsRollup is the aggregation of samples of metric on object in one bucket,
+//Value is the sum of samples, average is Value/Samples for gauges
+type StatsRollup struct {
+	restresource.ResourceBase `json:",inline"`
+	Resolution                string    `json:"resolution"`
+	Metric                    string    `json:"metric"`
+	Object                    string    `json:"object"`
+	BucketTime                time.Time `json:"bucketTime"`
+	Value                     uint64    `json:"value"`
+	Samples                   uint64    `json:"samples"`
+	Peak                      uint64    `json:"peak"`
+}
+
+var TableStatsRollup = restdb.ResourceDBType(&StatsRollup{})
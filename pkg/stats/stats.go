@@ -0,0 +1,26 @@
+package stats
+
+import (
+	restresource "github.com/zdnscloud/gorest/resource"
+
+	"github.com/trymanytimes/UpdateWeb/config"
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/stats/resource"
+)
+
+func Run(conf *config.DDIControllerConfig) {
+	go NewCollector(conf).Run()
+}
+
+func PersistentResources() []restresource.Resource {
+	return []restresource.Resource{
+		&resource.StatsRollup{},
+	}
+}
+
+//Indexes speeds up query of rollups by resolution, metric and time range
+func Indexes() []db.Index {
+	return []db.Index{
+		db.Index{Resource: &resource.StatsRollup{}, Columns: []string{"resolution", "metric", "bucket_time"}},
+	}
+}
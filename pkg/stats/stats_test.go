@@ -0,0 +1,29 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+
+	restdb "github.com/zdnscloud/gorest/db"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+)
+
+func TestIndexes(t *testing.T) {
+	meta, err := restdb.NewResourceMeta(PersistentResources())
+	if err != nil {
+		t.Fatalf("create resource meta failed: %s", err.Error())
+	}
+
+	sqls, err := db.IndexSqls(meta, Indexes())
+	if err != nil {
+		t.Fatalf("indexes of stats don't match its resources: %s", err.Error())
+	}
+
+	expected := []string{
+		"create index if not exists gr_stats_rollup_resolution_metric_bucket_time_idx on gr_stats_rollup (resolution, metric, bucket_time)",
+	}
+	if reflect.DeepEqual(sqls, expected) == false {
+		t.Errorf("index sqls expected %v but get %v", expected, sqls)
+	}
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/db"
+	"github.com/trymanytimes/UpdateWeb/pkg/stats/resource"
+)
+
+const (
+	queryRollupSql           = "select * from gr_stats_rollup where resolution = $1 and bucket_time >= $2 and bucket_time < $3"
+	deleteRollupSql          = "delete from gr_stats_rollup where resolution = $1 and bucket_time < $2"
+	insertRollupSql          = "insert into gr_stats_rollup (id, create_time, resolution, metric, object, bucket_time, value, samples, peak) values "
+	addRollupConflictSql     = " on conflict (id) do update set value = gr_stats_rollup.value + excluded.value, samples = gr_stats_rollup.samples + excluded.samples, peak = greatest(gr_stats_rollup.peak, excluded.peak)"
+	replaceRollupConflictSql = " on conflict (id) do update set value = excluded.value, samples = excluded.samples, peak = excluded.peak"
+	rollupColumnCount        = 9
+	//postgres accepts at most 65535 parameters in one statement
+	upsertRollupBatchSize = 1000
+)
+
+type Sample struct {
+	Metric string
+	Object string
+	Time   time.Time
+	Value  uint64
+}
+
+func rollupID(resolution resource.Resolution, metric, object string, bucketTime time.Time) string {
+	return string(resolution) + "-" + metric + "-" + object + "-" + strconv.FormatInt(bucketTime.Unix(), 10)
+}
+
+func newRollup(resolution resource.Resolution, metric, object string, bucketTime time.Time) *resource.StatsRollup {
+	rollup := &resource.StatsRollup{
+		Resolution: string(resolution),
+		Metric:     metric,
+		Object:     object,
+		BucketTime: bucketTime,
+	}
+	rollup.SetID(rollupID(resolution, metric, object, bucketTime))
+	return rollup
+}
+
+func (r *Sample) addTo(rollup *resource.StatsRollup) {
+	rollup.Value += r.Value
+	rollup.Samples += 1
+	if r.Value > rollup.Peak {
+		rollup.Peak = r.Value
+	}
+}
+
+//rollupSet keeps rollups in the order they are created, so rows are always
+//written in the same order
+type rollupSet struct {
+	resolution resource.Resolution
+	rollups    []*resource.StatsRollup
+	index      map[string]*resource.StatsRollup
+}
+
+func newRollupSet(resolution resource.Resolution) *rollupSet {
+	return &rollupSet{resolution: resolution, index: make(map[string]*resource.StatsRollup)}
+}
+
+func (s *rollupSet) get(metric, object string, t time.Time) *resource.StatsRollup {
+	bucketTime := s.resolution.BucketTime(t)
+	id := rollupID(s.resolution, metric, object, bucketTime)
+	rollup, ok := s.index[id]
+	if ok == false {
+		rollup = newRollup(s.resolution, metric, object, bucketTime)
+		s.index[id] = rollup
+		s.rollups = append(s.rollups, rollup)
+	}
+
+	return rollup
+}
+
+//AddSamples adds samples to the 5m buckets they belong to, it is used for
+//values sampled once by every collection
+func AddSamples(samples []Sample) error {
+	set := newRollupSet(resource.Resolution5m)
+	for i := range samples {
+		samples[i].addTo(set.get(samples[i].Metric, samples[i].Object, samples[i].Time))
+	}
+
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		return saveRollups(tx, set.rollups, addRollupConflictSql)
+	})
+}
+
+//ReplaceBuckets recomputes the 5m buckets from a window of samples kept by
+//backend, only buckets ended and fully covered by the window are saved so
+//collecting the same window again does not count samples twice
+func ReplaceBuckets(samples []Sample, windowBegin, now time.Time) error {
+	rollups := bucketsInWindow(samples, windowBegin, now)
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		return saveRollups(tx, rollups, replaceRollupConflictSql)
+	})
+}
+
+func bucketsInWindow(samples []Sample, windowBegin, now time.Time) []*resource.StatsRollup {
+	set := newRollupSet(resource.Resolution5m)
+	for i := range samples {
+		bucketTime := resource.Resolution5m.BucketTime(samples[i].Time)
+		if bucketTime.Before(windowBegin) || bucketTime.Add(resource.Resolution5m.Duration()).After(now) {
+			continue
+		}
+
+		samples[i].addTo(set.get(samples[i].Metric, samples[i].Object, samples[i].Time))
+	}
+
+	return set.rollups
+}
+
+//Rollup recomputes buckets of resolution in [from, to) from the buckets of
+//the lower resolution
+func Rollup(resolution, lower resource.Resolution, from, to time.Time) error {
+	from = resolution.BucketTime(from)
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		var lowerRollups []*resource.StatsRollup
+		if err := tx.FillEx(&lowerRollups, queryRollupSql, string(lower), from, to); err != nil {
+			return fmt.Errorf("list stats rollup %s failed: %s", lower, err.Error())
+		}
+
+		return saveRollups(tx, rollupLowerBuckets(resolution, lowerRollups), replaceRollupConflictSql)
+	})
+}
+
+func rollupLowerBuckets(resolution resource.Resolution, lowerRollups []*resource.StatsRollup) []*resource.StatsRollup {
+	set := newRollupSet(resolution)
+	for _, lowerRollup := range lowerRollups {
+		rollup := set.get(lowerRollup.Metric, lowerRollup.Object, lowerRollup.BucketTime)
+		rollup.Value += lowerRollup.Value
+		rollup.Samples += lowerRollup.Samples
+		if lowerRollup.Peak > rollup.Peak {
+			rollup.Peak = lowerRollup.Peak
+		}
+	}
+
+	return set.rollups
+}
+
+//saveRollups upserts rollups by batch, conflictSql decides how an existing
+//bucket is updated
+func saveRollups(tx restdb.Transaction, rollups []*resource.StatsRollup, conflictSql string) error {
+	now := time.Now()
+	for begin := 0; begin < len(rollups); begin += upsertRollupBatchSize {
+		end := begin + upsertRollupBatchSize
+		if end > len(rollups) {
+			end = len(rollups)
+		}
+
+		sql, args := upsertRollupsSqlAndArgs(rollups[begin:end], conflictSql, now)
+		if _, err := tx.Exec(sql, args...); err != nil {
+			return fmt.Errorf("save %d stats rollups failed: %s", end-begin, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func upsertRollupsSqlAndArgs(rollups []*resource.StatsRollup, conflictSql string, now time.Time) (string, []interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(insertRollupSql)
+	args := make([]interface{}, 0, len(rollups)*rollupColumnCount)
+	for i, rollup := range rollups {
+		if i != 0 {
+			buf.WriteString(",")
+		}
+
+		buf.WriteString("(")
+		for j := 1; j <= rollupColumnCount; j++ {
+			if j != 1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("$" + strconv.Itoa(i*rollupColumnCount+j))
+		}
+		buf.WriteString(")")
+		args = append(args, rollup.GetID(), now, rollup.Resolution, rollup.Metric, rollup.Object,
+			rollup.BucketTime, rollup.Value, rollup.Samples, rollup.Peak)
+	}
+	buf.WriteString(conflictSql)
+	return buf.String(), args
+}
+
+func Cleanup(resolution resource.Resolution, before time.Time) error {
+	return restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		if _, err := tx.Exec(deleteRollupSql, string(resolution), before); err != nil {
+			return fmt.Errorf("delete stats rollup %s before %s failed: %s", resolution, before.Format(time.RFC3339), err.Error())
+		}
+
+		return nil
+	})
+}
+
+//Query returns buckets of metric in [from, to) ordered by bucket time, all
+//objects are returned when object is empty
+func Query(resolution resource.Resolution, metric, object string, from, to time.Time) ([]*resource.StatsRollup, error) {
+	var buf bytes.Buffer
+	buf.WriteString(queryRollupSql)
+	buf.WriteString(" and metric = $4")
+	args := []interface{}{string(resolution), resolution.BucketTime(from), to, metric}
+	if object != "" {
+		buf.WriteString(" and object = $5")
+		args = append(args, object)
+	}
+	buf.WriteString(" order by bucket_time")
+
+	var rollups []*resource.StatsRollup
+	if err := restdb.WithTx(db.GetDB(), func(tx restdb.Transaction) error {
+		return tx.FillEx(&rollups, buf.String(), args...)
+	}); err != nil {
+		return nil, fmt.Errorf("query stats rollup %s of %s failed: %s", resolution, metric, err.Error())
+	}
+
+	return rollups, nil
+}
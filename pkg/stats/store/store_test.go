@@ -0,0 +1,151 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	restdb "github.com/zdnscloud/gorest/db"
+
+	"github.com/trymanytimes/UpdateWeb/pkg/stats/resource"
+)
+
+type execTx struct {
+	restdb.Transaction
+	sqls []string
+	args [][]interface{}
+}
+
+func (tx *execTx) Exec(sql string, args ...interface{}) (int64, error) {
+	tx.sqls = append(tx.sqls, sql)
+	tx.args = append(tx.args, args)
+	return int64(len(args) / rollupColumnCount), nil
+}
+
+func TestBucketsInWindow(t *testing.T) {
+	base := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Metric: "m", Object: "a", Time: base.Add(-time.Minute), Value: 100},
+		{Metric: "m", Object: "a", Time: base.Add(time.Minute), Value: 3},
+		{Metric: "m", Object: "a", Time: base.Add(2 * time.Minute), Value: 7},
+		{Metric: "m", Object: "b", Time: base.Add(4 * time.Minute), Value: 5},
+		{Metric: "m", Object: "a", Time: base.Add(6 * time.Minute), Value: 9},
+		{Metric: "m", Object: "a", Time: base.Add(11 * time.Minute), Value: 1},
+	}
+
+	//bucket before window begin and bucket not ended are skipped
+	rollups := bucketsInWindow(samples, base, base.Add(12*time.Minute))
+	expected := []struct {
+		object  string
+		bucket  time.Time
+		value   uint64
+		samples uint64
+		peak    uint64
+	}{
+		{"a", base, 10, 2, 7},
+		{"b", base, 5, 1, 5},
+		{"a", base.Add(5 * time.Minute), 9, 1, 9},
+	}
+
+	if len(rollups) != len(expected) {
+		t.Fatalf("buckets expected %d but get %d", len(expected), len(rollups))
+	}
+
+	for i, e := range expected {
+		r := rollups[i]
+		if r.Object != e.object || r.BucketTime.Equal(e.bucket) == false || r.Value != e.value ||
+			r.Samples != e.samples || r.Peak != e.peak || r.Resolution != string(resource.Resolution5m) {
+			t.Errorf("bucket %d expected %+v but get %+v", i, e, r)
+		}
+
+		if r.GetID() != rollupID(resource.Resolution5m, "m", e.object, e.bucket) {
+			t.Errorf("bucket %d has unexpected id %s", i, r.GetID())
+		}
+	}
+
+	if len(bucketsInWindow(nil, base, base.Add(time.Hour))) != 0 {
+		t.Errorf("empty samples should have no bucket")
+	}
+}
+
+func TestRollupLowerBuckets(t *testing.T) {
+	base := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	lower := []*resource.StatsRollup{
+		newRollup(resource.Resolution5m, "m", "a", base),
+		newRollup(resource.Resolution5m, "m", "a", base.Add(55*time.Minute)),
+		newRollup(resource.Resolution5m, "m", "a", base.Add(time.Hour)),
+	}
+	for i, r := range lower {
+		r.Value = uint64(10 * (i + 1))
+		r.Samples = 2
+		r.Peak = uint64(20 - i)
+	}
+
+	rollups := rollupLowerBuckets(resource.Resolution1h, lower)
+	if len(rollups) != 2 {
+		t.Fatalf("1h buckets expected 2 but get %d", len(rollups))
+	}
+
+	if r := rollups[0]; r.BucketTime.Equal(base) == false || r.Value != 30 || r.Samples != 4 || r.Peak != 20 ||
+		r.Resolution != string(resource.Resolution1h) {
+		t.Errorf("unexpected first 1h bucket %+v", r)
+	}
+
+	if r := rollups[1]; r.BucketTime.Equal(base.Add(time.Hour)) == false || r.Value != 30 || r.Samples != 2 || r.Peak != 18 {
+		t.Errorf("unexpected second 1h bucket %+v", r)
+	}
+}
+
+func TestUpsertRollupsSqlAndArgs(t *testing.T) {
+	now := time.Now()
+	base := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	rollups := []*resource.StatsRollup{
+		newRollup(resource.Resolution5m, "m", "a", base),
+		newRollup(resource.Resolution5m, "m", "b", base),
+	}
+	rollups[1].Value = 5
+
+	sql, args := upsertRollupsSqlAndArgs(rollups, replaceRollupConflictSql, now)
+	expected := insertRollupSql + "($1,$2,$3,$4,$5,$6,$7,$8,$9),($10,$11,$12,$13,$14,$15,$16,$17,$18)" + replaceRollupConflictSql
+	if sql != expected {
+		t.Errorf("sql expected %s but get %s", expected, sql)
+	}
+
+	if len(args) != 2*rollupColumnCount || args[9] != rollups[1].GetID() || args[15] != uint64(5) || args[10] != now {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestSaveRollupsBatch(t *testing.T) {
+	base := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	var rollups []*resource.StatsRollup
+	for i := 0; i < 2*upsertRollupBatchSize+1; i++ {
+		rollups = append(rollups, newRollup(resource.Resolution5m, "m", fmt.Sprintf("o%d", i), base))
+	}
+
+	tx := &execTx{}
+	if err := saveRollups(tx, rollups, addRollupConflictSql); err != nil {
+		t.Fatalf("save rollups failed: %s", err.Error())
+	}
+
+	expected := []int{upsertRollupBatchSize, upsertRollupBatchSize, 1}
+	if len(tx.sqls) != len(expected) {
+		t.Fatalf("statements expected %d but get %d", len(expected), len(tx.sqls))
+	}
+
+	for i, count := range expected {
+		if len(tx.args[i]) != count*rollupColumnCount || strings.HasSuffix(tx.sqls[i], addRollupConflictSql) == false {
+			t.Errorf("statement %d expected %d rollups", i, count)
+		}
+	}
+
+	if tx.args[2][0] != rollups[2*upsertRollupBatchSize].GetID() {
+		t.Errorf("last batch should save the last rollup")
+	}
+
+	tx = &execTx{}
+	if err := saveRollups(tx, nil, addRollupConflictSql); err != nil || len(tx.sqls) != 0 {
+		t.Errorf("save empty rollups should not exec any sql")
+	}
+}
@@ -0,0 +1,43 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+)
+
+//window records the earliest sample of a backend rolling window
+type window struct {
+	begin time.Time
+}
+
+func newWindow() *window {
+	return &window{}
+}
+
+func (w *window) add(timestamp uint64) time.Time {
+	t := time.Unix(int64(timestamp), 0)
+	if w.begin.IsZero() || t.Before(w.begin) {
+		w.begin = t
+	}
+
+	return t
+}
+
+func sortDeviceFlows(flows []*pbHomePage.DeviceFlowData) []*pbHomePage.DeviceFlowData {
+	sort.SliceStable(flows, func(i, j int) bool {
+		return flows[i].GetTimestamp() < flows[j].GetTimestamp()
+	})
+
+	return flows
+}
+
+//counterDelta returns 0 when the counter is reset
+func counterDelta(prev, current uint64) uint64 {
+	if current < prev {
+		return 0
+	}
+
+	return current - prev
+}
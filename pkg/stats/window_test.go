@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	pbHomePage "github.com/trymanytimes/UpdateWeb/pkg/proto/ateStatsHomePage"
+)
+
+func TestWindow(t *testing.T) {
+	w := newWindow()
+	if w.begin.IsZero() == false {
+		t.Fatalf("begin of empty window should be zero")
+	}
+
+	for _, timestamp := range []uint64{1600000600, 1600000300, 1600000900} {
+		if got := w.add(timestamp); got.Equal(time.Unix(int64(timestamp), 0)) == false {
+			t.Errorf("add %d should return its time but get %s", timestamp, got)
+		}
+	}
+
+	if w.begin.Equal(time.Unix(1600000300, 0)) == false {
+		t.Errorf("window should begin at the earliest sample but get %s", w.begin)
+	}
+}
+
+func TestSortDeviceFlows(t *testing.T) {
+	flows := sortDeviceFlows([]*pbHomePage.DeviceFlowData{
+		&pbHomePage.DeviceFlowData{Timestamp: 300, CurrentDeviceConnections: 2},
+		&pbHomePage.DeviceFlowData{Timestamp: 100, CurrentDeviceConnections: 1},
+		&pbHomePage.DeviceFlowData{Timestamp: 300, CurrentDeviceConnections: 3},
+		&pbHomePage.DeviceFlowData{Timestamp: 200, CurrentDeviceConnections: 4},
+	})
+
+	expected := []uint32{1, 4, 2, 3}
+	for i, flow := range flows {
+		if flow.GetCurrentDeviceConnections() != expected[i] {
+			t.Errorf("flow %d expected %d but get %d", i, expected[i], flow.GetCurrentDeviceConnections())
+		}
+	}
+
+	if len(sortDeviceFlows(nil)) != 0 {
+		t.Errorf("sort empty flows should return empty")
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	cases := []struct {
+		prev     uint64
+		current  uint64
+		expected uint64
+	}{
+		{0, 0, 0},
+		{100, 100, 0},
+		{100, 250, 150},
+		{250, 100, 0},
+		{1 << 63, 1<<63 + 10, 10},
+	}
+
+	for _, c := range cases {
+		if delta := counterDelta(c.prev, c.current); delta != c.expected {
+			t.Errorf("delta from %d to %d expected %d but get %d", c.prev, c.current, c.expected, delta)
+		}
+	}
+}
@@ -34,6 +34,10 @@ func removerExpireCsv(patten string) error {
 		}
 
 		if info.IsDir() {
+			//reports are removed by retention of report
+			if path == filepath.Clean(util.ReportPath) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
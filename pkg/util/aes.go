@@ -4,8 +4,14 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 )
 
 //fill
@@ -17,8 +23,13 @@ func pad(src []byte) []byte {
 
 func unpad(src []byte) ([]byte, error) {
 	length := len(src)
+	if length == 0 {
+		return nil, errors.New("unpad error. src is empty")
+	}
+
 	unpadding := int(src[length-1])
-	if unpadding > length {
+	if unpadding == 0 || unpadding > aes.BlockSize || unpadding > length ||
+		bytes.Equal(src[length-unpadding:], bytes.Repeat([]byte{byte(unpadding)}, unpadding)) == false {
 		return nil, errors.New("unpad error. This could happen when incorrect encryption key is used")
 	}
 	return src[:(length - unpadding)], nil
@@ -43,9 +54,16 @@ func Decrypt(key []byte, text string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	decodedMsg, _ := hex.DecodeString(text)
+	msg, err := hex.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+
+	if len(msg) == 0 || len(msg)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("ciphertext length %d isn't a multiple of block size", len(msg))
+	}
+
 	iv := make([]byte, aes.BlockSize)
-	msg := decodedMsg
 	mode := cipher.NewCBCDecrypter(block, iv)
 	mode.CryptBlocks(msg, msg)
 	unpadMsg, err := unpad(msg)
@@ -54,3 +72,80 @@ func Decrypt(key []byte, text string) (string, error) {
 	}
 	return string(unpadMsg), nil
 }
+
+//EncryptWithNonce encrypts text by AES-GCM with a random nonce, the nonce is
+//prepended to the hex encoded result so same text differs every time
+func EncryptWithNonce(key []byte, text string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(gcm.Seal(nonce, nonce, []byte(text), nil)), nil
+}
+
+func DecryptWithNonce(key []byte, text string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	msg, err := hex.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+
+	if len(msg) < gcm.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, msg[:gcm.NonceSize()], msg[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+//LoadKey reads hex encoded aes key from file, the key isn't generated when
+//file doesn't exist, because master and slave controllers share secrets in db
+//and must use the same key
+func LoadKey(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("key file isn't configured")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("key file %s doesn't exist, it should be created by `openssl rand -hex 32` "+
+			"and copied to every controller", path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("key file %s isn't hex encoded", path)
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("key of file %s should be 16, 24 or 32 bytes", path)
+	}
+}
@@ -0,0 +1,103 @@
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptWithNonce(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	first, err := EncryptWithNonce(key, "smtp-password")
+	if err != nil {
+		t.Fatalf("encrypt failed: %s", err.Error())
+	}
+
+	second, err := EncryptWithNonce(key, "smtp-password")
+	if err != nil {
+		t.Fatalf("encrypt failed: %s", err.Error())
+	}
+
+	if first == second {
+		t.Errorf("encrypt same text twice should get different result")
+	}
+
+	for _, ciphertext := range []string{first, second} {
+		if text, err := DecryptWithNonce(key, ciphertext); err != nil {
+			t.Errorf("decrypt failed: %s", err.Error())
+		} else if text != "smtp-password" {
+			t.Errorf("decrypt expected smtp-password but get %s", text)
+		}
+	}
+
+	if _, err := DecryptWithNonce([]byte("fedcba9876543210"), first); err == nil {
+		t.Errorf("decrypt with wrong key should fail")
+	}
+
+	if _, err := DecryptWithNonce(key, first[:10]); err == nil {
+		t.Errorf("decrypt truncated ciphertext should fail")
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	key := []byte("linkingthing.com")
+	encrypted, err := Encrypt(key, "admin")
+	if err != nil {
+		t.Fatalf("encrypt failed: %s", err.Error())
+	}
+
+	if text, err := Decrypt(key, encrypted); err != nil {
+		t.Errorf("decrypt failed: %s", err.Error())
+	} else if text != "admin" {
+		t.Errorf("decrypt expected admin but get %s", text)
+	}
+
+	wrongPadding, _ := Encrypt(key, "0123456789abcdef")
+	for _, ciphertext := range []string{
+		"",
+		"not hex",
+		encrypted[:10],
+		encrypted + "00",
+		wrongPadding[32:],
+	} {
+		if _, err := Decrypt(key, ciphertext); err == nil {
+			t.Errorf("decrypt %q should fail", ciphertext)
+		}
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key")
+	if err != nil {
+		t.Fatalf("create temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "secret.key")
+	if _, err := LoadKey(path); err == nil {
+		t.Errorf("missing key file should fail")
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) == false {
+		t.Errorf("missing key file shouldn't be created")
+	}
+
+	for content, valid := range map[string]bool{
+		"30313233343536373839616263646566\n": true,
+		"not hex":                            false,
+		"3031323334353637":                   false,
+	} {
+		ioutil.WriteFile(path, []byte(content), 0600)
+		if key, err := LoadKey(path); (err == nil) != valid {
+			t.Errorf("load key %q expected valid %v but get %v", content, valid, err)
+		} else if valid && bytes.Equal(key, []byte("0123456789abcdef")) == false {
+			t.Errorf("load key %q expected 0123456789abcdef but get %s", content, key)
+		}
+	}
+
+	if _, err := LoadKey(""); err == nil {
+		t.Errorf("empty key file should fail")
+	}
+}
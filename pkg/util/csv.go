@@ -9,8 +9,10 @@ import (
 const (
 	UTF8BOM      = "\xEF\xBB\xBF"
 	TimeFormat   = "2006-01-02 15:04:05"
+	DateFormat   = "2006-01-02"
 	FileRootPath = "/opt/website/"
 	CSVFilePath  = FileRootPath + "%s.csv"
+	ReportPath   = FileRootPath + "report/"
 )
 
 func GenCSVFile(filepath string, tableHeader []string, contents [][]string) error {
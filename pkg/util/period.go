@@ -0,0 +1,85 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	restresource "github.com/zdnscloud/gorest/resource"
+)
+
+const (
+	FilterPeriod       = "period"
+	DefaultPeriodHours = 6
+)
+
+var validPeriodHours = []string{"6", "12", "24", "168", "720", "2160"}
+
+//GetPeriodFromFilters returns the period in hours from filter period,
+//unknown values fall back to DefaultPeriodHours
+func GetPeriodFromFilters(filters []restresource.Filter) int {
+	for _, filter := range filters {
+		if filter.Name == FilterPeriod && filter.Modifier == restresource.Eq {
+			for _, value := range filter.Values {
+				for _, valid := range validPeriodHours {
+					if value == valid {
+						period, _ := strconv.Atoi(value)
+						return period
+					}
+				}
+			}
+		}
+	}
+
+	return DefaultPeriodHours
+}
+
+func PeriodBeginTime(period int) time.Time {
+	return time.Now().Add(-time.Duration(period) * time.Hour)
+}
+
+//GetTimeRangeFromFilters parses filter from and to, values are in TimeFormat
+//or a date like 2006-01-02 which covers the whole day
+func GetTimeRangeFromFilters(filters []restresource.Filter, defaultDuration time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	from := to.Add(-defaultDuration)
+	if value, ok := GetFilterValueWithEqModifierFromFilters(FilterTimeTo, filters); ok {
+		t, dateOnly, err := parseFilterTime(value)
+		if err != nil {
+			return from, to, err
+		}
+
+		if dateOnly {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		to = t
+		from = to.Add(-defaultDuration)
+	}
+
+	if value, ok := GetFilterValueWithEqModifierFromFilters(FilterTimeFrom, filters); ok {
+		t, _, err := parseFilterTime(value)
+		if err != nil {
+			return from, to, err
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return from, to, fmt.Errorf("time from %s is after time to %s", from.Format(TimeFormat), to.Format(TimeFormat))
+	}
+
+	return from, to, nil
+}
+
+func parseFilterTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(TimeFormat, value, time.Local); err == nil {
+		return t, false, nil
+	}
+
+	t, err := time.ParseInLocation(DateFormat, value, time.Local)
+	if err != nil {
+		return t, false, fmt.Errorf("invalid time %s", value)
+	}
+
+	return t, true, nil
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTotpCode(t *testing.T) {
+	//test vectors of RFC 6238 appendix B with SHA1, last 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for unix, expected := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		code, err := TotpCode(secret, TotpStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("compute totp code failed: %s", err.Error())
+		}
+
+		if code != expected {
+			t.Errorf("totp code at %d expected %s but get %s", unix, expected, code)
+		}
+	}
+}
+
+func TestValidateTotp(t *testing.T) {
+	secret, err := GenerateTotpSecret()
+	if err != nil {
+		t.Fatalf("generate totp secret failed: %s", err.Error())
+	}
+
+	now := time.Now()
+	previous, _ := TotpCode(secret, TotpStep(now)-1)
+	if step, ok := ValidateTotp(secret, previous, now, 1); ok == false || step != TotpStep(now)-1 {
+		t.Errorf("code of previous step should be accepted with skew 1")
+	}
+
+	tooOld, _ := TotpCode(secret, TotpStep(now)-2)
+	if _, ok := ValidateTotp(secret, tooOld, now, 1); ok {
+		t.Errorf("code out of skew should be refused")
+	}
+
+	if _, ok := ValidateTotp(secret, "12345", now, 1); ok {
+		t.Errorf("code with wrong length should be refused")
+	}
+}
+
+func TestTotpURI(t *testing.T) {
+	uri := TotpURI("linkingthing", "admin", "JBSWY3DPEHPK3PXP")
+	if strings.HasPrefix(uri, "otpauth://totp/linkingthing:admin?") == false ||
+		strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") == false ||
+		strings.Contains(uri, "issuer=linkingthing") == false {
+		t.Errorf("unexpected totp uri %s", uri)
+	}
+}
@@ -54,8 +54,14 @@ func NewServer() (*Server, error) {
 			param.Request.UserAgent(),
 		)
 	}))
-	router.StaticFS("/public", http.Dir(util.FileRootPath))
+	//directory listing is disabled, reports and exported files are reachable
+	//only by names returned to the user
+	router.StaticFS("/public", gin.Dir(util.FileRootPath, false))
 	router.POST("/login", authentification.Login)
+	router.POST("/login/totp", authentification.LoginTotp)
+	router.GET("/login/oidc", authentification.LoginOIDC)
+	router.POST("/login/oidc", authentification.LoginOIDCCallback)
+	router.POST("/refresh", authentification.Refresh)
 	router.GET("/", func(context *gin.Context) {
 		context.Redirect(http.StatusFound, "/public")
 	})